@@ -0,0 +1,91 @@
+package rsvp
+
+import (
+	"context"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+// AdminUser Entity, RecoveryCodes holds hashes of the unused TOTP recovery codes.
+// TOTPLastStep is the time step of the last TOTP code accepted, a code is used once.
+type AdminUser struct {
+	ID            bson.ObjectId `json:"-" bson:"_id,omitempty"`
+	Username      string        `json:"username" bson:"username"`
+	PasswordHash  string        `json:"-" bson:"password_hash"`
+	Role          string        `json:"role" bson:"role"`
+	TOTPEnabled   bool          `json:"totp_enabled" bson:"totp_enabled"`
+	TOTPSecret    string        `json:"-" bson:"totp_secret,omitempty"`
+	TOTPLastStep  int64         `json:"-" bson:"totp_last_step,omitempty"`
+	RecoveryCodes []string      `json:"-" bson:"recovery_codes,omitempty"`
+	Disabled      bool          `json:"disabled" bson:"disabled"`
+	CreatedAt     time.Time     `json:"created_at" bson:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at" bson:"updated_at"`
+}
+
+// AdminCredential holds username and password submitted by an admin.
+// OTP is a TOTP or recovery code, required when the admin enabled TOTP.
+type AdminCredential struct {
+	Username string `json:"username,required"`
+	Password string `json:"password,required"`
+	OTP      string `json:"otp"`
+}
+
+// PasswordChangeRequest holds a new password submitted for Username.
+// CurrentPassword is required when admins change their own password.
+type PasswordChangeRequest struct {
+	Username        string `json:"-"`
+	CurrentPassword string `json:"current_password"`
+	Password        string `json:"password,required"`
+}
+
+// TOTPEnrollment holds a new TOTP secret to be added to an authenticator app
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// AdminUserRequest holds data submitted to create an admin user
+type AdminUserRequest struct {
+	Username string `json:"username,required"`
+	Password string `json:"password,required"`
+	Role     string `json:"role,required"`
+}
+
+// AdminUserRepo provides data interchange between
+// application and admin user data provider.
+type AdminUserRepo interface {
+	CreateAdminUser(ctx context.Context, au AdminUser) (AdminUser, error)
+	GetAdminUser(ctx context.Context, username string) (*AdminUser, error)
+	GetAdminUsers(ctx context.Context) ([]*AdminUser, error)
+	CountAdminUsers(ctx context.Context) (int, error)
+	CountAdminUsersWithRole(ctx context.Context, role string) (int, error)
+	UpdateAdminUser(ctx context.Context, au AdminUser) error
+	// AdvanceTOTPStep records step as the last TOTP time step accepted from username, or returns
+	// response.NotFoundError when a code of step or a later one was accepted already
+	AdvanceTOTPStep(ctx context.Context, username string, step int64) error
+	// UseRecoveryCode removes the recovery code hash of username, or returns
+	// response.NotFoundError when it was used already
+	UseRecoveryCode(ctx context.Context, username string, hash string) error
+}
+
+// AdminUsecase changes admin users on behalf of actor, the admin making the change or nil for an API key.
+// Only owners can change owners or grant the owner role, and the last enabled owner cannot be demoted or disabled.
+type AdminUsecase interface {
+	CreateAdminUser(ctx context.Context, actor *AdminUser, req AdminUserRequest) (AdminUser, error)
+	GetAdminUsers(ctx context.Context) ([]*AdminUser, error)
+	Authenticate(ctx context.Context, cred AdminCredential) (*AdminUser, error)
+	ChangePassword(ctx context.Context, actor *AdminUser, req PasswordChangeRequest) error
+	SetAdminUserDisabled(ctx context.Context, actor *AdminUser, username string, disabled bool) error
+	SetAdminUserRole(ctx context.Context, actor *AdminUser, username string, role string) error
+	BootstrapAdminUser(ctx context.Context, cred AdminCredential) error
+
+	EnrollTOTP(ctx context.Context, username string) (TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, username string, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, username string, code string) error
+
+	Login(ctx context.Context, cred AdminCredential) (Session, error)
+	Logout(ctx context.Context, token string) error
+	RefreshSession(ctx context.Context, token string) (Session, error)
+	AuthenticateToken(ctx context.Context, token string) (*AdminUser, error)
+}
@@ -0,0 +1,56 @@
+package rsvp
+
+import (
+	"context"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+// APIKey Entity
+type APIKey struct {
+	ID         bson.ObjectId `json:"id" bson:"_id,omitempty"`
+	Name       string        `json:"name" bson:"name"`
+	Key        string        `json:"key,omitempty" bson:"-"`
+	KeyHash    string        `json:"-" bson:"key_hash"`
+	Scopes     []Permission  `json:"scopes" bson:"scopes"`
+	CreatedBy  string        `json:"created_by" bson:"created_by"`
+	CreatedAt  time.Time     `json:"created_at" bson:"created_at"`
+	LastUsedAt *time.Time    `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+	ExpiresAt  *time.Time    `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	RevokedAt  *time.Time    `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+}
+
+// APIKeyRequest holds data submitted to create an API key
+type APIKeyRequest struct {
+	Name      string       `json:"name,required"`
+	Scopes    []Permission `json:"scopes"`
+	ExpiresAt *time.Time   `json:"expires_at"`
+}
+
+// Allows reports whether the key is scoped to p
+func (k APIKey) Allows(p Permission) bool {
+	for _, scope := range k.Scopes {
+		if scope == p {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyRepo provides data interchange between
+// application and API key data provider.
+type APIKeyRepo interface {
+	CreateAPIKey(ctx context.Context, k APIKey) (APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error)
+	GetAPIKeys(ctx context.Context) ([]*APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string) error
+	TouchAPIKey(ctx context.Context, id bson.ObjectId, usedAt time.Time) error
+}
+
+type APIKeyUsecase interface {
+	CreateAPIKey(ctx context.Context, req APIKeyRequest, createdBy string) (APIKey, error)
+	GetAPIKeys(ctx context.Context) ([]*APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string) error
+	AuthenticateAPIKey(ctx context.Context, key string) (*APIKey, error)
+}
@@ -0,0 +1,61 @@
+package rsvp
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+// AuditEntry Entity
+type AuditEntry struct {
+	ID        bson.ObjectId `json:"id" bson:"_id,omitempty"`
+	Actor     string        `json:"actor" bson:"actor"`
+	Action    string        `json:"action" bson:"action"`
+	Target    string        `json:"target,omitempty" bson:"target,omitempty"`
+	Query     string        `json:"query,omitempty" bson:"query,omitempty"`
+	Status    int           `json:"status" bson:"status"`
+	RequestID string        `json:"request_id" bson:"request_id"`
+	IP        string        `json:"ip" bson:"ip"`
+	CreatedAt time.Time     `json:"created_at" bson:"created_at"`
+}
+
+// AuditFilter narrows down audit entries, zero fields match everything
+type AuditFilter struct {
+	Parameter
+	Actor  string
+	Action string
+	Target string
+	From   time.Time
+	To     time.Time
+}
+
+// AuditResult is a struct container to put result
+type AuditResult struct {
+	Data  []*AuditEntry
+	Total int64
+}
+
+// AuditIterator iterates over audit entries without loading them all in memory
+type AuditIterator interface {
+	Next(e *AuditEntry) bool
+	Err() error
+	Close() error
+}
+
+// AuditRepo provides data interchange between
+// application and audit log data provider.
+// The audit log is append-only, entries are never updated or removed.
+type AuditRepo interface {
+	CreateAuditEntry(ctx context.Context, e AuditEntry) (AuditEntry, error)
+	GetAuditEntries(ctx context.Context, f *AuditFilter) (*AuditResult, error)
+	IterateAuditEntries(ctx context.Context, f *AuditFilter) AuditIterator
+}
+
+type AuditUsecase interface {
+	Record(ctx context.Context, e AuditEntry) error
+	GetAuditEntries(ctx context.Context, f *AuditFilter) (*AuditResult, error)
+	// WriteAuditCsv streams the audit entries matching f as CSV rows to w
+	WriteAuditCsv(ctx context.Context, f *AuditFilter, w io.Writer) error
+}
@@ -1,13 +1,22 @@
 package config
 
 import (
+	"context"
+	"crypto/rand"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/constants"
 	"github.com/faris-arifiansyah/fws-rsvp/delivery"
 	"github.com/faris-arifiansyah/fws-rsvp/handler"
+	"github.com/faris-arifiansyah/fws-rsvp/ics"
+	"github.com/faris-arifiansyah/fws-rsvp/middleware"
+	"github.com/faris-arifiansyah/fws-rsvp/notifier"
 	"github.com/faris-arifiansyah/fws-rsvp/repository"
 	"github.com/faris-arifiansyah/fws-rsvp/usecase"
 	"github.com/faris-arifiansyah/mgoi"
@@ -15,12 +24,23 @@ import (
 	"github.com/joeshaw/envdecode"
 	"github.com/rs/cors"
 	"github.com/subosito/gotenv"
+	"go.uber.org/zap"
 )
 
 type Config struct {
 	Env  string `env:"ENV"`
 	Port uint16 `env:"PORT,default=8082"`
 
+	// BaseURL is the public URL of this service, used in links sent outside of it
+	BaseURL string `env:"BASE_URL,default=http://localhost:8082"`
+
+	// LinkSecret signs download links, a random one is used when empty
+	// so links stop working on restart
+	LinkSecret string `env:"LINK_SECRET"`
+
+	// TrustedProxies lists CIDRs whose forwarding headers are honoured, separated by semicolon
+	TrustedProxies []string `env:"TRUSTED_PROXIES"`
+
 	Database struct {
 		Host     string `env:"DATABASE_HOST,default=localhost"`
 		Name     string `env:"DATABASE_NAME,required"`
@@ -32,6 +52,150 @@ type Config struct {
 	Redis struct {
 		Address string `env:"REDIS_HOST,required"`
 	}
+
+	// SessionTTL is the lifetime of an admin session token
+	SessionTTL time.Duration `env:"SESSION_TTL,default=12h"`
+
+	// TOTPIssuer names this service in authenticator apps
+	TOTPIssuer string `env:"TOTP_ISSUER,default=FWS RSVP"`
+
+	Login struct {
+		MaxAttempts   int           `env:"LOGIN_MAX_ATTEMPTS,default=5"`
+		MaxIPAttempts int           `env:"LOGIN_MAX_IP_ATTEMPTS,default=20"`
+		Lockout       time.Duration `env:"LOGIN_LOCKOUT,default=1m"`
+		MaxLockout    time.Duration `env:"LOGIN_MAX_LOCKOUT,default=1h"`
+		Window        time.Duration `env:"LOGIN_FAILURE_WINDOW,default=1h"`
+	}
+
+	// Export configures background export jobs, rendered to files in Dir
+	Export struct {
+		Dir          string        `env:"EXPORT_DIR,default=exports"`
+		Workers      int           `env:"EXPORT_WORKERS,default=2"`
+		TTL          time.Duration `env:"EXPORT_TTL,default=24h"`
+		PollInterval time.Duration `env:"EXPORT_POLL_INTERVAL,default=5s"`
+		Lease        time.Duration `env:"EXPORT_LEASE,default=10m"`
+	}
+
+	// Webhook configures the delivery of events to webhooks. A failed delivery is retried
+	// after RetryBackoff, doubling up to MaxRetryBackoff, until MaxAttempts attempts.
+	Webhook struct {
+		Workers         int           `env:"WEBHOOK_WORKERS,default=2"`
+		MaxAttempts     int           `env:"WEBHOOK_MAX_ATTEMPTS,default=8"`
+		RetryBackoff    time.Duration `env:"WEBHOOK_RETRY_BACKOFF,default=30s"`
+		MaxRetryBackoff time.Duration `env:"WEBHOOK_MAX_RETRY_BACKOFF,default=1h"`
+		Timeout         time.Duration `env:"WEBHOOK_TIMEOUT,default=10s"`
+		PollInterval    time.Duration `env:"WEBHOOK_POLL_INTERVAL,default=5s"`
+		AllowPrivate    bool          `env:"WEBHOOK_ALLOW_PRIVATE_TARGETS,default=false"`
+	}
+
+	// SMTP emails the couple about every new RSVP, disabled when Host is empty.
+	// TLS is "starttls", "tls" or "none", To is separated by semicolon.
+	SMTP struct {
+		Host     string   `env:"SMTP_HOST"`
+		Port     int      `env:"SMTP_PORT,default=587"`
+		TLS      string   `env:"SMTP_TLS,default=starttls"`
+		Username string   `env:"SMTP_USERNAME"`
+		Password string   `env:"SMTP_PASSWORD"`
+		From     string   `env:"SMTP_FROM"`
+		To       []string `env:"SMTP_TO"`
+	}
+
+	// Chat posts new RSVPs and digests to a Telegram chat, with the bot TelegramToken and
+	// TelegramURL as the Bot API base URL, and to a Slack-style incoming webhook. Each chat
+	// is disabled while its token or URL is empty.
+	Chat struct {
+		TelegramToken   string        `env:"TELEGRAM_BOT_TOKEN"`
+		TelegramChatID  string        `env:"TELEGRAM_CHAT_ID"`
+		TelegramURL     string        `env:"TELEGRAM_API_URL,default=https://api.telegram.org"`
+		SlackWebhookURL string        `env:"SLACK_WEBHOOK_URL"`
+		Timeout         time.Duration `env:"CHAT_TIMEOUT,default=10s"`
+	}
+
+	// NotifyEachRsvp emails and posts every new RSVP to the chats that are set up, turn it off to rely on digests
+	NotifyEachRsvp bool `env:"NOTIFY_EACH_RSVP,default=true"`
+
+	// ConfirmGuests emails a confirmation to guests who give their email, when SMTP is set up
+	ConfirmGuests bool `env:"CONFIRM_GUESTS,default=true"`
+
+	// Event is the wedding guests RSVP to, offered as a calendar file when Start is set.
+	// Start and End are "YYYY-MM-DD HH:MM" in TimeZone, End is optional. The calendar
+	// alerts Reminder before Start, zero for no alert.
+	Event struct {
+		Title       string        `env:"EVENT_TITLE,default=Our Wedding"`
+		Start       string        `env:"EVENT_START"`
+		End         string        `env:"EVENT_END"`
+		TimeZone    string        `env:"EVENT_TIMEZONE,default=Asia/Jakarta"`
+		Location    string        `env:"EVENT_LOCATION"`
+		Description string        `env:"EVENT_DESCRIPTION"`
+		URL         string        `env:"EVENT_URL"`
+		Reminder    time.Duration `env:"EVENT_REMINDER,default=24h"`
+	}
+
+	// Reminder configures reminder campaigns. EmailSender is "smtp", "log" or "off" and
+	// SMSSender is "log" or "off", "log" writing reminders to the log instead of sending them.
+	// A reminder is sent every Interval at most, and an invitee is reminded MaxPerInvitee
+	// times at most, Cooldown apart. Message replaces the default reminder template. A campaign
+	// left running by a stopped instance is taken over Lease after its last reminder.
+	Reminder struct {
+		EmailSender   string        `env:"REMINDER_EMAIL_SENDER,default=smtp"`
+		SMSSender     string        `env:"REMINDER_SMS_SENDER,default=log"`
+		Message       string        `env:"REMINDER_MESSAGE"`
+		Interval      time.Duration `env:"REMINDER_INTERVAL,default=2s"`
+		Cooldown      time.Duration `env:"REMINDER_COOLDOWN,default=72h"`
+		MaxPerInvitee int           `env:"REMINDER_MAX_PER_INVITEE,default=3"`
+		PollInterval  time.Duration `env:"REMINDER_POLL_INTERVAL,default=5s"`
+		Lease         time.Duration `env:"REMINDER_LEASE,default=5m"`
+	}
+
+	// Digest sends a summary of the responses on Schedule, "daily", "weekly" on Weekday or "off",
+	// at Time (HH:MM) in TimeZone. Channel is "email", "telegram", "slack" or "log", and templates
+	// in TemplateDir replace the defaults of email and log.
+	Digest struct {
+		Schedule    string `env:"DIGEST_SCHEDULE,default=off"`
+		Weekday     string `env:"DIGEST_WEEKDAY,default=monday"`
+		Time        string `env:"DIGEST_TIME,default=08:00"`
+		TimeZone    string `env:"DIGEST_TIMEZONE,default=Asia/Jakarta"`
+		Channel     string `env:"DIGEST_CHANNEL,default=email"`
+		TemplateDir string `env:"DIGEST_TEMPLATE_DIR"`
+	}
+
+	// Outbox configures the delivery of RSVP events to email and webhooks. A failed delivery
+	// is retried after RetryBackoff, doubling up to MaxRetryBackoff, until MaxAttempts attempts.
+	Outbox struct {
+		Workers         int           `env:"OUTBOX_WORKERS,default=1"`
+		MaxAttempts     int           `env:"OUTBOX_MAX_ATTEMPTS,default=10"`
+		RetryBackoff    time.Duration `env:"OUTBOX_RETRY_BACKOFF,default=30s"`
+		MaxRetryBackoff time.Duration `env:"OUTBOX_MAX_RETRY_BACKOFF,default=1h"`
+		Lease           time.Duration `env:"OUTBOX_LEASE,default=5m"`
+		Grace           time.Duration `env:"OUTBOX_GRACE,default=1m"`
+		PollInterval    time.Duration `env:"OUTBOX_POLL_INTERVAL,default=1s"`
+	}
+
+	// Stream pushes created and updated RSVPs to admins over server-sent events. The last
+	// ReplaySize events are kept for ReplayTTL so a client can resume, and a heartbeat is
+	// written every Heartbeat to keep idle connections open.
+	Stream struct {
+		ReplaySize int           `env:"STREAM_REPLAY_SIZE,default=100"`
+		ReplayTTL  time.Duration `env:"STREAM_REPLAY_TTL,default=1h"`
+		Heartbeat  time.Duration `env:"STREAM_HEARTBEAT,default=15s"`
+	}
+
+	// Display shows approved guest messages as a slideshow on a screen, turned off while Token
+	// is empty. Each message is shown for Rotation in FontSize pixels, and only messages from
+	// MinLength to MaxLength characters long are shown, without an upper bound when MaxLength is zero.
+	Display struct {
+		Token     string        `env:"DISPLAY_TOKEN"`
+		Rotation  time.Duration `env:"DISPLAY_ROTATION,default=10s"`
+		FontSize  int           `env:"DISPLAY_FONT_SIZE,default=48"`
+		MinLength int           `env:"DISPLAY_MIN_LENGTH,default=1"`
+		MaxLength int           `env:"DISPLAY_MAX_LENGTH,default=280"`
+	}
+
+	// Admin is the first admin user, created only when no admin user exists yet
+	Admin struct {
+		Username string `env:"FWS_RSVP_USERNAME"`
+		Password string `env:"FWS_RSVP_PASSWORD"`
+	}
 }
 
 type RedisOption struct {
@@ -94,6 +258,185 @@ func NewRedis(opt RedisOption) (*redis.Client, error) {
 	return client, err
 }
 
+// NewNotifier returns the notifier configured in cfg, or nil when none is
+func NewNotifier(cfg *Config) (*notifier.SMTPNotifier, error) {
+	if cfg.SMTP.Host == "" {
+		return nil, nil
+	}
+
+	return notifier.NewSMTPNotifier(notifier.SMTPOption{
+		Host:     cfg.SMTP.Host,
+		Port:     cfg.SMTP.Port,
+		TLS:      cfg.SMTP.TLS,
+		Username: cfg.SMTP.Username,
+		Password: cfg.SMTP.Password,
+		From:     cfg.SMTP.From,
+		To:       cfg.SMTP.To,
+	})
+}
+
+// NewChatNotifiers returns the chat notifiers configured in cfg, by channel
+func NewChatNotifiers(cfg *Config) (map[string]*notifier.ChatNotifier, error) {
+	chats := map[string]*notifier.ChatNotifier{}
+
+	if cfg.Chat.TelegramToken != "" {
+		cn, err := notifier.NewTelegramNotifier(notifier.TelegramOption{
+			BaseURL: cfg.Chat.TelegramURL,
+			Token:   cfg.Chat.TelegramToken,
+			ChatID:  cfg.Chat.TelegramChatID,
+			Timeout: cfg.Chat.Timeout,
+		})
+		if err != nil {
+			return nil, err
+		}
+		chats["telegram"] = cn
+	}
+
+	if cfg.Chat.SlackWebhookURL != "" {
+		cn, err := notifier.NewSlackNotifier(notifier.SlackOption{
+			WebhookURL: cfg.Chat.SlackWebhookURL,
+			Timeout:    cfg.Chat.Timeout,
+		})
+		if err != nil {
+			return nil, err
+		}
+		chats["slack"] = cn
+	}
+
+	return chats, nil
+}
+
+// NewDigestOption returns the digest options configured in cfg, sending with rsvpNotifier
+// on the email channel and with chats on theirs, or nil when digests are off
+func NewDigestOption(cfg *Config, rsvpNotifier *notifier.SMTPNotifier, chats map[string]*notifier.ChatNotifier) (*usecase.DigestOption, error) {
+	opt := usecase.DigestOption{Schedule: cfg.Digest.Schedule}
+
+	switch cfg.Digest.Schedule {
+	case rsvp.DigestOff:
+		return nil, nil
+	case rsvp.DigestDaily, rsvp.DigestWeekly:
+	default:
+		return nil, fmt.Errorf("unknown digest schedule %q", cfg.Digest.Schedule)
+	}
+
+	weekday, ok := weekdays[strings.ToLower(cfg.Digest.Weekday)]
+	if !ok {
+		return nil, fmt.Errorf("unknown digest weekday %q", cfg.Digest.Weekday)
+	}
+	opt.Weekday = weekday
+
+	at, err := time.Parse("15:04", cfg.Digest.Time)
+	if err != nil {
+		return nil, fmt.Errorf("invalid digest time %q", cfg.Digest.Time)
+	}
+	opt.Hour, opt.Minute = at.Hour(), at.Minute()
+
+	if opt.Location, err = time.LoadLocation(cfg.Digest.TimeZone); err != nil {
+		return nil, err
+	}
+
+	if opt.Templates, err = usecase.LoadDigestTemplates(cfg.Digest.TemplateDir); err != nil {
+		return nil, err
+	}
+
+	switch cfg.Digest.Channel {
+	case "email":
+		if rsvpNotifier == nil {
+			return nil, fmt.Errorf("digest channel email needs SMTP_HOST")
+		}
+		opt.Sender = rsvpNotifier
+	case "log":
+		opt.Sender = notifier.LogSender{}
+	case "telegram", "slack":
+		cn, ok := chats[cfg.Digest.Channel]
+		if !ok {
+			return nil, fmt.Errorf("digest channel %s is not set up", cfg.Digest.Channel)
+		}
+		opt.ReportSender = cn
+	default:
+		return nil, fmt.Errorf("unknown digest channel %q", cfg.Digest.Channel)
+	}
+
+	return &opt, nil
+}
+
+// NewReminderSenders returns the reminder senders configured in cfg by channel, sending
+// email with rsvpNotifier. The email channel is left out when SMTP is not set up.
+func NewReminderSenders(cfg *Config, rsvpNotifier *notifier.SMTPNotifier) (map[string]rsvp.ReminderSender, error) {
+	senders := map[string]rsvp.ReminderSender{}
+
+	switch cfg.Reminder.EmailSender {
+	case "smtp":
+		if rsvpNotifier != nil {
+			senders[rsvp.ReminderEmail] = rsvpNotifier
+		}
+	case "log":
+		senders[rsvp.ReminderEmail] = notifier.LogReminderSender{Channel: rsvp.ReminderEmail}
+	case "off":
+	default:
+		return nil, fmt.Errorf("unknown reminder email sender %q", cfg.Reminder.EmailSender)
+	}
+
+	switch cfg.Reminder.SMSSender {
+	case "log":
+		senders[rsvp.ReminderSMS] = notifier.LogReminderSender{Channel: rsvp.ReminderSMS}
+	case "off":
+	default:
+		return nil, fmt.Errorf("unknown reminder SMS sender %q", cfg.Reminder.SMSSender)
+	}
+
+	return senders, nil
+}
+
+// NewCalendarEvent returns the event configured in cfg, whose Start is zero when none is.
+// Its UID is derived from BaseURL, so a calendar updates the event when it changes.
+func NewCalendarEvent(cfg *Config) (ics.Event, error) {
+	event := ics.Event{
+		Summary:     cfg.Event.Title,
+		Description: cfg.Event.Description,
+		Location:    cfg.Event.Location,
+		URL:         cfg.Event.URL,
+		Reminder:    cfg.Event.Reminder,
+	}
+	if cfg.Event.Start == "" {
+		return event, nil
+	}
+
+	location, err := time.LoadLocation(cfg.Event.TimeZone)
+	if err != nil {
+		return event, err
+	}
+
+	if event.Start, err = time.ParseInLocation(eventTimeFormat, cfg.Event.Start, location); err != nil {
+		return event, fmt.Errorf("invalid event start %q", cfg.Event.Start)
+	}
+	if cfg.Event.End != "" {
+		if event.End, err = time.ParseInLocation(eventTimeFormat, cfg.Event.End, location); err != nil || !event.End.After(event.Start) {
+			return event, fmt.Errorf("invalid event end %q", cfg.Event.End)
+		}
+	}
+
+	host := "fws-rsvp"
+	if u, err := url.Parse(cfg.BaseURL); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	event.UID = "wedding@" + host
+
+	return event, nil
+}
+
+const eventTimeFormat = "2006-01-02 15:04"
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
 func RunServer() {
 	cfg := NewConfig()
 
@@ -113,14 +456,166 @@ func RunServer() {
 	check(err)
 
 	rsvpRepo := repository.NewMongoRsvp(db)
-	uc := usecase.NewRsvpUsecase(&usecase.AccessProvider{
-		RsvpRepo: rsvpRepo,
+	adminUserRepo := repository.NewMongoAdminUser(db)
+	check(repository.EnsureAdminUserIndexes(db))
+	sessionRepo := repository.NewRedisSession(redis)
+	roleRepo := repository.NewMongoRole(db)
+	apiKeyRepo := repository.NewMongoAPIKey(db)
+	auditRepo := repository.NewMongoAudit(db)
+	exportJobRepo := repository.NewMongoExportJob(db)
+	webhookRepo := repository.NewMongoWebhook(db)
+	check(repository.EnsureWebhookIndexes(db))
+	outboxRepo := repository.NewMongoOutbox(db)
+	digestRepo := repository.NewMongoDigest(db)
+	inviteeRepo := repository.NewMongoInvitee(db)
+	reminderRepo := repository.NewMongoReminder(db)
+	eventStreamRepo := repository.NewRedisEventStream(redis, cfg.Stream.ReplaySize, cfg.Stream.ReplayTTL)
+	fileStore, err := repository.NewLocalFileStore(cfg.Export.Dir)
+	check(err)
+	rsvpNotifier, err := NewNotifier(cfg)
+	check(err)
+	chats, err := NewChatNotifiers(cfg)
+	check(err)
+	digestOpt, err := NewDigestOption(cfg, rsvpNotifier, chats)
+	check(err)
+	event, err := NewCalendarEvent(cfg)
+	check(err)
+	reminderSenders, err := NewReminderSenders(cfg, rsvpNotifier)
+	check(err)
+	pvd := &usecase.AccessProvider{
+		RsvpRepo:        rsvpRepo,
+		AdminUserRepo:   adminUserRepo,
+		SessionRepo:     sessionRepo,
+		RoleRepo:        roleRepo,
+		APIKeyRepo:      apiKeyRepo,
+		AuditRepo:       auditRepo,
+		ExportJobRepo:   exportJobRepo,
+		WebhookRepo:     webhookRepo,
+		FileStore:       fileStore,
+		OutboxRepo:      outboxRepo,
+		DigestRepo:      digestRepo,
+		InviteeRepo:     inviteeRepo,
+		ReminderRepo:    reminderRepo,
+		EventStreamRepo: eventStreamRepo,
+	}
+	webhookUc := usecase.NewWebhookUsecase(pvd, usecase.WebhookOption{
+		MaxAttempts:  cfg.Webhook.MaxAttempts,
+		Backoff:      cfg.Webhook.RetryBackoff,
+		MaxBackoff:   cfg.Webhook.MaxRetryBackoff,
+		Timeout:      cfg.Webhook.Timeout,
+		PollInterval: cfg.Webhook.PollInterval,
+
+		AllowPrivateTargets: cfg.Webhook.AllowPrivate,
+	})
+	reminderUc := usecase.NewReminderUsecase(pvd, usecase.ReminderOption{
+		Senders:       reminderSenders,
+		Message:       cfg.Reminder.Message,
+		Interval:      cfg.Reminder.Interval,
+		Cooldown:      cfg.Reminder.Cooldown,
+		MaxPerInvitee: cfg.Reminder.MaxPerInvitee,
+		PollInterval:  cfg.Reminder.PollInterval,
+		Lease:         cfg.Reminder.Lease,
+	})
+	streamUc := usecase.NewStreamUsecase(pvd)
+	handlers := map[string]rsvp.Publisher{"webhook": webhookUc, "invitees": reminderUc, "stream": streamUc}
+	if rsvpNotifier != nil && cfg.NotifyEachRsvp {
+		handlers["email"] = notifier.NewPublisher(rsvpNotifier)
+	}
+	if cfg.NotifyEachRsvp {
+		for channel, cn := range chats {
+			handlers[channel] = notifier.NewPublisher(cn)
+		}
+	}
+	if rsvpNotifier != nil && cfg.ConfirmGuests {
+		handlers["confirmation"] = notifier.NewConfirmer(rsvpNotifier, event)
+	}
+	outboxUc := usecase.NewOutboxUsecase(pvd, usecase.OutboxOption{
+		Handlers:     handlers,
+		MaxAttempts:  cfg.Outbox.MaxAttempts,
+		Backoff:      cfg.Outbox.RetryBackoff,
+		MaxBackoff:   cfg.Outbox.MaxRetryBackoff,
+		Lease:        cfg.Outbox.Lease,
+		Grace:        cfg.Outbox.Grace,
+		PollInterval: cfg.Outbox.PollInterval,
+	})
+	uc := usecase.NewRsvpUsecase(pvd)
+	displayUc := usecase.NewDisplayUsecase(pvd, streamUc)
+	adminUc := usecase.NewAdminUsecase(pvd, usecase.AdminOption{
+		SessionTTL: cfg.SessionTTL,
+		TOTPIssuer: cfg.TOTPIssuer,
 	})
+	roleUc := usecase.NewRoleUsecase(pvd)
+	apiKeyUc := usecase.NewAPIKeyUsecase(pvd)
+	auditUc := usecase.NewAuditUsecase(pvd)
+	exportJobUc := usecase.NewExportJobUsecase(pvd, usecase.ExportJobOption{
+		TTL:          cfg.Export.TTL,
+		PollInterval: cfg.Export.PollInterval,
+		Lease:        cfg.Export.Lease,
+	})
+
+	err = adminUc.BootstrapAdminUser(context.Background(), rsvp.AdminCredential{
+		Username: cfg.Admin.Username,
+		Password: cfg.Admin.Password,
+	})
+	check(err)
 
-	rsvpHandler := delivery.NewRsvpHandler(uc, redis)
-	h, err := handler.NewHandler(&rsvpHandler)
+	resolver, err := middleware.NewClientIPResolver(cfg.TrustedProxies)
 	check(err)
 
+	logger, err := zap.NewProduction()
+	check(err)
+
+	throttle := handler.NewLoginThrottle(redis, constants.RedisPrefix, handler.ThrottleOption{
+		MaxAttempts:   cfg.Login.MaxAttempts,
+		MaxIPAttempts: cfg.Login.MaxIPAttempts,
+		Lockout:       cfg.Login.Lockout,
+		MaxLockout:    cfg.Login.MaxLockout,
+		Window:        cfg.Login.Window,
+	}, logger)
+	linkSecret := []byte(cfg.LinkSecret)
+	if len(linkSecret) == 0 {
+		log.Println("LINK_SECRET is not set, signed links will stop working on restart")
+		linkSecret = make([]byte, 32)
+		_, err = rand.Read(linkSecret)
+		check(err)
+	}
+	signer := handler.NewLinkSigner(linkSecret, cfg.BaseURL, redis, constants.RedisPrefix)
+
+	auth := handler.NewAuthenticator(adminUc, roleUc, apiKeyUc, auditUc, throttle, signer)
+	rsvpHandler := delivery.NewRsvpHandler(uc, auth, redis)
+	adminUserHandler := delivery.NewAdminUserHandler(adminUc, auth)
+	authHandler := delivery.NewAuthHandler(adminUc, auth)
+	roleHandler := delivery.NewRoleHandler(roleUc, auth)
+	apiKeyHandler := delivery.NewAPIKeyHandler(apiKeyUc, auth)
+	auditHandler := delivery.NewAuditHandler(auditUc, auth)
+	linkHandler := delivery.NewLinkHandler(auth)
+	exportJobHandler := delivery.NewExportJobHandler(exportJobUc, auth)
+	webhookHandler := delivery.NewWebhookHandler(webhookUc, auth)
+	calendarHandler := delivery.NewCalendarHandler(event, auth)
+	reminderHandler := delivery.NewReminderHandler(reminderUc, auth)
+	streamHandler := delivery.NewStreamHandler(streamUc, auth, cfg.Stream.Heartbeat)
+	displayHandler := delivery.NewDisplayHandler(displayUc, auth, delivery.DisplayOption{
+		Token:    cfg.Display.Token,
+		Title:    cfg.Event.Title,
+		Rotation: cfg.Display.Rotation,
+		FontSize: cfg.Display.FontSize,
+		Filter: rsvp.DisplayFilter{
+			MinLength: cfg.Display.MinLength,
+			MaxLength: cfg.Display.MaxLength,
+		},
+		Heartbeat: cfg.Stream.Heartbeat,
+	})
+	h, err := handler.NewHandler(resolver, &rsvpHandler, &adminUserHandler, &authHandler, &roleHandler, &apiKeyHandler, &auditHandler, &linkHandler, &exportJobHandler, &webhookHandler, &calendarHandler, &reminderHandler, &streamHandler, &displayHandler)
+	check(err)
+
+	exportJobUc.RunExportWorkers(context.Background(), cfg.Export.Workers)
+	webhookUc.RunWebhookWorkers(context.Background(), cfg.Webhook.Workers)
+	outboxUc.RunOutboxDispatchers(context.Background(), cfg.Outbox.Workers)
+	reminderUc.RunReminderWorker(context.Background())
+	if digestOpt != nil {
+		usecase.NewDigestUsecase(pvd, *digestOpt).RunDigestScheduler(context.Background())
+	}
+
 	co := cors.New(cors.Options{
 		AllowedOrigins: []string{"*"},
 		AllowedMethods: []string{"GET", "POST", "PATCH", "DELETE", "PUT", "HEAD", "OPTIONS"},
@@ -128,6 +623,7 @@ func RunServer() {
 		MaxAge:         86400,
 	})
 
+	// event streams lift both timeouts on their own connections
 	s := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Port),
 		Handler:      co.Handler(h),
@@ -0,0 +1,177 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/handler"
+	"github.com/faris-arifiansyah/fws-rsvp/middleware"
+	"github.com/faris-arifiansyah/fws-rsvp/request/validator"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/julienschmidt/httprouter"
+)
+
+// AdminUserHandler struct
+type AdminUserHandler struct {
+	uc   rsvp.AdminUsecase
+	auth *handler.Authenticator
+}
+
+func NewAdminUserHandler(uc rsvp.AdminUsecase, auth *handler.Authenticator) AdminUserHandler {
+	return AdminUserHandler{
+		uc:   uc,
+		auth: auth,
+	}
+}
+
+func (h *AdminUserHandler) Register(router *httprouter.Router, ds []middleware.Decorator) error {
+	if router == nil {
+		return fmt.Errorf("router cannot be empty")
+	}
+
+	router.GET("/admin-users", handler.Decorate(h.auth.WithAuth(h.RetrieveAllAdminUser, rsvp.PermissionAdminUserManage), ds...))
+	router.POST("/admin-users", handler.Decorate(h.auth.WithAuth(h.CreateAdminUser, rsvp.PermissionAdminUserManage), ds...))
+	router.PUT("/admin-users/:username/password", handler.Decorate(h.auth.WithAuth(h.ChangePassword, handler.Authenticated), ds...))
+	router.PUT("/admin-users/:username/role", handler.Decorate(h.auth.WithAuth(h.ChangeRole, rsvp.PermissionAdminUserManage), ds...))
+	router.POST("/admin-users/:username/disable", handler.Decorate(h.auth.WithAuth(h.DisableAdminUser, rsvp.PermissionAdminUserManage), ds...))
+	router.POST("/admin-users/:username/enable", handler.Decorate(h.auth.WithAuth(h.EnableAdminUser, rsvp.PermissionAdminUserManage), ds...))
+
+	return nil
+}
+
+func (h *AdminUserHandler) RetrieveAllAdminUser(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	admins, err := h.uc.GetAdminUsers(r.Context())
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusOK, Total: int64(len(admins))}
+	response.Write(w, response.BuildSuccess(admins, m), http.StatusOK)
+	return nil
+}
+
+func (h *AdminUserHandler) CreateAdminUser(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	var req rsvp.AdminUserRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+	defer r.Body.Close()
+
+	if errs := validator.Validate(req); len(errs) > 0 {
+		response.Write(w, response.BuildErrors(errs), http.StatusBadRequest)
+		return errs[0]
+	}
+
+	admin, err := h.uc.CreateAdminUser(r.Context(), handler.AdminFromContext(r.Context()), req)
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusCreated}
+	response.Write(w, response.BuildSuccess(admin, m), http.StatusCreated)
+	return nil
+}
+
+func (h *AdminUserHandler) ChangePassword(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	var req rsvp.PasswordChangeRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+	defer r.Body.Close()
+
+	req.Username = params.ByName("username")
+	if errs := validator.Validate(req); len(errs) > 0 {
+		response.Write(w, response.BuildErrors(errs), http.StatusBadRequest)
+		return errs[0]
+	}
+
+	// Every admin may change their own password, other passwords need admin user management
+	if admin := handler.AdminFromContext(r.Context()); admin == nil || !strings.EqualFold(admin.Username, req.Username) {
+		if err := h.auth.Authorize(r.Context(), rsvp.PermissionAdminUserManage); err != nil {
+			errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+			response.Write(w, errBody, httpStatus)
+			return err
+		}
+	}
+
+	if err := h.uc.ChangePassword(r.Context(), handler.AdminFromContext(r.Context()), req); err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusOK}
+	response.Write(w, response.BuildSuccess("password changed", m), http.StatusOK)
+	return nil
+}
+
+func (h *AdminUserHandler) ChangeRole(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	var req struct {
+		Role string `json:"role,required"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+	defer r.Body.Close()
+
+	if errs := validator.Validate(req); len(errs) > 0 {
+		response.Write(w, response.BuildErrors(errs), http.StatusBadRequest)
+		return errs[0]
+	}
+
+	if err := h.uc.SetAdminUserRole(r.Context(), handler.AdminFromContext(r.Context()), params.ByName("username"), req.Role); err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusOK}
+	response.Write(w, response.BuildSuccess(fmt.Sprintf("admin user %s updated", params.ByName("username")), m), http.StatusOK)
+	return nil
+}
+
+func (h *AdminUserHandler) DisableAdminUser(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	username := params.ByName("username")
+
+	// Disabling yourself would lock you out of the endpoint to undo it
+	if admin := handler.AdminFromContext(r.Context()); admin != nil && strings.EqualFold(admin.Username, username) {
+		err := response.BadRequestError
+		err.Field = "username"
+		response.Write(w, response.BuildError([]error{err}), err.HTTPCode)
+		return err
+	}
+
+	return h.setDisabled(w, r, username, true)
+}
+
+func (h *AdminUserHandler) EnableAdminUser(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	return h.setDisabled(w, r, params.ByName("username"), false)
+}
+
+func (h *AdminUserHandler) setDisabled(w http.ResponseWriter, r *http.Request, username string, disabled bool) error {
+	if err := h.uc.SetAdminUserDisabled(r.Context(), handler.AdminFromContext(r.Context()), username, disabled); err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusOK}
+	response.Write(w, response.BuildSuccess(fmt.Sprintf("admin user %s updated", username), m), http.StatusOK)
+	return nil
+}
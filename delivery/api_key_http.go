@@ -0,0 +1,101 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/handler"
+	"github.com/faris-arifiansyah/fws-rsvp/middleware"
+	"github.com/faris-arifiansyah/fws-rsvp/request/validator"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/julienschmidt/httprouter"
+)
+
+// APIKeyHandler struct
+type APIKeyHandler struct {
+	uc   rsvp.APIKeyUsecase
+	auth *handler.Authenticator
+}
+
+func NewAPIKeyHandler(uc rsvp.APIKeyUsecase, auth *handler.Authenticator) APIKeyHandler {
+	return APIKeyHandler{
+		uc:   uc,
+		auth: auth,
+	}
+}
+
+func (h *APIKeyHandler) Register(router *httprouter.Router, ds []middleware.Decorator) error {
+	if router == nil {
+		return fmt.Errorf("router cannot be empty")
+	}
+
+	router.GET("/api-keys", handler.Decorate(h.auth.WithAuth(h.RetrieveAllAPIKey, rsvp.PermissionAPIKeyManage), ds...))
+	router.POST("/api-keys", handler.Decorate(h.auth.WithAuth(h.CreateAPIKey, rsvp.PermissionAPIKeyManage), ds...))
+	router.DELETE("/api-keys/:id", handler.Decorate(h.auth.WithAuth(h.RevokeAPIKey, rsvp.PermissionAPIKeyManage), ds...))
+
+	return nil
+}
+
+func (h *APIKeyHandler) RetrieveAllAPIKey(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	keys, err := h.uc.GetAPIKeys(r.Context())
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusOK, Total: int64(len(keys))}
+	response.Write(w, response.BuildSuccess(keys, m), http.StatusOK)
+	return nil
+}
+
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	var req rsvp.APIKeyRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+	defer r.Body.Close()
+
+	if errs := validator.Validate(req); len(errs) > 0 {
+		response.Write(w, response.BuildErrors(errs), http.StatusBadRequest)
+		return errs[0]
+	}
+
+	// A key cannot be granted more than its creator holds
+	for _, scope := range req.Scopes {
+		if err := h.auth.Authorize(r.Context(), scope); err != nil {
+			errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+			response.Write(w, errBody, httpStatus)
+			return err
+		}
+	}
+
+	createdBy := middleware.Actor(r.Context())
+	k, err := h.uc.CreateAPIKey(r.Context(), req, createdBy)
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusCreated}
+	response.Write(w, response.BuildSuccess(k, m), http.StatusCreated)
+	return nil
+}
+
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	if err := h.uc.RevokeAPIKey(r.Context(), params.ByName("id")); err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusOK}
+	response.Write(w, response.BuildSuccess("api key revoked", m), http.StatusOK)
+	return nil
+}
@@ -0,0 +1,91 @@
+package delivery_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/delivery"
+	"github.com/faris-arifiansyah/fws-rsvp/handler"
+	"github.com/faris-arifiansyah/fws-rsvp/middleware"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAdmins authenticates every token as the admin budi
+type fakeAdmins struct {
+	rsvp.AdminUsecase
+}
+
+func (fakeAdmins) AuthenticateToken(ctx context.Context, token string) (*rsvp.AdminUser, error) {
+	return &rsvp.AdminUser{Username: "budi", Role: "manager"}, nil
+}
+
+// fakeRoles grants the permissions of a manager who may only read RSVPs and manage keys
+type fakeRoles struct {
+	rsvp.RoleUsecase
+}
+
+func (fakeRoles) Authorize(ctx context.Context, admin *rsvp.AdminUser, p rsvp.Permission) error {
+	if p != rsvp.PermissionRsvpRead && p != rsvp.PermissionAPIKeyManage {
+		return response.UserUnauthorizedError
+	}
+	return nil
+}
+
+type fakeAudits struct {
+	rsvp.AuditUsecase
+}
+
+func (fakeAudits) Record(ctx context.Context, e rsvp.AuditEntry) error {
+	return nil
+}
+
+// fakeAPIKeyUsecase remembers the keys it created
+type fakeAPIKeyUsecase struct {
+	rsvp.APIKeyUsecase
+	created []rsvp.APIKeyRequest
+}
+
+func (fk *fakeAPIKeyUsecase) CreateAPIKey(ctx context.Context, req rsvp.APIKeyRequest, createdBy string) (rsvp.APIKey, error) {
+	fk.created = append(fk.created, req)
+	return rsvp.APIKey{Name: req.Name, Scopes: req.Scopes, CreatedBy: createdBy}, nil
+}
+
+func TestCreateAPIKeyScopes(t *testing.T) {
+	resolver, err := middleware.NewClientIPResolver(nil)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"held scopes", `{"name": "ci", "scopes": ["rsvps:read"]}`, http.StatusCreated},
+		{"scope the creator lacks", `{"name": "ci", "scopes": ["rsvps:read", "rsvps:delete"]}`, http.StatusForbidden},
+		{"every permission", `{"name": "ci", "scopes": ["*"]}`, http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			uc := &fakeAPIKeyUsecase{}
+			auth := handler.NewAuthenticator(fakeAdmins{}, fakeRoles{}, nil, fakeAudits{}, nil, nil)
+			h := delivery.NewAPIKeyHandler(uc, auth)
+
+			router := httprouter.New()
+			assert.NoError(t, h.Register(router, []middleware.Decorator{middleware.WithStandardContext(resolver)}))
+
+			r := httptest.NewRequest(http.MethodPost, "/api-keys", strings.NewReader(test.body))
+			r.Header.Set("Authorization", "Bearer token")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			assert.Equal(t, test.status, w.Code)
+			assert.Equal(t, test.status == http.StatusCreated, len(uc.created) == 1)
+		})
+	}
+}
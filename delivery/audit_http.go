@@ -0,0 +1,96 @@
+package delivery
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/constants"
+	"github.com/faris-arifiansyah/fws-rsvp/handler"
+	"github.com/faris-arifiansyah/fws-rsvp/middleware"
+	"github.com/faris-arifiansyah/fws-rsvp/request"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/julienschmidt/httprouter"
+)
+
+// AuditHandler struct
+type AuditHandler struct {
+	uc   rsvp.AuditUsecase
+	auth *handler.Authenticator
+}
+
+func NewAuditHandler(uc rsvp.AuditUsecase, auth *handler.Authenticator) AuditHandler {
+	return AuditHandler{
+		uc:   uc,
+		auth: auth,
+	}
+}
+
+func (h *AuditHandler) Register(router *httprouter.Router, ds []middleware.Decorator) error {
+	if router == nil {
+		return fmt.Errorf("router cannot be empty")
+	}
+
+	router.GET("/audit", handler.Decorate(h.auth.WithAuth(h.RetrieveAllAuditEntry, rsvp.PermissionAuditRead), ds...))
+
+	return nil
+}
+
+// RetrieveAllAuditEntry lists audit entries, or streams every matching entry as CSV
+// with format=csv unless limit is given
+func (h *AuditHandler) RetrieveAllAuditEntry(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	ctx := r.Context()
+
+	qh := request.NewQueryHelper(r)
+	csv := qh.GetString("format", "json") == "csv"
+	limit := 10
+	if csv {
+		limit = constants.NoLimit
+	}
+
+	f := rsvp.AuditFilter{
+		Parameter: rsvp.Parameter{
+			Sort:   qh.GetString("sort", ""),
+			Limit:  qh.GetInt("limit", limit),
+			Offset: qh.GetInt("offset", 0),
+		},
+		Actor:  qh.GetString("actor", ""),
+		Action: qh.GetString("action", ""),
+		Target: qh.GetString("target", ""),
+		From:   qh.GetTime("from", time.Time{}),
+		To:     qh.GetTime("to", time.Time{}),
+	}
+
+	if csv {
+		timestamp := time.Now().Format("2006-01-02_15-04-05")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="audit-%s.csv"`, timestamp))
+		w.Header().Set("Content-Type", "text/csv")
+
+		bw := &bodyWriter{ResponseWriter: w}
+		if err := h.uc.WriteAuditCsv(ctx, &f, bw); err != nil {
+			bw.fail(err)
+			return err
+		}
+
+		return nil
+	}
+
+	auditResult, err := h.uc.GetAuditEntries(ctx, &f)
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{
+		HTTPStatus: http.StatusOK,
+		Limit:      f.Limit,
+		Offset:     f.Offset,
+		Total:      auditResult.Total,
+		Sort:       f.Sort,
+	}
+
+	response.Write(w, response.BuildSuccess(auditResult.Data, m), http.StatusOK)
+	return nil
+}
@@ -0,0 +1,102 @@
+package delivery_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/constants"
+	"github.com/faris-arifiansyah/fws-rsvp/delivery"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAuditUsecase remembers the last filter and writes it as the CSV body
+type fakeAuditUsecase struct {
+	rsvp.AuditUsecase
+	filter rsvp.AuditFilter
+}
+
+func (fa *fakeAuditUsecase) GetAuditEntries(ctx context.Context, f *rsvp.AuditFilter) (*rsvp.AuditResult, error) {
+	fa.filter = *f
+	return &rsvp.AuditResult{}, nil
+}
+
+func (fa *fakeAuditUsecase) WriteAuditCsv(ctx context.Context, f *rsvp.AuditFilter, w io.Writer) error {
+	fa.filter = *f
+	_, err := io.WriteString(w, "Time,Actor\n")
+	return err
+}
+
+func TestRetrieveAllAuditEntry(t *testing.T) {
+	from := time.Date(2019, 8, 17, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	tests := []struct {
+		name        string
+		query       string
+		contentType string
+		expected    rsvp.AuditFilter
+	}{
+		{
+			name:        "json",
+			query:       "",
+			contentType: "application/json",
+			expected:    rsvp.AuditFilter{Parameter: rsvp.Parameter{Limit: 10}},
+		},
+		{
+			name:        "filters",
+			query:       "actor=admin:faris&action=DELETE&target=5c4a&from=2019-08-17T00:00:00Z&to=2019-08-18T00:00:00Z&sort=created_at&limit=5&offset=10",
+			contentType: "application/json",
+			expected: rsvp.AuditFilter{
+				Parameter: rsvp.Parameter{Sort: "created_at", Limit: 5, Offset: 10},
+				Actor:     "admin:faris",
+				Action:    "DELETE",
+				Target:    "5c4a",
+				From:      from,
+				To:        to,
+			},
+		},
+		{
+			name:        "csv",
+			query:       "format=csv&actor=admin:faris",
+			contentType: "text/csv",
+			expected:    rsvp.AuditFilter{Parameter: rsvp.Parameter{Limit: constants.NoLimit}, Actor: "admin:faris"},
+		},
+		{
+			name:        "csv with limit",
+			query:       "format=csv&limit=100",
+			contentType: "text/csv",
+			expected:    rsvp.AuditFilter{Parameter: rsvp.Parameter{Limit: 100}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			uc := &fakeAuditUsecase{}
+			h := delivery.NewAuditHandler(uc, nil)
+
+			w := httptest.NewRecorder()
+			err := h.RetrieveAllAuditEntry(w, httptest.NewRequest(http.MethodGet, "/audit?"+test.query, nil), nil)
+			assert.NoError(t, err)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Header().Get("Content-Type"), test.contentType)
+			assert.Equal(t, test.expected.Limit, uc.filter.Limit)
+			assert.Equal(t, test.expected.Offset, uc.filter.Offset)
+			assert.Equal(t, test.expected.Sort, uc.filter.Sort)
+			assert.Equal(t, test.expected.Actor, uc.filter.Actor)
+			assert.Equal(t, test.expected.Action, uc.filter.Action)
+			assert.Equal(t, test.expected.Target, uc.filter.Target)
+			assert.True(t, test.expected.From.Equal(uc.filter.From))
+			assert.True(t, test.expected.To.Equal(uc.filter.To))
+			if test.contentType == "text/csv" {
+				assert.Contains(t, w.Header().Get("Content-Disposition"), `attachment; filename="audit-`)
+				assert.Equal(t, "Time,Actor\n", w.Body.String())
+			}
+		})
+	}
+}
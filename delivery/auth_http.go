@@ -0,0 +1,218 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/handler"
+	"github.com/faris-arifiansyah/fws-rsvp/middleware"
+	"github.com/faris-arifiansyah/fws-rsvp/request/validator"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/julienschmidt/httprouter"
+)
+
+// AuthHandler struct
+type AuthHandler struct {
+	uc   rsvp.AdminUsecase
+	auth *handler.Authenticator
+}
+
+func NewAuthHandler(uc rsvp.AdminUsecase, auth *handler.Authenticator) AuthHandler {
+	return AuthHandler{
+		uc:   uc,
+		auth: auth,
+	}
+}
+
+func (h *AuthHandler) Register(router *httprouter.Router, ds []middleware.Decorator) error {
+	if router == nil {
+		return fmt.Errorf("router cannot be empty")
+	}
+
+	router.POST("/auth/login", handler.Decorate(h.auth.WithAuth(h.Login, handler.Anonymous), ds...))
+	router.POST("/auth/logout", handler.Decorate(h.auth.WithAuth(h.Logout, handler.Authenticated), ds...))
+	router.POST("/auth/refresh", handler.Decorate(h.auth.WithAuth(h.Refresh, handler.Authenticated), ds...))
+	router.POST("/auth/totp", handler.Decorate(h.auth.WithAuth(h.EnrollTOTP, handler.Authenticated), ds...))
+	router.POST("/auth/totp/confirm", handler.Decorate(h.auth.WithAuth(h.ConfirmTOTP, handler.Authenticated), ds...))
+	router.DELETE("/auth/totp", handler.Decorate(h.auth.WithAuth(h.DisableTOTP, handler.Authenticated), ds...))
+	router.DELETE("/lockouts/:kind/:value", handler.Decorate(h.auth.WithAuth(h.ClearLockout, rsvp.PermissionAdminUserManage), ds...))
+
+	return nil
+}
+
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	var cred rsvp.AdminCredential
+
+	if err := json.NewDecoder(r.Body).Decode(&cred); err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+	defer r.Body.Close()
+
+	if errs := validator.Validate(cred); len(errs) > 0 {
+		response.Write(w, response.BuildErrors(errs), http.StatusBadRequest)
+		return errs[0]
+	}
+
+	var session rsvp.Session
+	err := h.auth.Guard(w, r, cred.Username, func() (err error) {
+		session, err = h.uc.Login(r.Context(), cred)
+		return err
+	})
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusCreated}
+	response.Write(w, response.BuildSuccess(session, m), http.StatusCreated)
+	return nil
+}
+
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	token, err := requireBearerToken(w, r)
+	if err != nil {
+		return err
+	}
+
+	if err = h.uc.Logout(r.Context(), token); err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusOK}
+	response.Write(w, response.BuildSuccess("logged out", m), http.StatusOK)
+	return nil
+}
+
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	token, err := requireBearerToken(w, r)
+	if err != nil {
+		return err
+	}
+
+	session, err := h.uc.RefreshSession(r.Context(), token)
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusCreated}
+	response.Write(w, response.BuildSuccess(session, m), http.StatusCreated)
+	return nil
+}
+
+func (h *AuthHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	admin := handler.AdminFromContext(r.Context())
+
+	enrollment, err := h.uc.EnrollTOTP(r.Context(), admin.Username)
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusCreated}
+	response.Write(w, response.BuildSuccess(enrollment, m), http.StatusCreated)
+	return nil
+}
+
+// ConfirmTOTP enables TOTP and returns the recovery codes.
+// Existing sessions are revoked, so the admin logs in again with the second factor.
+func (h *AuthHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	admin := handler.AdminFromContext(r.Context())
+
+	code, err := decodeOTP(w, r)
+	if err != nil {
+		return err
+	}
+
+	recoveryCodes, err := h.uc.ConfirmTOTP(r.Context(), admin.Username, code)
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusOK}
+	response.Write(w, response.BuildSuccess(map[string]interface{}{"recovery_codes": recoveryCodes}, m), http.StatusOK)
+	return nil
+}
+
+func (h *AuthHandler) DisableTOTP(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	admin := handler.AdminFromContext(r.Context())
+
+	code, err := decodeOTP(w, r)
+	if err != nil {
+		return err
+	}
+
+	if err = h.uc.DisableTOTP(r.Context(), admin.Username, code); err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusOK}
+	response.Write(w, response.BuildSuccess("two-factor authentication disabled", m), http.StatusOK)
+	return nil
+}
+
+func decodeOTP(w http.ResponseWriter, r *http.Request) (string, error) {
+	var req struct {
+		OTP string `json:"otp,required"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return "", err
+	}
+	defer r.Body.Close()
+
+	if errs := validator.Validate(req); len(errs) > 0 {
+		response.Write(w, response.BuildErrors(errs), http.StatusBadRequest)
+		return "", errs[0]
+	}
+
+	return req.OTP, nil
+}
+
+// ClearLockout lifts the lockout of a username (kind users) or a client IP (kind ips)
+func (h *AuthHandler) ClearLockout(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	kind := params.ByName("kind")
+	if kind != handler.LockoutUser && kind != handler.LockoutIP {
+		err := response.NotFoundError
+		response.Write(w, response.BuildError([]error{err}), err.HTTPCode)
+		return err
+	}
+
+	if err := h.auth.ClearLockout(kind, params.ByName("value")); err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusOK}
+	response.Write(w, response.BuildSuccess("lockout cleared", m), http.StatusOK)
+	return nil
+}
+
+// requireBearerToken returns the session token of r, Basic Auth requests have no session to act on
+func requireBearerToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	token := handler.BearerToken(r)
+	if token == "" {
+		err := response.BadRequestError
+		err.Field = "Authorization"
+		response.Write(w, response.BuildError([]error{err}), err.HTTPCode)
+		return "", err
+	}
+
+	return token, nil
+}
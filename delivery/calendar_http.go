@@ -0,0 +1,52 @@
+package delivery
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/faris-arifiansyah/fws-rsvp/handler"
+	"github.com/faris-arifiansyah/fws-rsvp/ics"
+	"github.com/faris-arifiansyah/fws-rsvp/middleware"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/julienschmidt/httprouter"
+)
+
+// CalendarHandler struct
+type CalendarHandler struct {
+	event ics.Event
+	auth  *handler.Authenticator
+}
+
+// NewCalendarHandler is a function to create CalendarHandler serving event, which is not found when its Start is zero
+func NewCalendarHandler(event ics.Event, auth *handler.Authenticator) CalendarHandler {
+	return CalendarHandler{
+		event: event,
+		auth:  auth,
+	}
+}
+
+func (h *CalendarHandler) Register(router *httprouter.Router, ds []middleware.Decorator) error {
+	if router == nil {
+		return fmt.Errorf("router cannot be empty")
+	}
+
+	router.GET("/events/calendar.ics", handler.Decorate(h.auth.WithAuth(h.DownloadCalendar, handler.Anonymous), ds...))
+
+	return nil
+}
+
+// DownloadCalendar returns the event as an iCalendar file
+func (h *CalendarHandler) DownloadCalendar(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	if h.event.Start.IsZero() {
+		err := response.NotFoundError
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	w.Header().Set("Content-Type", ics.ContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="calendar.ics"`)
+	_, err := w.Write(ics.Marshal(time.Now(), h.event))
+	return err
+}
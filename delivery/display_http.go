@@ -0,0 +1,285 @@
+package delivery
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"html/template"
+	"net/http"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/handler"
+	"github.com/faris-arifiansyah/fws-rsvp/middleware"
+	"github.com/faris-arifiansyah/fws-rsvp/request"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Bounds of the settings a display page can ask for
+const (
+	minDisplayRotation = time.Second
+	minDisplayFontSize = 8
+	maxDisplayFontSize = 400
+)
+
+// displayFade is how long a wish fades out and in, in milliseconds
+const displayFade = 800
+
+const displayPageHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+html, body { height: 100%; margin: 0; }
+body { display: flex; align-items: center; justify-content: center; background: #1d1b26; color: #fdfbf7; font-family: Georgia, serif; cursor: none; overflow: hidden; }
+#wish { max-width: 80vw; text-align: center; transition: opacity {{.Fade}}ms; }
+#wish.hidden { opacity: 0; }
+#message { margin: 0; font-size: {{.FontSize}}px; line-height: 1.4; white-space: pre-wrap; }
+#name { margin-top: 1em; font-size: {{.NameSize}}px; opacity: 0.75; }
+</style>
+</head>
+<body>
+<div id="wish">
+<blockquote id="message">{{with .First}}{{.Message}}{{end}}</blockquote>
+<div id="name">{{with .First}}&mdash; {{.Name}}{{end}}</div>
+</div>
+<script>
+(function () {
+	var wishes = {{.Wishes}};
+	var box = document.getElementById("wish");
+	var message = document.getElementById("message");
+	var name = document.getElementById("name");
+	var current = wishes.length > 0 ? wishes[0].id : "";
+	var next = 1;
+
+	function show() {
+		if (wishes.length === 0) {
+			current = "";
+			message.textContent = "";
+			name.textContent = "";
+			return;
+		}
+		if (next >= wishes.length) {
+			next = 0;
+		}
+		var wish = wishes[next++];
+		current = wish.id;
+		box.className = "hidden";
+		setTimeout(function () {
+			message.textContent = wish.message;
+			name.textContent = "— " + wish.name;
+			box.className = "";
+		}, {{.Fade}});
+	}
+
+	function remove(id) {
+		for (var i = 0; i < wishes.length; i++) {
+			if (wishes[i].id === id) {
+				wishes.splice(i, 1);
+				if (i < next) {
+					next--;
+				}
+				return;
+			}
+		}
+	}
+
+	setInterval(show, {{.Rotation}});
+
+	var source = new EventSource({{.StreamURL}});
+	source.addEventListener("wish", function (e) {
+		var wish = JSON.parse(e.data);
+		remove(wish.id);
+		// a new wish is shown next
+		wishes.splice(next, 0, wish);
+	});
+	source.addEventListener("withdrawn", function (e) {
+		var id = JSON.parse(e.data).id;
+		remove(id);
+		if (id === current) {
+			show();
+		}
+	});
+})();
+</script>
+</body>
+</html>
+`
+
+var displayPage = template.Must(template.New("display").Parse(displayPageHTML))
+
+// DisplayOption configures the display. It is turned off while Token is empty. Rotation,
+// FontSize and Filter are defaults a display page can override with its query.
+type DisplayOption struct {
+	Token     string
+	Title     string
+	Rotation  time.Duration
+	FontSize  int
+	Filter    rsvp.DisplayFilter
+	Heartbeat time.Duration
+}
+
+// DisplayHandler struct
+type DisplayHandler struct {
+	uc   rsvp.DisplayUsecase
+	auth *handler.Authenticator
+	opt  DisplayOption
+}
+
+func NewDisplayHandler(uc rsvp.DisplayUsecase, auth *handler.Authenticator, opt DisplayOption) DisplayHandler {
+	return DisplayHandler{
+		uc:   uc,
+		auth: auth,
+		opt:  opt,
+	}
+}
+
+func (h *DisplayHandler) Register(router *httprouter.Router, ds []middleware.Decorator) error {
+	if router == nil {
+		return fmt.Errorf("router cannot be empty")
+	}
+
+	router.GET("/display", handler.Decorate(h.auth.WithAuth(h.withToken(h.ShowDisplay), handler.Anonymous), ds...))
+	router.GET("/display/wishes", handler.Decorate(h.auth.WithAuth(h.withToken(h.RetrieveAllWish), handler.Anonymous), ds...))
+	router.GET("/display/stream", handler.Decorate(h.auth.WithAuth(h.withToken(h.StreamWish), handler.Anonymous), ds...))
+
+	return nil
+}
+
+// withToken lets through requests with the display token in the token query
+func (h *DisplayHandler) withToken(fn func(http.ResponseWriter, *http.Request, httprouter.Params) error) func(http.ResponseWriter, *http.Request, httprouter.Params) error {
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+		var err error
+
+		token := request.NewQueryHelper(r).GetString("token", "")
+		switch {
+		case h.opt.Token == "":
+			err = response.NotFoundError
+		case subtle.ConstantTimeCompare([]byte(token), []byte(h.opt.Token)) != 1:
+			err = response.UserUnauthorizedError
+		}
+
+		if err != nil {
+			errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+			response.Write(w, errBody, httpStatus)
+			return err
+		}
+
+		return fn(w, r, params)
+	}
+}
+
+// filter returns the display filter, overridden by the min_length and max_length queries
+func (h *DisplayHandler) filter(r *http.Request) rsvp.DisplayFilter {
+	qh := request.NewQueryHelper(r)
+
+	return rsvp.DisplayFilter{
+		MinLength: qh.GetInt("min_length", h.opt.Filter.MinLength),
+		MaxLength: qh.GetInt("max_length", h.opt.Filter.MaxLength),
+	}
+}
+
+// ShowDisplay renders the slideshow of wishes, showing each one for the rotation query
+// (a duration such as 8s) in font_size pixels, and adding wishes as they are approved.
+// The stream resumes from the wishes rendered, so no approval made meanwhile is missed.
+func (h *DisplayHandler) ShowDisplay(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	qh := request.NewQueryHelper(r)
+
+	wishes, lastID, err := h.uc.GetWishesToStream(r.Context(), h.filter(r))
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	rotation, err := time.ParseDuration(qh.GetString("rotation", ""))
+	if err != nil || rotation < minDisplayRotation {
+		rotation = h.opt.Rotation
+	}
+	fontSize := qh.GetInt("font_size", h.opt.FontSize)
+	if fontSize < minDisplayFontSize || fontSize > maxDisplayFontSize {
+		fontSize = h.opt.FontSize
+	}
+	query := r.URL.Query()
+	query.Set("last_event_id", lastID)
+
+	page := struct {
+		Title     string
+		First     *rsvp.Wish
+		Wishes    []rsvp.Wish
+		Rotation  int64
+		Fade      int
+		FontSize  int
+		NameSize  int
+		StreamURL string
+	}{
+		Title:    h.opt.Title,
+		Wishes:   wishes,
+		Rotation: int64(rotation / time.Millisecond),
+		Fade:     displayFade,
+		FontSize: fontSize,
+		NameSize: fontSize / 2,
+		// relative to the page, so the display works behind a path prefix
+		StreamURL: "display/stream?" + query.Encode(),
+	}
+	if len(wishes) > 0 {
+		page.First = &wishes[0]
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	return displayPage.Execute(w, page)
+}
+
+// RetrieveAllWish lists the wishes on the display, oldest approval first
+func (h *DisplayHandler) RetrieveAllWish(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	wishes, err := h.uc.GetWishes(r.Context(), h.filter(r))
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusOK, Total: int64(len(wishes))}
+	response.Write(w, response.BuildSuccess(wishes, m), http.StatusOK)
+	return nil
+}
+
+// StreamWish pushes wishes as they are approved or withdrawn as server-sent events,
+// "wish" and "withdrawn", until the client disconnects
+func (h *DisplayHandler) StreamWish(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	wishes, err := h.uc.StreamWishes(r.Context(), h.filter(r), lastEventID(r))
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	ew, err := startEvents(w)
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	heartbeat := time.NewTicker(h.opt.Heartbeat)
+	defer heartbeat.Stop()
+
+	for err == nil {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case <-heartbeat.C:
+			err = ew.heartbeat()
+		case we, ok := <-wishes:
+			if !ok {
+				return nil
+			}
+			err = ew.event(we.ID, we.Type, we.Wish)
+		}
+	}
+
+	return nil
+}
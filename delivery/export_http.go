@@ -0,0 +1,104 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/handler"
+	"github.com/faris-arifiansyah/fws-rsvp/middleware"
+	"github.com/faris-arifiansyah/fws-rsvp/request/validator"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/julienschmidt/httprouter"
+)
+
+// exportContentTypes are the content types of the export formats
+var exportContentTypes = map[string]string{
+	rsvp.ExportFormatCsv:    "text/csv; charset=utf-8",
+	rsvp.ExportFormatXlsx:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	rsvp.ExportFormatNdjson: "application/x-ndjson",
+}
+
+// ExportJobHandler struct
+type ExportJobHandler struct {
+	uc   rsvp.ExportJobUsecase
+	auth *handler.Authenticator
+}
+
+func NewExportJobHandler(uc rsvp.ExportJobUsecase, auth *handler.Authenticator) ExportJobHandler {
+	return ExportJobHandler{
+		uc:   uc,
+		auth: auth,
+	}
+}
+
+func (h *ExportJobHandler) Register(router *httprouter.Router, ds []middleware.Decorator) error {
+	if router == nil {
+		return fmt.Errorf("router cannot be empty")
+	}
+
+	router.POST("/exports", handler.Decorate(h.auth.WithAuth(h.CreateExportJob, rsvp.PermissionRsvpExport), ds...))
+	router.GET("/exports/:id", handler.Decorate(h.auth.WithAuth(h.RetrieveExportJob, rsvp.PermissionRsvpExport), ds...))
+	router.GET("/exports/:id/download", handler.Decorate(h.auth.WithAuth(h.DownloadExport, rsvp.PermissionRsvpExport), ds...))
+
+	return nil
+}
+
+func (h *ExportJobHandler) CreateExportJob(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	var req rsvp.ExportJobRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+	defer r.Body.Close()
+
+	if errs := validator.Validate(req); len(errs) > 0 {
+		response.Write(w, response.BuildErrors(errs), http.StatusBadRequest)
+		return errs[0]
+	}
+
+	job, err := h.uc.CreateExportJob(r.Context(), req, middleware.Actor(r.Context()))
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	w.Header().Set("Location", "/exports/"+job.ID.Hex())
+	m := response.MetaInfo{HTTPStatus: http.StatusAccepted}
+	response.Write(w, response.BuildSuccess(job, m), http.StatusAccepted)
+	return nil
+}
+
+func (h *ExportJobHandler) RetrieveExportJob(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	job, err := h.uc.GetExportJob(r.Context(), params.ByName("id"))
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusOK}
+	response.Write(w, response.BuildSuccess(job, m), http.StatusOK)
+	return nil
+}
+
+// DownloadExport serves the file of a finished export job, supporting range requests to resume downloads
+func (h *ExportJobHandler) DownloadExport(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	job, f, err := h.uc.OpenExportFile(r.Context(), params.ByName("id"))
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, job.FileName))
+	w.Header().Set("Content-Type", exportContentTypes[job.Format])
+	http.ServeContent(w, r, job.FileName, *job.FinishedAt, f)
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/handler"
+	"github.com/faris-arifiansyah/fws-rsvp/middleware"
+	"github.com/faris-arifiansyah/fws-rsvp/request/validator"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	defaultLinkTTL = 24 * time.Hour
+	maxLinkTTL     = 7 * 24 * time.Hour
+)
+
+// signablePaths are the routes served with WithSignedLink, with the permission needed to mint a link to them
+var signablePaths = map[string]rsvp.Permission{
+	"/files/rsvps":        rsvp.PermissionRsvpExport,
+	"/files/rsvps.xlsx":   rsvp.PermissionRsvpExport,
+	"/files/rsvps.ndjson": rsvp.PermissionRsvpExport,
+}
+
+// LinkRequest holds data submitted to mint a signed link
+type LinkRequest struct {
+	Path      string            `json:"path,required"`
+	Query     map[string]string `json:"query"`
+	ExpiresIn string            `json:"expires_in"`
+	SingleUse bool              `json:"single_use"`
+}
+
+// LinkHandler struct
+type LinkHandler struct {
+	auth *handler.Authenticator
+}
+
+func NewLinkHandler(auth *handler.Authenticator) LinkHandler {
+	return LinkHandler{
+		auth: auth,
+	}
+}
+
+func (h *LinkHandler) Register(router *httprouter.Router, ds []middleware.Decorator) error {
+	if router == nil {
+		return fmt.Errorf("router cannot be empty")
+	}
+
+	router.POST("/links", handler.Decorate(h.auth.WithAuth(h.CreateLink, handler.Authenticated), ds...))
+
+	return nil
+}
+
+// CreateLink mints a link to a file download that works without credentials until it expires
+func (h *LinkHandler) CreateLink(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	var req LinkRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+	defer r.Body.Close()
+
+	if errs := validator.Validate(req); len(errs) > 0 {
+		response.Write(w, response.BuildErrors(errs), http.StatusBadRequest)
+		return errs[0]
+	}
+
+	permission, ok := signablePaths[req.Path]
+	if !ok {
+		err := response.BadRequestError
+		err.Field = "path"
+		response.Write(w, response.BuildError([]error{err}), err.HTTPCode)
+		return err
+	}
+
+	// A link cannot open more than its minter can
+	if err := h.auth.Authorize(r.Context(), permission); err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	ttl := defaultLinkTTL
+	if req.ExpiresIn != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.ExpiresIn); err != nil || ttl <= 0 || ttl > maxLinkTTL {
+			err := response.BadRequestError
+			err.Field = "expires_in"
+			response.Write(w, response.BuildError([]error{err}), err.HTTPCode)
+			return err
+		}
+	}
+
+	query := url.Values{}
+	for k, v := range req.Query {
+		if handler.IsReserved(k) {
+			err := response.BadRequestError
+			err.Field = "query"
+			response.Write(w, response.BuildError([]error{err}), err.HTTPCode)
+			return err
+		}
+		query.Set(k, v)
+	}
+
+	link, err := h.auth.SignLink(req.Path, query, time.Now().Add(ttl), req.SingleUse, middleware.Actor(r.Context()))
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusCreated}
+	response.Write(w, response.BuildSuccess(link, m), http.StatusCreated)
+	return nil
+}
@@ -0,0 +1,187 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/handler"
+	"github.com/faris-arifiansyah/fws-rsvp/middleware"
+	"github.com/faris-arifiansyah/fws-rsvp/request"
+	"github.com/faris-arifiansyah/fws-rsvp/request/validator"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ReminderHandler struct
+type ReminderHandler struct {
+	uc   rsvp.ReminderUsecase
+	auth *handler.Authenticator
+}
+
+func NewReminderHandler(uc rsvp.ReminderUsecase, auth *handler.Authenticator) ReminderHandler {
+	return ReminderHandler{
+		uc:   uc,
+		auth: auth,
+	}
+}
+
+func (h *ReminderHandler) Register(router *httprouter.Router, ds []middleware.Decorator) error {
+	if router == nil {
+		return fmt.Errorf("router cannot be empty")
+	}
+
+	router.GET("/invitees", handler.Decorate(h.auth.WithAuth(h.RetrieveAllInvitee, rsvp.PermissionInviteeManage), ds...))
+	router.POST("/invitees", handler.Decorate(h.auth.WithAuth(h.CreateInvitees, rsvp.PermissionInviteeManage), ds...))
+	router.DELETE("/invitees/:id", handler.Decorate(h.auth.WithAuth(h.DeleteInvitee, rsvp.PermissionInviteeManage), ds...))
+	router.GET("/invitees/:id/reminders", handler.Decorate(h.auth.WithAuth(h.RetrieveAllReminder, rsvp.PermissionInviteeManage), ds...))
+	router.GET("/reminder-campaigns", handler.Decorate(h.auth.WithAuth(h.RetrieveAllCampaign, rsvp.PermissionInviteeManage), ds...))
+	router.POST("/reminder-campaigns", handler.Decorate(h.auth.WithAuth(h.CreateCampaign, rsvp.PermissionInviteeManage), ds...))
+	router.GET("/reminder-campaigns/:id", handler.Decorate(h.auth.WithAuth(h.RetrieveCampaign, rsvp.PermissionInviteeManage), ds...))
+	router.POST("/reminder-campaigns/:id/cancel", handler.Decorate(h.auth.WithAuth(h.CancelCampaign, rsvp.PermissionInviteeManage), ds...))
+
+	return nil
+}
+
+// RetrieveAllInvitee lists invitees by name, only those who have not responded with pending=true
+func (h *ReminderHandler) RetrieveAllInvitee(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	qh := request.NewQueryHelper(r)
+	p := rsvp.Parameter{
+		Limit:  qh.GetInt("limit", 10),
+		Offset: qh.GetInt("offset", 0),
+	}
+
+	result, err := h.uc.GetInvitees(r.Context(), &p, qh.GetBool("pending", false))
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{
+		HTTPStatus: http.StatusOK,
+		Limit:      p.Limit,
+		Offset:     p.Offset,
+		Total:      result.Total,
+	}
+
+	response.Write(w, response.BuildSuccess(result.Data, m), http.StatusOK)
+	return nil
+}
+
+// CreateInvitees adds the invitees of a JSON array to the list
+func (h *ReminderHandler) CreateInvitees(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	var invitees []rsvp.Invitee
+
+	if err := json.NewDecoder(r.Body).Decode(&invitees); err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+	defer r.Body.Close()
+
+	invitees, err := h.uc.CreateInvitees(r.Context(), invitees)
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusCreated, Total: int64(len(invitees))}
+	response.Write(w, response.BuildSuccess(invitees, m), http.StatusCreated)
+	return nil
+}
+
+func (h *ReminderHandler) DeleteInvitee(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	if err := h.uc.DeleteInvitee(r.Context(), params.ByName("id")); err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusOK}
+	response.Write(w, response.BuildSuccess("invitee deleted", m), http.StatusOK)
+	return nil
+}
+
+// RetrieveAllReminder lists the reminders sent to an invitee, newest first
+func (h *ReminderHandler) RetrieveAllReminder(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	reminders, err := h.uc.GetReminders(r.Context(), params.ByName("id"))
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusOK, Total: int64(len(reminders))}
+	response.Write(w, response.BuildSuccess(reminders, m), http.StatusOK)
+	return nil
+}
+
+func (h *ReminderHandler) RetrieveAllCampaign(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	campaigns, err := h.uc.GetReminderCampaigns(r.Context())
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusOK, Total: int64(len(campaigns))}
+	response.Write(w, response.BuildSuccess(campaigns, m), http.StatusOK)
+	return nil
+}
+
+// CreateCampaign queues a reminder campaign, poll RetrieveCampaign for its progress
+func (h *ReminderHandler) CreateCampaign(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	var req rsvp.ReminderCampaignRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+	defer r.Body.Close()
+
+	if errs := validator.Validate(req); len(errs) > 0 {
+		response.Write(w, response.BuildErrors(errs), http.StatusBadRequest)
+		return errs[0]
+	}
+
+	c, err := h.uc.CreateReminderCampaign(r.Context(), req, middleware.Actor(r.Context()))
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusAccepted}
+	response.Write(w, response.BuildSuccess(c, m), http.StatusAccepted)
+	return nil
+}
+
+func (h *ReminderHandler) RetrieveCampaign(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	c, err := h.uc.GetReminderCampaign(r.Context(), params.ByName("id"))
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusOK}
+	response.Write(w, response.BuildSuccess(c, m), http.StatusOK)
+	return nil
+}
+
+func (h *ReminderHandler) CancelCampaign(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	c, err := h.uc.CancelReminderCampaign(r.Context(), params.ByName("id"))
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusOK}
+	response.Write(w, response.BuildSuccess(c, m), http.StatusOK)
+	return nil
+}
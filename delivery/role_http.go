@@ -0,0 +1,90 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/handler"
+	"github.com/faris-arifiansyah/fws-rsvp/middleware"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/julienschmidt/httprouter"
+)
+
+// RoleHandler struct
+type RoleHandler struct {
+	uc   rsvp.RoleUsecase
+	auth *handler.Authenticator
+}
+
+func NewRoleHandler(uc rsvp.RoleUsecase, auth *handler.Authenticator) RoleHandler {
+	return RoleHandler{
+		uc:   uc,
+		auth: auth,
+	}
+}
+
+func (h *RoleHandler) Register(router *httprouter.Router, ds []middleware.Decorator) error {
+	if router == nil {
+		return fmt.Errorf("router cannot be empty")
+	}
+
+	router.GET("/roles", handler.Decorate(h.auth.WithAuth(h.RetrieveAllRole, rsvp.PermissionAdminUserManage), ds...))
+	router.PUT("/roles/:name", handler.Decorate(h.auth.WithAuth(h.SaveRole, rsvp.PermissionAdminUserManage), ds...))
+	router.DELETE("/roles/:name", handler.Decorate(h.auth.WithAuth(h.DeleteRole, rsvp.PermissionAdminUserManage), ds...))
+
+	return nil
+}
+
+func (h *RoleHandler) RetrieveAllRole(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	roles, err := h.uc.GetRoles(r.Context())
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{
+		HTTPStatus: http.StatusOK,
+		Total:      int64(len(roles)),
+		Facets:     map[string]interface{}{"permissions": rsvp.Permissions},
+	}
+	response.Write(w, response.BuildSuccess(roles, m), http.StatusOK)
+	return nil
+}
+
+func (h *RoleHandler) SaveRole(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	var role rsvp.Role
+
+	if err := json.NewDecoder(r.Body).Decode(&role); err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+	defer r.Body.Close()
+
+	role.Name = params.ByName("name")
+	saved, err := h.uc.SaveRole(r.Context(), role)
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusOK}
+	response.Write(w, response.BuildSuccess(saved, m), http.StatusOK)
+	return nil
+}
+
+func (h *RoleHandler) DeleteRole(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	if err := h.uc.DeleteRole(r.Context(), params.ByName("name")); err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusOK}
+	response.Write(w, response.BuildSuccess(fmt.Sprintf("role %s deleted", params.ByName("name")), m), http.StatusOK)
+	return nil
+}
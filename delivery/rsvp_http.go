@@ -1,10 +1,12 @@
 package delivery
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"net"
+	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	rsvp "github.com/faris-arifiansyah/fws-rsvp"
@@ -20,14 +22,16 @@ import (
 
 // RsvpHandler struct
 type RsvpHandler struct {
-	uc  rsvp.Usecase
-	rds *redis.Client
+	uc      rsvp.Usecase
+	auth    *handler.Authenticator
+	limiter *handler.RateLimiter
 }
 
-func NewRsvpHandler(uc rsvp.Usecase, rds *redis.Client) RsvpHandler {
+func NewRsvpHandler(uc rsvp.Usecase, auth *handler.Authenticator, rds *redis.Client) RsvpHandler {
 	return RsvpHandler{
-		uc:  uc,
-		rds: rds,
+		uc:      uc,
+		auth:    auth,
+		limiter: handler.NewRateLimiter(rds, constants.RedisPrefix, constants.RateLimit, constants.RateLimitExp*time.Second),
 	}
 }
 
@@ -36,9 +40,17 @@ func (h *RsvpHandler) Register(router *httprouter.Router, ds []middleware.Decora
 		return fmt.Errorf("router cannot be empty")
 	}
 
-	router.POST("/rsvps", handler.Decorate(handler.WithAuth(h.CreateRsvp, handler.Anonymous), ds...))
-	router.GET("/rsvps", handler.Decorate(handler.WithAuth(h.RetrieveAllRsvp, handler.Admin), ds...))
-	router.GET("/files/rsvps", handler.Decorate(handler.WithAuth(h.DownloadRsvpCsv, handler.Admin), ds...))
+	router.POST("/rsvps", handler.Decorate(h.auth.WithAuth(handler.WithRateLimit(h.CreateRsvp, h.limiter), handler.Anonymous), ds...))
+	router.GET("/rsvps", handler.Decorate(h.auth.WithAuth(h.RetrieveAllRsvp, rsvp.PermissionRsvpRead), ds...))
+	router.DELETE("/rsvps/:id", handler.Decorate(h.auth.WithAuth(h.DeleteRsvp, rsvp.PermissionRsvpDelete), ds...))
+	router.PUT("/rsvps/:id/approval", handler.Decorate(h.auth.WithAuth(h.ApproveMessage, rsvp.PermissionMessageApprove), ds...))
+	router.DELETE("/rsvps/:id/approval", handler.Decorate(h.auth.WithAuth(h.WithdrawMessage, rsvp.PermissionMessageApprove), ds...))
+	router.GET("/files/rsvps", handler.Decorate(h.auth.WithSignedLink(h.DownloadRsvpCsv, rsvp.PermissionRsvpExport), ds...))
+	router.GET("/files/rsvps.xlsx", handler.Decorate(h.auth.WithSignedLink(h.DownloadRsvpXlsx, rsvp.PermissionRsvpExport), ds...))
+	router.GET("/files/rsvps.ndjson", handler.Decorate(h.auth.WithSignedLink(h.DownloadRsvpNdjson, rsvp.PermissionRsvpExport), ds...))
+	router.POST("/imports/rsvps", handler.Decorate(h.auth.WithAuth(h.ImportRsvpCsv, rsvp.PermissionRsvpImport), ds...))
+	router.POST("/imports/rsvps.ndjson", handler.Decorate(h.auth.WithAuth(h.ImportRsvpNdjson, rsvp.PermissionRsvpImport), ds...))
+	router.GET("/reports/catering", handler.Decorate(h.auth.WithAuth(h.RetrieveCateringReport, rsvp.PermissionReportCatering), ds...))
 
 	return nil
 }
@@ -46,7 +58,6 @@ func (h *RsvpHandler) Register(router *httprouter.Router, ds []middleware.Decora
 func (h *RsvpHandler) CreateRsvp(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
 	var ctx = r.Context()
 	var rsvpRequest rsvp.Rsvp
-	var remoteAddr = r.RemoteAddr
 	var err error
 
 	decoder := json.NewDecoder(r.Body)
@@ -61,24 +72,7 @@ func (h *RsvpHandler) CreateRsvp(w http.ResponseWriter, r *http.Request, _ httpr
 	if len(errs) > 0 {
 		errBody := response.BuildErrors(errs)
 		response.Write(w, errBody, http.StatusBadRequest)
-		return err
-	}
-
-	//Check Rate Limit
-	host, _, _ := net.SplitHostPort(remoteAddr)
-	count, err := h.rds.Get(constants.RedisPrefix + host).Int()
-	if count+1 > constants.RateLimit { //Rate Limit Exceeded
-		err = response.RateLimitExceededError
-	} else if count == 0 { //Set in Redis with Expire
-		err = h.rds.Set(constants.RedisPrefix+host, 1, time.Duration(constants.RateLimitExp*time.Second)).Err()
-	} else { //Increment Number of Requests
-		err = h.rds.Incr(constants.RedisPrefix + host).Err()
-	}
-
-	if err != nil {
-		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
-		response.Write(w, errBody, httpStatus)
-		return err
+		return errs[0]
 	}
 
 	//Create RSVP
@@ -123,26 +117,175 @@ func (h *RsvpHandler) RetrieveAllRsvp(w http.ResponseWriter, r *http.Request, _
 	return nil
 }
 
+func (h *RsvpHandler) DeleteRsvp(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	if err := h.uc.DeleteRsvp(r.Context(), params.ByName("id")); err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusOK}
+	response.Write(w, response.BuildSuccess("rsvp deleted", m), http.StatusOK)
+	return nil
+}
+
+// ApproveMessage shows the message of an RSVP on the display
+func (h *RsvpHandler) ApproveMessage(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	return h.setMessageApproval(w, r, params.ByName("id"), true)
+}
+
+// WithdrawMessage takes the message of an RSVP off the display
+func (h *RsvpHandler) WithdrawMessage(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	return h.setMessageApproval(w, r, params.ByName("id"), false)
+}
+
+func (h *RsvpHandler) setMessageApproval(w http.ResponseWriter, r *http.Request, id string, approved bool) error {
+	rp, err := h.uc.SetMessageApproval(r.Context(), id, approved)
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusOK}
+	response.Write(w, response.BuildSuccess(rp, m), http.StatusOK)
+	return nil
+}
+
+func (h *RsvpHandler) RetrieveCateringReport(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	summary, err := h.uc.GetAttendanceSummary(r.Context())
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusOK}
+	response.Write(w, response.BuildSuccess(summary, m), http.StatusOK)
+	return nil
+}
+
+// DownloadRsvpCsv streams every RSVP as CSV, unless limit is given.
+// The layout is chosen with columns, lang, tz, date_format, delimiter and bom.
 func (h *RsvpHandler) DownloadRsvpCsv(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
-	ctx := r.Context()
+	return h.download(w, r, rsvp.ExportFormatCsv, h.uc.WriteRsvpsCsv)
+}
+
+// DownloadRsvpXlsx streams every RSVP as an XLSX workbook, taking the same parameters as DownloadRsvpCsv.
+// Only columns, lang and tz affect the layout, cells are typed.
+func (h *RsvpHandler) DownloadRsvpXlsx(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	return h.download(w, r, rsvp.ExportFormatXlsx, h.uc.WriteRsvpsXlsx)
+}
+
+// DownloadRsvpNdjson streams every RSVP with all of its fields as JSON, one per line, to be restored with ImportRsvpNdjson
+func (h *RsvpHandler) DownloadRsvpNdjson(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	return h.download(w, r, rsvp.ExportFormatNdjson, func(ctx context.Context, p *rsvp.Parameter, _ rsvp.ExportOption, w io.Writer) error {
+		return h.uc.WriteRsvpsNdjson(ctx, p, w)
+	})
+}
+
+// ImportRsvpNdjson restores RSVPs from a body written by DownloadRsvpNdjson
+func (h *RsvpHandler) ImportRsvpNdjson(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	defer r.Body.Close()
+
+	result, err := h.uc.ImportRsvpsNdjson(r.Context(), r.Body)
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusOK}
+	response.Write(w, response.BuildSuccess(result, m), http.StatusOK)
+	return nil
+}
+
+// ImportRsvpCsv creates RSVPs from a CSV file sent as the body or as the file field of a multipart form.
+// Headers are mapped with map, a comma separated list of header:column pairs, and rows are read
+// with tz, date_format and delimiter like DownloadRsvpCsv writes them. With dry_run=true nothing is saved.
+func (h *RsvpHandler) ImportRsvpCsv(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	defer r.Body.Close()
 
 	qh := request.NewQueryHelper(r)
-	p := rsvp.Parameter{
-		Sort:   qh.GetString("sort", ""),
-		Limit:  qh.GetInt("limit", 10),
-		Offset: qh.GetInt("offset", 0),
+	opt := rsvp.ImportOption{
+		Mapping:    map[string]string{},
+		TimeZone:   qh.GetString("tz", ""),
+		DateFormat: qh.GetString("date_format", ""),
+		Delimiter:  qh.GetString("delimiter", ""),
+		DryRun:     qh.GetBool("dry_run", false),
+	}
+
+	for _, pair := range qh.GetStrings("map", nil) {
+		i := strings.LastIndex(pair, ":")
+		if i < 0 {
+			err := response.BadRequestError
+			err.Field = "map"
+			response.Write(w, response.BuildError([]error{err}), err.HTTPCode)
+			return err
+		}
+		opt.Mapping[strings.TrimSpace(pair[:i])] = strings.TrimSpace(pair[i+1:])
 	}
 
-	file, err := h.uc.WriteRsvpsCsv(ctx, &p)
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			err := response.BadRequestError
+			err.Field = "file"
+			response.Write(w, response.BuildError([]error{err}), err.HTTPCode)
+			return err
+		}
+		defer file.Close()
+		body = file
+	}
+
+	result, err := h.uc.ImportRsvpsCsv(r.Context(), body, opt)
 	if err != nil {
 		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
 		response.Write(w, errBody, httpStatus)
 		return err
 	}
 
-	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, file.Name))
-	w.Header().Set("Content-Type", "text/csv")
-	w.Write(file.Content)
+	status := http.StatusCreated
+	if opt.DryRun {
+		status = http.StatusOK
+	}
+
+	m := response.MetaInfo{HTTPStatus: status}
+	response.Write(w, response.BuildSuccess(result, m), status)
+	return nil
+}
+
+func (h *RsvpHandler) download(w http.ResponseWriter, r *http.Request, format string, write func(context.Context, *rsvp.Parameter, rsvp.ExportOption, io.Writer) error) error {
+	ctx := r.Context()
+
+	qh := request.NewQueryHelper(r)
+	p := rsvp.Parameter{
+		Sort:   qh.GetString("sort", ""),
+		Limit:  qh.GetInt("limit", constants.NoLimit),
+		Offset: qh.GetInt("offset", 0),
+	}
+
+	opt := rsvp.ExportOption{
+		Columns:    qh.GetStrings("columns", nil),
+		Language:   qh.GetString("lang", ""),
+		TimeZone:   qh.GetString("tz", ""),
+		DateFormat: qh.GetString("date_format", ""),
+		Delimiter:  qh.GetString("delimiter", ""),
+		BOM:        qh.GetBool("bom", false),
+	}
+
+	timestamp := time.Now().Format("2006-01-02_15-04-05")
+	filename := fmt.Sprintf("rsvp-%s.%s", timestamp, format)
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Header().Set("Content-Type", exportContentTypes[format])
+
+	bw := &bodyWriter{ResponseWriter: w}
+	if err := write(ctx, &p, opt, bw); err != nil {
+		bw.fail(err)
+		return err
+	}
 
 	return nil
 }
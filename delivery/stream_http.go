@@ -0,0 +1,149 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/handler"
+	"github.com/faris-arifiansyah/fws-rsvp/middleware"
+	"github.com/faris-arifiansyah/fws-rsvp/request"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/julienschmidt/httprouter"
+)
+
+// streamRetry is how long a client waits before reconnecting, in milliseconds
+const streamRetry = 3000
+
+// StreamHandler struct
+type StreamHandler struct {
+	uc        rsvp.StreamUsecase
+	auth      *handler.Authenticator
+	heartbeat time.Duration
+}
+
+// NewStreamHandler is a function to create StreamHandler writing a heartbeat every heartbeat
+func NewStreamHandler(uc rsvp.StreamUsecase, auth *handler.Authenticator, heartbeat time.Duration) StreamHandler {
+	return StreamHandler{
+		uc:        uc,
+		auth:      auth,
+		heartbeat: heartbeat,
+	}
+}
+
+func (h *StreamHandler) Register(router *httprouter.Router, ds []middleware.Decorator) error {
+	if router == nil {
+		return fmt.Errorf("router cannot be empty")
+	}
+
+	router.GET("/rsvps/stream", handler.Decorate(h.auth.WithAuth(h.StreamRsvp, rsvp.PermissionRsvpRead), ds...))
+
+	return nil
+}
+
+// StreamRsvp pushes created and updated RSVPs as server-sent events until the client disconnects,
+// resuming after the Last-Event-ID header or last_event_id query when given
+func (h *StreamHandler) StreamRsvp(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	events, err := h.uc.StreamEvents(r.Context(), lastEventID(r))
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	ew, err := startEvents(w)
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for err == nil {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case <-heartbeat.C:
+			err = ew.heartbeat()
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			err = ew.event(e.ID, e.Type, e)
+		}
+	}
+
+	return nil
+}
+
+// lastEventID returns the ID of the last event a reconnecting client received
+func lastEventID(r *http.Request) string {
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+	return request.NewQueryHelper(r).GetString("last_event_id", "")
+}
+
+// eventWriter writes server-sent events, flushing each one. Once a write fails,
+// which means the client is gone, every later write fails with the same error.
+type eventWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	err     error
+}
+
+// startEvents answers with an event stream, failing before writing anything when w cannot stream
+func startEvents(w http.ResponseWriter) (*eventWriter, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, fmt.Errorf("streaming is not supported")
+	}
+
+	// a stream stays open longer than the server's read and write timeouts, which would cut it.
+	// Where the deadlines cannot be lifted, the client reconnects once the stream is cut.
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// keeps proxies such as nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	ew := &eventWriter{w: w, flusher: flusher}
+	ew.write("retry: %d\n\n", streamRetry)
+	return ew, nil
+}
+
+// event writes data as JSON in an event of eventType with id
+func (ew *eventWriter) event(id string, eventType string, data interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	return ew.write("id: %s\nevent: %s\ndata: %s\n\n", id, eventType, b)
+}
+
+// heartbeat writes a comment, which clients ignore
+func (ew *eventWriter) heartbeat() error {
+	return ew.write(": heartbeat\n\n")
+}
+
+func (ew *eventWriter) write(format string, args ...interface{}) error {
+	if ew.err != nil {
+		return ew.err
+	}
+	if _, ew.err = fmt.Fprintf(ew.w, format, args...); ew.err != nil {
+		return ew.err
+	}
+
+	ew.flusher.Flush()
+	return nil
+}
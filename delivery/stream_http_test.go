@@ -0,0 +1,72 @@
+package delivery_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/delivery"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeStreamUsecase sends event once after delay
+type fakeStreamUsecase struct {
+	rsvp.StreamUsecase
+	event rsvp.Event
+	delay time.Duration
+}
+
+func (fs *fakeStreamUsecase) StreamEvents(ctx context.Context, lastID string) (<-chan rsvp.Event, error) {
+	events := make(chan rsvp.Event)
+	go func() {
+		defer close(events)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(fs.delay):
+		}
+		select {
+		case <-ctx.Done():
+		case events <- fs.event:
+		}
+		<-ctx.Done()
+	}()
+
+	return events, nil
+}
+
+func TestStreamOutlivesServerTimeouts(t *testing.T) {
+	assert := assert.New(t)
+
+	uc := &fakeStreamUsecase{event: rsvp.Event{ID: "4e3f1b9c", Type: rsvp.EventRsvpCreated}, delay: 300 * time.Millisecond}
+	h := delivery.NewStreamHandler(uc, nil, 50*time.Millisecond)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.StreamRsvp(w, r, nil)
+	}))
+	server.Config.ReadTimeout = 100 * time.Millisecond
+	server.Config.WriteTimeout = 100 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	assert.NoError(err)
+	defer resp.Body.Close()
+	assert.Equal("text/event-stream", resp.Header.Get("Content-Type"))
+
+	// the event arrives after both timeouts have passed
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "id: ") {
+			assert.Equal("id: 4e3f1b9c", scanner.Text())
+			return
+		}
+	}
+	t.Fatalf("stream ended before the event: %v", scanner.Err())
+}
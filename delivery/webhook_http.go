@@ -0,0 +1,133 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/handler"
+	"github.com/faris-arifiansyah/fws-rsvp/middleware"
+	"github.com/faris-arifiansyah/fws-rsvp/request"
+	"github.com/faris-arifiansyah/fws-rsvp/request/validator"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/julienschmidt/httprouter"
+)
+
+// WebhookHandler struct
+type WebhookHandler struct {
+	uc   rsvp.WebhookUsecase
+	auth *handler.Authenticator
+}
+
+func NewWebhookHandler(uc rsvp.WebhookUsecase, auth *handler.Authenticator) WebhookHandler {
+	return WebhookHandler{
+		uc:   uc,
+		auth: auth,
+	}
+}
+
+func (h *WebhookHandler) Register(router *httprouter.Router, ds []middleware.Decorator) error {
+	if router == nil {
+		return fmt.Errorf("router cannot be empty")
+	}
+
+	router.GET("/webhooks", handler.Decorate(h.auth.WithAuth(h.RetrieveAllWebhook, rsvp.PermissionWebhookManage), ds...))
+	router.POST("/webhooks", handler.Decorate(h.auth.WithAuth(h.CreateWebhook, rsvp.PermissionWebhookManage), ds...))
+	router.DELETE("/webhooks/:id", handler.Decorate(h.auth.WithAuth(h.DeleteWebhook, rsvp.PermissionWebhookManage), ds...))
+	router.GET("/webhooks/:id/deliveries", handler.Decorate(h.auth.WithAuth(h.RetrieveAllDelivery, rsvp.PermissionWebhookManage), ds...))
+	router.POST("/webhooks/:id/deliveries/:delivery/redeliver", handler.Decorate(h.auth.WithAuth(h.Redeliver, rsvp.PermissionWebhookManage), ds...))
+
+	return nil
+}
+
+func (h *WebhookHandler) RetrieveAllWebhook(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	webhooks, err := h.uc.GetWebhooks(r.Context())
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusOK, Total: int64(len(webhooks))}
+	response.Write(w, response.BuildSuccess(webhooks, m), http.StatusOK)
+	return nil
+}
+
+// CreateWebhook responds with the secret of the webhook, which is not shown again
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	var req rsvp.WebhookRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+	defer r.Body.Close()
+
+	if errs := validator.Validate(req); len(errs) > 0 {
+		response.Write(w, response.BuildErrors(errs), http.StatusBadRequest)
+		return errs[0]
+	}
+
+	wh, err := h.uc.CreateWebhook(r.Context(), req, middleware.Actor(r.Context()))
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusCreated}
+	response.Write(w, response.BuildSuccess(wh, m), http.StatusCreated)
+	return nil
+}
+
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	if err := h.uc.DeleteWebhook(r.Context(), params.ByName("id")); err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusOK}
+	response.Write(w, response.BuildSuccess("webhook deleted", m), http.StatusOK)
+	return nil
+}
+
+func (h *WebhookHandler) RetrieveAllDelivery(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	qh := request.NewQueryHelper(r)
+	p := rsvp.Parameter{
+		Limit:  qh.GetInt("limit", 10),
+		Offset: qh.GetInt("offset", 0),
+	}
+
+	result, err := h.uc.GetWebhookDeliveries(r.Context(), params.ByName("id"), &p)
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{
+		HTTPStatus: http.StatusOK,
+		Limit:      p.Limit,
+		Offset:     p.Offset,
+		Total:      result.Total,
+	}
+
+	response.Write(w, response.BuildSuccess(result.Data, m), http.StatusOK)
+	return nil
+}
+
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	d, err := h.uc.Redeliver(r.Context(), params.ByName("id"), params.ByName("delivery"))
+	if err != nil {
+		errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+		response.Write(w, errBody, httpStatus)
+		return err
+	}
+
+	m := response.MetaInfo{HTTPStatus: http.StatusAccepted}
+	response.Write(w, response.BuildSuccess(d, m), http.StatusAccepted)
+	return nil
+}
@@ -0,0 +1,42 @@
+package delivery
+
+import (
+	"net/http"
+
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+)
+
+// bodyWriter remembers whether the body has been started, so a handler
+// streaming a file can still answer with a JSON error when it fails early
+type bodyWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (bw *bodyWriter) Write(b []byte) (int, error) {
+	bw.started = true
+	return bw.ResponseWriter.Write(b)
+}
+
+func (bw *bodyWriter) Flush() {
+	if f, ok := bw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (bw *bodyWriter) Unwrap() http.ResponseWriter {
+	return bw.ResponseWriter
+}
+
+// fail writes err as JSON unless the body has been started,
+// in which case the truncated download is all the client gets
+func (bw *bodyWriter) fail(err error) {
+	if bw.started {
+		return
+	}
+
+	bw.Header().Del("Content-Disposition")
+	errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+	response.Write(bw.ResponseWriter, errBody, httpStatus)
+}
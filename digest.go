@@ -0,0 +1,85 @@
+package rsvp
+
+import (
+	"context"
+	"time"
+
+	"github.com/faris-arifiansyah/fws-rsvp/enumeration"
+)
+
+// Digest schedules
+const (
+	DigestOff    = "off"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// Digest statuses
+const (
+	DigestPending = "pending"
+	DigestSent    = "sent"
+	DigestFailed  = "failed"
+)
+
+// Digest is a digest report run for a scheduled time
+type Digest struct {
+	// ID is the scheduled time in RFC 3339, so a schedule runs once
+	ID        string     `json:"id" bson:"_id"`
+	Schedule  string     `json:"schedule" bson:"schedule"`
+	From      time.Time  `json:"from" bson:"from"`
+	To        time.Time  `json:"to" bson:"to"`
+	Status    string     `json:"status" bson:"status"`
+	Error     string     `json:"error,omitempty" bson:"error,omitempty"`
+	CreatedAt time.Time  `json:"created_at" bson:"created_at"`
+	SentAt    *time.Time `json:"sent_at,omitempty" bson:"sent_at,omitempty"`
+}
+
+// AttendanceChange is an RSVP whose attendance changed from Previous
+type AttendanceChange struct {
+	Rsvp     Rsvp
+	Previous enumeration.AttendanceType
+}
+
+// DigestReport summarises the responses from From until To
+type DigestReport struct {
+	From     time.Time
+	To       time.Time
+	NewRsvps []Rsvp
+	// Messages are the RSVPs with a new or edited message, as last written
+	Messages  []Rsvp
+	Changes   []AttendanceChange
+	Cancelled []Rsvp
+	Totals    AttendanceSummary
+}
+
+// IsEmpty reports whether nothing happened in the period of the report
+func (dr *DigestReport) IsEmpty() bool {
+	return len(dr.NewRsvps) == 0 && len(dr.Messages) == 0 && len(dr.Changes) == 0 && len(dr.Cancelled) == 0
+}
+
+// DigestSender delivers a rendered digest report, by email or any other channel
+type DigestSender interface {
+	SendDigest(ctx context.Context, subject string, text string, html string) error
+}
+
+// DigestReportSender formats and delivers a digest report itself, such as a chat message.
+// The times of the report are in the digest time zone.
+type DigestReportSender interface {
+	SendDigestReport(ctx context.Context, schedule string, report *DigestReport) error
+}
+
+// DigestRepo provides data interchange between
+// application and data provider.
+type DigestRepo interface {
+	// ClaimDigest saves d unless a digest with its ID exists, reporting whether it did
+	ClaimDigest(ctx context.Context, d Digest) (bool, error)
+	UpdateDigest(ctx context.Context, d Digest) error
+	GetLastSentDigest(ctx context.Context) (*Digest, error)
+}
+
+type DigestUsecase interface {
+	GetDigestReport(ctx context.Context, from time.Time, to time.Time) (*DigestReport, error)
+	// RunDigestScheduler sends a digest on every scheduled time until ctx is done,
+	// and right away when the last scheduled one was missed
+	RunDigestScheduler(ctx context.Context)
+}
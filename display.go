@@ -0,0 +1,69 @@
+package rsvp
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Display event types
+const (
+	WishShown     = "wish"
+	WishWithdrawn = "withdrawn"
+)
+
+// Wish is an approved message shown on the display. It leaves out the address and email of the guest.
+type Wish struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Message    string    `json:"message"`
+	ApprovedAt time.Time `json:"approved_at"`
+}
+
+// NewWish returns the wish of rp, which must be approved
+func NewWish(rp Rsvp) Wish {
+	return Wish{
+		ID:         rp.ID.Hex(),
+		Name:       rp.Name,
+		Message:    strings.TrimSpace(rp.Message),
+		ApprovedAt: *rp.ApprovedAt,
+	}
+}
+
+// WishEvent tells the display to show a wish or to take it down. ID is the ID of the
+// RSVP event it comes from, so a display can resume after it.
+type WishEvent struct {
+	ID   string
+	Type string
+	Wish Wish
+}
+
+// DisplayFilter selects the messages shown on the display by their length in characters,
+// without an upper bound when MaxLength is zero
+type DisplayFilter struct {
+	MinLength int
+	MaxLength int
+}
+
+// Allows reports whether the message of rp is approved and as long as f requires
+func (f DisplayFilter) Allows(rp Rsvp) bool {
+	message := strings.TrimSpace(rp.Message)
+	if rp.ApprovedAt == nil || message == "" {
+		return false
+	}
+
+	length := utf8.RuneCountInString(message)
+	return length >= f.MinLength && (f.MaxLength == 0 || length <= f.MaxLength)
+}
+
+type DisplayUsecase interface {
+	// GetWishes returns the wishes that pass f, oldest approval first
+	GetWishes(ctx context.Context, f DisplayFilter) ([]Wish, error)
+	// GetWishesToStream returns the wishes that pass f like GetWishes, along with the ID
+	// StreamWishes resumes after to receive every wish approved or withdrawn since
+	GetWishesToStream(ctx context.Context, f DisplayFilter) ([]Wish, string, error)
+	// StreamWishes returns the wishes that pass f as they are approved or withdrawn, resuming
+	// after the RSVP event with lastID when it is not empty. The channel is closed once ctx is done.
+	StreamWishes(ctx context.Context, f DisplayFilter, lastID string) (<-chan WishEvent, error)
+}
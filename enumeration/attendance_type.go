@@ -2,6 +2,8 @@ package enumeration
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 )
 
 type AttendanceType int16
@@ -18,9 +20,66 @@ var atMap = map[AttendanceType]string{
 	AttendanceTypeMaybe: "Maybe",
 }
 
+var atMapID = map[AttendanceType]string{
+	AttendanceTypeNo:    "Tidak",
+	AttendanceTypeYes:   "Ya",
+	AttendanceTypeMaybe: "Mungkin",
+}
+
+var atEmoji = map[AttendanceType]string{
+	AttendanceTypeNo:    "❌",
+	AttendanceTypeYes:   "✅",
+	AttendanceTypeMaybe: "🤔",
+}
+
+// Emoji returns a symbol of at for chat messages, a question mark when at is unknown
+func (at AttendanceType) Emoji() string {
+	if str, ok := atEmoji[at]; ok {
+		return str
+	}
+	return "❔"
+}
+
+// Label returns the name of at in language lang, "id" for Indonesian or English otherwise
+func (at AttendanceType) Label(lang string) string {
+	if lang == "id" {
+		if str, ok := atMapID[at]; ok {
+			return str
+		}
+	}
+	return at.String()
+}
+
+// IsValid reports whether at is a known attendance type
+func (at AttendanceType) IsValid() bool {
+	_, ok := atMap[at]
+	return ok
+}
+
 func (at AttendanceType) String() string {
 	if str, ok := atMap[at]; ok {
 		return str
 	}
 	return fmt.Sprintf("AttendanceType(%d)", at)
 }
+
+// ParseAttendanceType parses s as a number or an English or Indonesian name of an attendance type, ignoring case
+func ParseAttendanceType(s string) (AttendanceType, error) {
+	s = strings.TrimSpace(s)
+
+	if n, err := strconv.Atoi(s); err == nil {
+		if at := AttendanceType(n); at.IsValid() {
+			return at, nil
+		}
+	}
+
+	for _, names := range []map[AttendanceType]string{atMap, atMapID} {
+		for at, name := range names {
+			if strings.EqualFold(name, s) {
+				return at, nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("invalid attendance type %q", s)
+}
@@ -36,3 +36,97 @@ func TestAttendanceTypeString(t *testing.T) {
 		assert.Equal(tc.expectedStr, tc.attendanceType.String())
 	}
 }
+
+func TestAttendanceTypeLabel(t *testing.T) {
+	assert := assert.New(t)
+
+	testCases := []struct {
+		attendanceType enumeration.AttendanceType
+		lang           string
+		expectedLabel  string
+	}{
+		{
+			attendanceType: enumeration.AttendanceTypeYes,
+			lang:           "id",
+			expectedLabel:  "Ya",
+		},
+		{
+			attendanceType: enumeration.AttendanceTypeNo,
+			lang:           "id",
+			expectedLabel:  "Tidak",
+		},
+		{
+			attendanceType: enumeration.AttendanceTypeMaybe,
+			lang:           "en",
+			expectedLabel:  "Maybe",
+		},
+		{
+			attendanceType: enumeration.AttendanceType(-1),
+			lang:           "id",
+			expectedLabel:  "AttendanceType(-1)",
+		},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(tc.expectedLabel, tc.attendanceType.Label(tc.lang))
+	}
+}
+
+func TestAttendanceTypeEmoji(t *testing.T) {
+	assert := assert.New(t)
+
+	testCases := []struct {
+		attendanceType enumeration.AttendanceType
+		expectedEmoji  string
+	}{
+		{
+			attendanceType: enumeration.AttendanceTypeYes,
+			expectedEmoji:  "✅",
+		},
+		{
+			attendanceType: enumeration.AttendanceTypeNo,
+			expectedEmoji:  "❌",
+		},
+		{
+			attendanceType: enumeration.AttendanceTypeMaybe,
+			expectedEmoji:  "🤔",
+		},
+		{
+			attendanceType: enumeration.AttendanceType(-1),
+			expectedEmoji:  "❔",
+		},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(tc.expectedEmoji, tc.attendanceType.Emoji())
+	}
+}
+
+func TestParseAttendanceType(t *testing.T) {
+	assert := assert.New(t)
+
+	testCases := []struct {
+		value                  string
+		expectedAttendanceType enumeration.AttendanceType
+		expectedError          bool
+	}{
+		{value: "Yes", expectedAttendanceType: enumeration.AttendanceTypeYes},
+		{value: " tidak ", expectedAttendanceType: enumeration.AttendanceTypeNo},
+		{value: "MUNGKIN", expectedAttendanceType: enumeration.AttendanceTypeMaybe},
+		{value: "1", expectedAttendanceType: enumeration.AttendanceTypeYes},
+		{value: "3", expectedError: true},
+		{value: "hadir", expectedError: true},
+		{value: "", expectedError: true},
+	}
+
+	for _, tc := range testCases {
+		at, err := enumeration.ParseAttendanceType(tc.value)
+		if tc.expectedError {
+			assert.Error(err, tc.value)
+			continue
+		}
+
+		assert.NoError(err, tc.value)
+		assert.Equal(tc.expectedAttendanceType, at, tc.value)
+	}
+}
@@ -0,0 +1,104 @@
+package rsvp
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+// Export formats
+const (
+	ExportFormatCsv    = "csv"
+	ExportFormatXlsx   = "xlsx"
+	ExportFormatNdjson = "ndjson"
+)
+
+// Export job statuses
+const (
+	ExportJobPending = "pending"
+	ExportJobRunning = "running"
+	ExportJobDone    = "done"
+	ExportJobFailed  = "failed"
+	ExportJobExpired = "expired"
+)
+
+// ExportJob Entity, an export rendered in the background.
+// Rows counts the rows written so far out of Total, Progress is their percentage.
+// A running job is leased to a worker until LeaseUntil, after which another worker renders it again.
+// Claim tells the workers apart, so one whose lease ran out no longer updates the job.
+type ExportJob struct {
+	ID         bson.ObjectId `json:"id" bson:"_id,omitempty"`
+	Format     string        `json:"format" bson:"format"`
+	Parameter  Parameter     `json:"-" bson:"parameter"`
+	Option     ExportOption  `json:"-" bson:"option"`
+	Status     string        `json:"status" bson:"status"`
+	Rows       int64         `json:"rows" bson:"rows"`
+	Total      int64         `json:"total" bson:"total"`
+	Progress   int           `json:"progress" bson:"-"`
+	Error      string        `json:"error,omitempty" bson:"error,omitempty"`
+	FileName   string        `json:"file_name,omitempty" bson:"file_name,omitempty"`
+	Size       int64         `json:"size,omitempty" bson:"size,omitempty"`
+	CreatedBy  string        `json:"created_by" bson:"created_by"`
+	CreatedAt  time.Time     `json:"created_at" bson:"created_at"`
+	StartedAt  *time.Time    `json:"started_at,omitempty" bson:"started_at,omitempty"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty" bson:"finished_at,omitempty"`
+	ExpiresAt  *time.Time    `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	LeaseUntil *time.Time    `json:"-" bson:"lease_until,omitempty"`
+	Claim      string        `json:"-" bson:"claim,omitempty"`
+}
+
+// ExportJobRequest holds data submitted to start an export job, see ExportOption for the layout fields
+type ExportJobRequest struct {
+	Format     string   `json:"format,required"`
+	Sort       string   `json:"sort"`
+	Limit      *int     `json:"limit"`
+	Offset     int      `json:"offset"`
+	Columns    []string `json:"columns"`
+	Language   string   `json:"lang"`
+	TimeZone   string   `json:"tz"`
+	DateFormat string   `json:"date_format"`
+	Delimiter  string   `json:"delimiter"`
+	BOM        bool     `json:"bom"`
+}
+
+// StoredFile is a file read from a FileStore
+type StoredFile interface {
+	io.ReadSeeker
+	io.Closer
+}
+
+// FileStore keeps rendered files by name
+type FileStore interface {
+	Create(ctx context.Context, name string) (io.WriteCloser, error)
+	Open(ctx context.Context, name string) (StoredFile, error)
+	Remove(ctx context.Context, name string) error
+}
+
+// ExportJobRepo provides data interchange between
+// application and export job data provider.
+type ExportJobRepo interface {
+	CreateExportJob(ctx context.Context, job ExportJob) (ExportJob, error)
+	GetExportJob(ctx context.Context, id string) (*ExportJob, error)
+	// ClaimExportJob marks the oldest pending job, or a running one whose lease is over at now,
+	// as running from the first row until lease under a new claim and returns it, or returns
+	// response.NotFoundError when there is none
+	ClaimExportJob(ctx context.Context, now time.Time, lease time.Time) (*ExportJob, error)
+	UpdateExportJob(ctx context.Context, job ExportJob) error
+	// UpdateClaimedExportJob saves job while it is held under job.Claim, or returns
+	// response.NotFoundError when another worker claimed it since
+	UpdateClaimedExportJob(ctx context.Context, job ExportJob) error
+	// UpdateExportJobRows records the rows written by the job with id while it is held under claim,
+	// extending its lease to lease, or returns response.NotFoundError when another worker claimed it since
+	UpdateExportJobRows(ctx context.Context, id bson.ObjectId, claim string, rows int64, lease time.Time) error
+	GetExpiredExportJobs(ctx context.Context, now time.Time) ([]*ExportJob, error)
+}
+
+type ExportJobUsecase interface {
+	CreateExportJob(ctx context.Context, req ExportJobRequest, createdBy string) (ExportJob, error)
+	GetExportJob(ctx context.Context, id string) (*ExportJob, error)
+	OpenExportFile(ctx context.Context, id string) (*ExportJob, StoredFile, error)
+	// RunExportWorkers renders pending jobs with workers goroutines until ctx is done
+	RunExportWorkers(ctx context.Context, workers int)
+}
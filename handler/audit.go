@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/middleware"
+	"github.com/julienschmidt/httprouter"
+)
+
+// statusRecorder remembers the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	sr.status = status
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+// Flush keeps streaming responses working through the recorder
+func (sr *statusRecorder) Flush() {
+	if f, ok := sr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
+
+func (a *Authenticator) audit(r *http.Request, params httprouter.Params, sr *statusRecorder) {
+	var targets []string
+	for _, p := range params {
+		targets = append(targets, p.Value)
+	}
+
+	// A signature could be replayed until the link expires, so it is not kept
+	query := r.URL.Query()
+	query.Del(linkSignature)
+
+	ctx := r.Context()
+	err := a.audits.Record(ctx, rsvp.AuditEntry{
+		Actor:     middleware.Actor(ctx),
+		Action:    r.Method + " " + r.URL.Path,
+		Target:    strings.Join(targets, "/"),
+		Query:     query.Encode(),
+		Status:    sr.status,
+		RequestID: middleware.RequestID(ctx),
+		IP:        middleware.ClientIP(ctx),
+	})
+	if err != nil {
+		log.Printf("failed to record audit entry of request %s: %s\n", middleware.RequestID(ctx), err)
+	}
+}
@@ -0,0 +1,137 @@
+package handler_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/handler"
+	"github.com/faris-arifiansyah/fws-rsvp/middleware"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAdmins authenticates the token "valid" as the admin faris,
+// and the password "budi-password" of budi, who enabled TOTP
+type fakeAdmins struct {
+	rsvp.AdminUsecase
+}
+
+func (fa fakeAdmins) Authenticate(ctx context.Context, cred rsvp.AdminCredential) (*rsvp.AdminUser, error) {
+	if cred.Username != "budi" || cred.Password != "budi-password" {
+		return nil, response.UserUnauthorizedError
+	}
+	if cred.OTP == "" {
+		return nil, response.OTPRequiredError
+	}
+	return &rsvp.AdminUser{Username: "budi", Role: rsvp.RoleViewer, TOTPEnabled: true}, nil
+}
+
+func (fa fakeAdmins) AuthenticateToken(ctx context.Context, token string) (*rsvp.AdminUser, error) {
+	if token != "valid" {
+		return nil, response.UserUnauthorizedError
+	}
+	return &rsvp.AdminUser{Username: "faris", Role: rsvp.RoleViewer}, nil
+}
+
+// fakeRoles only grants rsvps:read
+type fakeRoles struct {
+	rsvp.RoleUsecase
+}
+
+func (fr fakeRoles) Authorize(ctx context.Context, admin *rsvp.AdminUser, p rsvp.Permission) error {
+	if p != rsvp.PermissionRsvpRead {
+		return response.UserUnauthorizedError
+	}
+	return nil
+}
+
+// fakeAudits keeps the recorded entries in memory
+type fakeAudits struct {
+	rsvp.AuditUsecase
+	sync.Mutex
+	entries []rsvp.AuditEntry
+}
+
+func (fa *fakeAudits) Record(ctx context.Context, e rsvp.AuditEntry) error {
+	fa.Lock()
+	defer fa.Unlock()
+
+	fa.entries = append(fa.entries, e)
+	return nil
+}
+
+func TestWithAuthAudit(t *testing.T) {
+	resolver, err := middleware.NewClientIPResolver(nil)
+	assert.NoError(t, err)
+
+	ok := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+		w.WriteHeader(http.StatusCreated)
+		return nil
+	}
+
+	tests := []struct {
+		name       string
+		permission rsvp.Permission
+		url        string
+		token      string
+		expected   []rsvp.AuditEntry
+	}{
+		{
+			name:       "authorized",
+			permission: rsvp.PermissionRsvpRead,
+			url:        "/rsvps/5c4a?limit=5&signature=abc",
+			token:      "valid",
+			expected: []rsvp.AuditEntry{{
+				Actor: "admin:faris", Action: "GET /rsvps/5c4a", Target: "5c4a", Query: "limit=5", Status: http.StatusCreated, RequestID: "req-1", IP: "192.0.2.1",
+			}},
+		},
+		{
+			name:       "forbidden",
+			permission: rsvp.PermissionRsvpDelete,
+			url:        "/rsvps/5c4a",
+			token:      "valid",
+			expected: []rsvp.AuditEntry{{
+				Actor: "admin:faris", Action: "GET /rsvps/5c4a", Target: "5c4a", Status: http.StatusForbidden, RequestID: "req-1", IP: "192.0.2.1",
+			}},
+		},
+		{
+			name:       "unauthenticated",
+			permission: rsvp.PermissionRsvpRead,
+			url:        "/rsvps/5c4a",
+			token:      "invalid",
+			expected: []rsvp.AuditEntry{{
+				Action: "GET /rsvps/5c4a", Target: "5c4a", Status: http.StatusForbidden, RequestID: "req-1", IP: "192.0.2.1",
+			}},
+		},
+		{
+			name:       "anonymous",
+			permission: handler.Anonymous,
+			url:        "/rsvps/5c4a",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			audits := &fakeAudits{}
+			auth := handler.NewAuthenticator(fakeAdmins{}, fakeRoles{}, nil, audits, nil, nil)
+
+			router := httprouter.New()
+			router.GET("/rsvps/:id", handler.Decorate(auth.WithAuth(ok, test.permission), middleware.WithStandardContext(resolver)))
+
+			r := httptest.NewRequest(http.MethodGet, test.url, nil)
+			r.RemoteAddr = "192.0.2.1:1234"
+			r.Header.Set("X-Request-ID", "req-1")
+			if test.token != "" {
+				r.Header.Set("Authorization", "Bearer "+test.token)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), r)
+
+			assert.Equal(t, test.expected, audits.entries)
+		})
+	}
+}
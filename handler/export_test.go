@@ -0,0 +1,7 @@
+package handler
+
+// TakeScript and RefundScript are the Lua scripts of the rate limiter, for a fake Redis to emulate
+const (
+	TakeScript   = takeScript
+	RefundScript = refundScript
+)
@@ -1,21 +1,24 @@
 package handler
 
 import (
+	"context"
 	"fmt"
 	"net/http"
-	"os"
+	"strings"
 
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
 	"github.com/faris-arifiansyah/fws-rsvp/middleware"
 	"github.com/faris-arifiansyah/fws-rsvp/response"
 	"github.com/julienschmidt/httprouter"
 )
 
-// AuthType is custom type for decorator key type
-type AuthType string
+type ctxKey string
 
 const (
-	Anonymous AuthType = "anonymous"
-	Admin     AuthType = "admin"
+	// Anonymous is required by routes open to everyone
+	Anonymous rsvp.Permission = ""
+	// Authenticated is required by routes open to every admin regardless of role
+	Authenticated rsvp.Permission = "authenticated"
 )
 
 type Registration interface {
@@ -33,14 +36,14 @@ func NotFound(w http.ResponseWriter, _ *http.Request) {
 	response.Write(w, res, meta.HTTPStatus)
 }
 
-func NewHandler(registrations ...Registration) (http.Handler, error) {
+func NewHandler(resolver *middleware.ClientIPResolver, registrations ...Registration) (http.Handler, error) {
 	router := httprouter.New()
 	router.HandleMethodNotAllowed = false
 
 	router.HandlerFunc("GET", "/healthz", Healthz)
 
 	// decorator for delivery
-	sd := middleware.StandardDecorators()
+	sd := middleware.StandardDecorators(resolver)
 
 	// start route
 	for _, reg := range registrations {
@@ -52,27 +55,160 @@ func NewHandler(registrations ...Registration) (http.Handler, error) {
 	return router, nil
 }
 
-func WithAuth(h func(http.ResponseWriter, *http.Request, httprouter.Params) error, authType AuthType) middleware.HandleWithError {
+// Authenticator authenticates admin requests against the admin user store
+// and authorizes them against the role of the admin
+type Authenticator struct {
+	admins   rsvp.AdminUsecase
+	roles    rsvp.RoleUsecase
+	apiKeys  rsvp.APIKeyUsecase
+	audits   rsvp.AuditUsecase
+	throttle *LoginThrottle
+	signer   *LinkSigner
+}
+
+func NewAuthenticator(admins rsvp.AdminUsecase, roles rsvp.RoleUsecase, apiKeys rsvp.APIKeyUsecase, audits rsvp.AuditUsecase, throttle *LoginThrottle, signer *LinkSigner) *Authenticator {
+	return &Authenticator{
+		admins:   admins,
+		roles:    roles,
+		apiKeys:  apiKeys,
+		audits:   audits,
+		throttle: throttle,
+		signer:   signer,
+	}
+}
+
+// WithAuth decorates handler with authentication and the permission it requires.
+// Admin requests are accepted with a session token in a Bearer Authorization
+// header, or with Basic Auth credentials of admins without TOTP. A TOTP code is
+// accepted once, so admins with TOTP log in for a token.
+// Machine requests are accepted with an X-API-Key header whose scopes include permission.
+// The authenticated admin or API key is available to the handler through
+// AdminFromContext and APIKeyFromContext.
+// Every request to a route that is not anonymous is recorded in the audit log,
+// whether or not it is authorized.
+func (a *Authenticator) WithAuth(h func(http.ResponseWriter, *http.Request, httprouter.Params) error, permission rsvp.Permission) middleware.HandleWithError {
 	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
-		if authType == Admin {
-			headerUsername, headerPass, ok := r.BasicAuth()
+		if permission == Anonymous {
+			return h(w, r, params)
+		}
+
+		sr := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer a.audit(r, params, sr)
+
+		var ctx context.Context
+		var err error
+
+		if key := r.Header.Get("X-API-Key"); key != "" {
+			ctx, err = a.authenticateAPIKey(sr, r, key, permission)
+		} else {
+			ctx, err = a.authenticateAdmin(sr, r, permission)
+		}
 
-			if !ok {
-				response.Write(w, response.BuildError([]error{response.UserUnauthorizedError}), response.UserUnauthorizedError.HTTPCode)
-				return response.UserUnauthorizedError
-			}
+		if err != nil {
+			errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+			response.Write(sr, errBody, httpStatus)
+			return err
+		}
+
+		return h(sr, r.WithContext(ctx), params)
+	}
+}
+
+func (a *Authenticator) authenticateAdmin(w http.ResponseWriter, r *http.Request, permission rsvp.Permission) (context.Context, error) {
+	var admin *rsvp.AdminUser
+	var err error
+
+	if token := BearerToken(r); token != "" {
+		admin, err = a.admins.AuthenticateToken(r.Context(), token)
+	} else if headerUsername, headerPass, ok := r.BasicAuth(); ok {
+		err = a.Guard(w, r, headerUsername, func() (err error) {
+			admin, err = a.admins.Authenticate(r.Context(), rsvp.AdminCredential{
+				Username: headerUsername,
+				Password: headerPass,
+			})
+			return err
+		})
+		if err == response.OTPRequiredError {
+			err = response.SessionRequiredError
+		}
+	} else {
+		err = response.UserUnauthorizedError
+	}
+	if err != nil {
+		return nil, err
+	}
 
-			username := os.Getenv("FWS_RSVP_USERNAME")
-			pass := os.Getenv("FWS_RSVP_PASSWORD")
+	middleware.SetActor(r.Context(), "admin:"+admin.Username)
 
-			if username != headerUsername || pass != headerPass {
-				response.Write(w, response.BuildError([]error{response.UserUnauthorizedError}), response.UserUnauthorizedError.HTTPCode)
-				return response.UserUnauthorizedError
-			}
+	if permission != Authenticated {
+		if err = a.roles.Authorize(r.Context(), admin, permission); err != nil {
+			return nil, err
 		}
+	}
+
+	return context.WithValue(r.Context(), ctxKey("Admin"), admin), nil
+}
+
+// API keys act for machines, so routes open to every admin are not open to them
+// Unknown keys count towards the lockout of the client IP.
+func (a *Authenticator) authenticateAPIKey(w http.ResponseWriter, r *http.Request, key string, permission rsvp.Permission) (context.Context, error) {
+	var k *rsvp.APIKey
+	ctx := r.Context()
+
+	err := a.Guard(w, r, "", func() (err error) {
+		k, err = a.apiKeys.AuthenticateAPIKey(ctx, key)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	middleware.SetActor(ctx, "api_key:"+k.Name)
+
+	if permission == Authenticated || !k.Allows(permission) {
+		return nil, response.UserUnauthorizedError
+	}
+
+	return context.WithValue(ctx, ctxKey("APIKey"), k), nil
+}
+
+// BearerToken returns the token of a Bearer Authorization header, or empty string if there is none
+func BearerToken(r *http.Request) string {
+	const prefix = "bearer "
+
+	header := r.Header.Get("Authorization")
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return ""
+	}
+
+	return strings.TrimSpace(header[len(prefix):])
+}
+
+// Authorize returns response.UserUnauthorizedError unless the admin or API key authenticated by WithAuth has permission.
+// It is for handlers whose required permission depends on the request.
+func (a *Authenticator) Authorize(ctx context.Context, permission rsvp.Permission) error {
+	if k := APIKeyFromContext(ctx); k != nil && k.Allows(permission) {
+		return nil
+	}
 
-		return h(w, r, params)
+	admin := AdminFromContext(ctx)
+	if admin == nil {
+		return response.UserUnauthorizedError
 	}
+
+	return a.roles.Authorize(ctx, admin, permission)
+}
+
+// AdminFromContext returns the admin authenticated by WithAuth, or nil if the request is not made by an admin
+func AdminFromContext(ctx context.Context) *rsvp.AdminUser {
+	admin, _ := ctx.Value(ctxKey("Admin")).(*rsvp.AdminUser)
+	return admin
+}
+
+// APIKeyFromContext returns the API key authenticated by WithAuth, or nil if the request is not made with an API key
+func APIKeyFromContext(ctx context.Context) *rsvp.APIKey {
+	k, _ := ctx.Value(ctxKey("APIKey")).(*rsvp.APIKey)
+	return k
 }
 
 // Decorate util to simplify combining middleware
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/middleware"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/go-redis/redis"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Query parameters reserved by signed links
+const (
+	linkExpires   = "expires"
+	linkNonce     = "nonce"
+	linkBy        = "by"
+	linkSignature = "signature"
+)
+
+// LinkSigner mints and verifies expiring links signed with HMAC-SHA256,
+// optionally usable only once
+type LinkSigner struct {
+	secret  []byte
+	baseURL string
+	rds     *redis.Client
+	prefix  string
+}
+
+// SignedLink holds a minted link
+type SignedLink struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+	SingleUse bool      `json:"single_use"`
+}
+
+// NewLinkSigner is a function to create LinkSigner minting links under baseURL
+func NewLinkSigner(secret []byte, baseURL string, rds *redis.Client, prefix string) *LinkSigner {
+	return &LinkSigner{
+		secret:  secret,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		rds:     rds,
+		prefix:  prefix,
+	}
+}
+
+// IsReserved reports whether key is a query parameter set by the signer
+func IsReserved(key string) bool {
+	return key == linkExpires || key == linkNonce || key == linkBy || key == linkSignature
+}
+
+// Sign returns a link to path with query, valid until expiresAt and
+// recording actor as the one who minted it
+func (ls *LinkSigner) Sign(path string, query url.Values, expiresAt time.Time, singleUse bool, actor string) (SignedLink, error) {
+	q := url.Values{}
+	for k, v := range query {
+		if !IsReserved(k) {
+			q[k] = v
+		}
+	}
+
+	q.Set(linkExpires, strconv.FormatInt(expiresAt.Unix(), 10))
+	q.Set(linkBy, actor)
+	if singleUse {
+		nonce, err := uuid.NewRandom()
+		if err != nil {
+			return SignedLink{}, err
+		}
+		q.Set(linkNonce, nonce.String())
+	}
+
+	q.Set(linkSignature, ls.signature(path, q))
+
+	return SignedLink{
+		URL:       ls.baseURL + path + "?" + q.Encode(),
+		ExpiresAt: expiresAt.Truncate(time.Second),
+		SingleUse: singleUse,
+	}, nil
+}
+
+// Verify returns response.InvalidLinkError unless r is a request to an unexpired signed link.
+// A single use link is spent by its first successful verification.
+func (ls *LinkSigner) Verify(r *http.Request) error {
+	q := r.URL.Query()
+
+	expected := ls.signature(r.URL.Path, q)
+	if !hmac.Equal([]byte(expected), []byte(q.Get(linkSignature))) {
+		return response.InvalidLinkError
+	}
+
+	expires, err := strconv.ParseInt(q.Get(linkExpires), 10, 64)
+	if err != nil {
+		return response.InvalidLinkError
+	}
+
+	left := time.Until(time.Unix(expires, 0))
+	if left <= 0 {
+		return response.InvalidLinkError
+	}
+
+	if nonce := q.Get(linkNonce); nonce != "" {
+		fresh, err := ls.rds.SetNX(ls.prefix+"link:"+nonce, 1, left).Result()
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return response.InvalidLinkError
+		}
+	}
+
+	return nil
+}
+
+// signature signs path and every query parameter but the signature itself
+func (ls *LinkSigner) signature(path string, query url.Values) string {
+	q := url.Values{}
+	for k, v := range query {
+		if k != linkSignature {
+			q[k] = v
+		}
+	}
+
+	mac := hmac.New(sha256.New, ls.secret)
+	mac.Write([]byte(path + "?" + q.Encode()))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// WithSignedLink decorates handler like WithAuth, but a request to a link signed
+// by the signer of the Authenticator is accepted instead of credentials.
+// Such requests are audited with the admin who minted the link.
+func (a *Authenticator) WithSignedLink(h func(http.ResponseWriter, *http.Request, httprouter.Params) error, permission rsvp.Permission) middleware.HandleWithError {
+	withAuth := a.WithAuth(h, permission)
+
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+		if r.URL.Query().Get(linkSignature) == "" {
+			return withAuth(w, r, params)
+		}
+
+		sr := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer a.audit(r, params, sr)
+
+		if err := a.signer.Verify(r); err != nil {
+			errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+			response.Write(sr, errBody, httpStatus)
+			return err
+		}
+
+		middleware.SetActor(r.Context(), "signed_link:"+r.URL.Query().Get(linkBy))
+
+		return h(sr, r, params)
+	}
+}
+
+// SignLink mints a link with the signer of the Authenticator, see LinkSigner.Sign
+func (a *Authenticator) SignLink(path string, query url.Values, expiresAt time.Time, singleUse bool, actor string) (SignedLink, error) {
+	return a.signer.Sign(path, query, expiresAt, singleUse, actor)
+}
@@ -0,0 +1,62 @@
+package handler_test
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/faris-arifiansyah/fws-rsvp/handler"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinkSignerVerify(t *testing.T) {
+	assert := assert.New(t)
+
+	signer := handler.NewLinkSigner([]byte("secret"), "https://rsvp.example.com/", nil, "rsvp:")
+	query := url.Values{"sort": {"name"}, "limit": {"-1"}}
+
+	link, err := signer.Sign("/files/rsvps", query, time.Now().Add(time.Hour), false, "admin:faris")
+	assert.NoError(err)
+	assert.True(strings.HasPrefix(link.URL, "https://rsvp.example.com/files/rsvps?"))
+
+	expired, err := signer.Sign("/files/rsvps", query, time.Now().Add(-time.Minute), false, "admin:faris")
+	assert.NoError(err)
+
+	other := handler.NewLinkSigner([]byte("other"), "https://rsvp.example.com", nil, "rsvp:")
+	forged, err := other.Sign("/files/rsvps", query, time.Now().Add(time.Hour), false, "admin:faris")
+	assert.NoError(err)
+
+	testCases := []struct {
+		url         string
+		expectedErr error
+	}{
+		{
+			url:         link.URL,
+			expectedErr: nil,
+		},
+		{
+			url:         strings.Replace(link.URL, "sort=name", "sort=-name", 1),
+			expectedErr: response.InvalidLinkError,
+		},
+		{
+			url:         strings.Replace(link.URL, "/files/rsvps", "/rsvps", 1),
+			expectedErr: response.InvalidLinkError,
+		},
+		{
+			url:         expired.URL,
+			expectedErr: response.InvalidLinkError,
+		},
+		{
+			url:         forged.URL,
+			expectedErr: response.InvalidLinkError,
+		},
+	}
+
+	for _, tc := range testCases {
+		r := httptest.NewRequest("GET", tc.url, nil)
+		assert.Equal(tc.expectedErr, signer.Verify(r), tc.url)
+	}
+}
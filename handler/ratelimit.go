@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/faris-arifiansyah/fws-rsvp/middleware"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/go-redis/redis"
+	"github.com/julienschmidt/httprouter"
+)
+
+// RateLimiter counts requests per key in a fixed window stored in Redis
+type RateLimiter struct {
+	rds    *redis.Client
+	prefix string
+	limit  int
+	window time.Duration
+}
+
+// Quota holds the state of a rate limit window after a request is counted
+type Quota struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// NewRateLimiter is a function to create rate limiter allowing limit requests per window
+func NewRateLimiter(rds *redis.Client, prefix string, limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		rds:    rds,
+		prefix: prefix,
+		limit:  limit,
+		window: window,
+	}
+}
+
+// takeScript counts a request and starts the window with the first one, returning the count
+// and the milliseconds left in the window. A key left without expiry gets one as well.
+const takeScript = `
+local count = redis.call("INCR", KEYS[1])
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {count, ttl}
+`
+
+// refundScript gives back a request unless its window has ended, which would leave the key
+// counting down without expiry
+const refundScript = `
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return redis.call("DECR", KEYS[1])
+end
+return 0
+`
+
+var (
+	take   = redis.NewScript(takeScript)
+	refund = redis.NewScript(refundScript)
+)
+
+// Take counts one request for key and returns the remaining quota.
+// It returns response.RateLimitExceededError when the limit has been reached.
+func (rl *RateLimiter) Take(key string) (Quota, error) {
+	quota := Quota{Limit: rl.limit}
+
+	res, err := take.Run(rl.rds, []string{rl.prefix + key}, int64(rl.window/time.Millisecond)).Result()
+	if err != nil {
+		return quota, err
+	}
+	vals, ok := res.([]interface{})
+	if !ok || len(vals) != 2 {
+		return quota, fmt.Errorf("unexpected rate limit reply %v", res)
+	}
+	count, _ := vals[0].(int64)
+	expire, _ := vals[1].(int64)
+
+	quota.Reset = time.Now().Add(time.Duration(expire) * time.Millisecond).Truncate(time.Second)
+	if int(count) > rl.limit {
+		return quota, response.RateLimitExceededError
+	}
+
+	quota.Remaining = rl.limit - int(count)
+	return quota, nil
+}
+
+// Refund gives back a request counted for key by Take, unless the window has ended since
+func (rl *RateLimiter) Refund(key string) error {
+	return refund.Run(rl.rds, []string{rl.prefix + key}).Err()
+}
+
+// quotaWriter sets X-RateLimit-Remaining as the handler writes its status, not counting
+// a request the handler rejects, as it is refunded
+type quotaWriter struct {
+	http.ResponseWriter
+	remaining   int
+	wroteHeader bool
+}
+
+func (qw *quotaWriter) WriteHeader(status int) {
+	if !qw.wroteHeader {
+		qw.wroteHeader = true
+		remaining := qw.remaining
+		if status >= http.StatusBadRequest {
+			remaining++
+		}
+		qw.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	}
+	qw.ResponseWriter.WriteHeader(status)
+}
+
+func (qw *quotaWriter) Write(b []byte) (int, error) {
+	if !qw.wroteHeader {
+		qw.WriteHeader(http.StatusOK)
+	}
+	return qw.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (qw *quotaWriter) Unwrap() http.ResponseWriter {
+	return qw.ResponseWriter
+}
+
+// WithRateLimit decorates handler with rate limiting per client IP.
+// Only requests the handler accepts count: when it returns an error, e.g. for an invalid
+// body, the request is refunded. Every response carries the X-RateLimit-* headers, counting
+// the request itself unless it is refunded, and requests over the limit carry Retry-After as well.
+func WithRateLimit(h func(http.ResponseWriter, *http.Request, httprouter.Params) error, rl *RateLimiter) middleware.HandleWithError {
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+		ip := middleware.ClientIP(r.Context())
+		quota, err := rl.Take(ip)
+		if err != nil && err != response.RateLimitExceededError {
+			errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+			response.Write(w, errBody, httpStatus)
+			return err
+		}
+
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(quota.Limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(quota.Remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(quota.Reset.Unix(), 10))
+
+		if err != nil {
+			retryAfter := int(time.Until(quota.Reset).Seconds())
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+
+			errBody, httpStatus := response.BuildErrorAndStatus(err, "")
+			errBody.Meta.RateLimitReset = &quota.Reset
+			response.Write(w, errBody, httpStatus)
+			return err
+		}
+
+		if err = h(&quotaWriter{ResponseWriter: w, remaining: quota.Remaining}, r, params); err != nil {
+			if rerr := rl.Refund(ip); rerr != nil {
+				log.Printf("failed to refund rate limit of request %s: %s\n", middleware.RequestID(r.Context()), rerr)
+			}
+		}
+		return err
+	}
+}
@@ -0,0 +1,97 @@
+package handler_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/faris-arifiansyah/fws-rsvp/handler"
+	"github.com/faris-arifiansyah/fws-rsvp/middleware"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithRateLimit(t *testing.T) {
+	rds, stop := newFakeRedis(t)
+	defer stop()
+	limiter := handler.NewRateLimiter(rds, "test:", 2, time.Minute)
+	resolver, err := middleware.NewClientIPResolver(nil)
+	assert.NoError(t, err)
+
+	calls := 0
+	create := middleware.ApplyDecorators(handler.WithRateLimit(func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+		calls++
+		if r.URL.Query().Get("invalid") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return errors.New("invalid rsvp")
+		}
+		w.WriteHeader(http.StatusCreated)
+		return nil
+	}, limiter), middleware.WithStandardContext(resolver))
+
+	tests := []struct {
+		name       string
+		url        string
+		ip         string
+		status     int
+		remaining  int
+		retryAfter bool
+	}{
+		{"accepted", "/rsvps", "192.0.2.1", http.StatusCreated, 1, false},
+		// a rejected submission does not use up the quota
+		{"invalid", "/rsvps?invalid=1", "192.0.2.1", http.StatusBadRequest, 1, false},
+		{"accepted after invalid", "/rsvps", "192.0.2.1", http.StatusCreated, 0, false},
+		{"over the limit", "/rsvps", "192.0.2.1", http.StatusTooManyRequests, 0, true},
+		{"another client", "/rsvps", "198.51.100.7", http.StatusCreated, 1, false},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodPost, test.url, nil)
+		r.RemoteAddr = test.ip + ":1234"
+		w := httptest.NewRecorder()
+		create(w, r, nil)
+
+		assert.Equal(t, test.status, w.Code, test.name)
+		assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"), test.name)
+		assert.Equal(t, strconv.Itoa(test.remaining), w.Header().Get("X-RateLimit-Remaining"), test.name)
+
+		reset, err := strconv.ParseInt(w.Header().Get("X-RateLimit-Reset"), 10, 64)
+		assert.NoError(t, err, test.name)
+		assert.InDelta(t, time.Now().Add(time.Minute).Unix(), reset, 2, test.name)
+
+		if !test.retryAfter {
+			assert.Empty(t, w.Header().Get("Retry-After"), test.name)
+			continue
+		}
+		retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+		assert.NoError(t, err, test.name)
+		assert.InDelta(t, 60, retryAfter, 2, test.name)
+	}
+
+	// the request over the limit never reaches the handler
+	assert.Equal(t, 4, calls)
+}
+
+func TestRateLimiterRefundAfterWindow(t *testing.T) {
+	assert := assert.New(t)
+
+	rds, stop := newFakeRedis(t)
+	defer stop()
+	limiter := handler.NewRateLimiter(rds, "test:", 2, 50*time.Millisecond)
+
+	_, err := limiter.Take("192.0.2.1")
+	assert.NoError(err)
+	time.Sleep(100 * time.Millisecond)
+
+	// the window ended before the refund, which leaves no key behind
+	assert.NoError(limiter.Refund("192.0.2.1"))
+	assert.Equal(int64(0), rds.Exists("test:192.0.2.1").Val())
+
+	quota, err := limiter.Take("192.0.2.1")
+	assert.NoError(err)
+	assert.Equal(1, quota.Remaining)
+	assert.True(rds.PTTL("test:192.0.2.1").Val() > 0)
+}
@@ -0,0 +1,267 @@
+package handler_test
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/faris-arifiansyah/fws-rsvp/handler"
+	"github.com/go-redis/redis"
+)
+
+// fakeRedis is an in-memory Redis server speaking just enough of the protocol
+// for the login throttle and the rate limiter: strings and counters with expiry, transactions,
+// and the scripts of the rate limiter, emulated in Go
+type fakeRedis struct {
+	sync.Mutex
+	values  map[string]string
+	expires map[string]time.Time
+	// scripts are run by their SHA1 once loaded by EVAL
+	scripts map[string]func(keys []string, args []string) string
+	loaded  map[string]bool
+}
+
+func scriptSHA(script string) string {
+	sum := sha1.Sum([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+// newFakeRedis starts a fakeRedis and returns a client connected to it, and a func stopping both
+func newFakeRedis(t *testing.T) (*redis.Client, func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fr := &fakeRedis{values: map[string]string{}, expires: map[string]time.Time{}, loaded: map[string]bool{}}
+	fr.scripts = map[string]func(keys []string, args []string) string{
+		scriptSHA(handler.TakeScript):   fr.take,
+		scriptSHA(handler.RefundScript): fr.refund,
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go fr.serve(conn)
+		}
+	}()
+
+	rds := redis.NewClient(&redis.Options{Addr: ln.Addr().String()})
+	return rds, func() {
+		rds.Close()
+		ln.Close()
+	}
+}
+
+func (fr *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	var queue [][]string
+	inTx := false
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+
+		var reply string
+		switch name := strings.ToUpper(args[0]); {
+		case name == "MULTI":
+			inTx, queue = true, nil
+			reply = "+OK\r\n"
+		case name == "EXEC":
+			reply = fmt.Sprintf("*%d\r\n", len(queue))
+			for _, cmd := range queue {
+				reply += fr.exec(cmd)
+			}
+			inTx = false
+		case inTx:
+			queue = append(queue, args)
+			reply = "+QUEUED\r\n"
+		default:
+			reply = fr.exec(args)
+		}
+
+		if _, err = io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// readCommand reads an array of bulk strings
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, size+2)
+		if _, err = io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		args[i] = string(b[:size])
+	}
+	return args, nil
+}
+
+func (fr *fakeRedis) exec(args []string) string {
+	fr.Lock()
+	defer fr.Unlock()
+
+	switch strings.ToUpper(args[0]) {
+	case "EVAL":
+		sha := scriptSHA(args[1])
+		fr.loaded[sha] = true
+		return fr.eval(sha, args[2:])
+	case "EVALSHA":
+		if !fr.loaded[args[1]] {
+			return "-NOSCRIPT No matching script. Please use EVAL.\r\n"
+		}
+		return fr.eval(args[1], args[2:])
+	}
+	return fr.run(args)
+}
+
+// eval runs the script sha with the keys and args of an EVAL command
+func (fr *fakeRedis) eval(sha string, args []string) string {
+	script, ok := fr.scripts[sha]
+	if !ok {
+		return "-ERR unknown script\r\n"
+	}
+	n, _ := strconv.Atoi(args[0])
+	return script(args[1:1+n], args[1+n:])
+}
+
+// take emulates the take script of the rate limiter
+func (fr *fakeRedis) take(keys []string, args []string) string {
+	count := intReply(fr.run([]string{"INCR", keys[0]}))
+	ttl := intReply(fr.run([]string{"PTTL", keys[0]}))
+	if ttl < 0 {
+		fr.run([]string{"PEXPIRE", keys[0], args[0]})
+		ttl, _ = strconv.Atoi(args[0])
+	}
+	return fmt.Sprintf("*2\r\n:%d\r\n:%d\r\n", count, ttl)
+}
+
+// refund emulates the refund script of the rate limiter
+func (fr *fakeRedis) refund(keys []string, args []string) string {
+	if intReply(fr.run([]string{"EXISTS", keys[0]})) == 1 {
+		return fr.run([]string{"DECR", keys[0]})
+	}
+	return ":0\r\n"
+}
+
+func intReply(reply string) int {
+	n, _ := strconv.Atoi(strings.TrimSpace(reply[1:]))
+	return n
+}
+
+// run runs a plain command
+func (fr *fakeRedis) run(args []string) string {
+	now := time.Now()
+	for key, at := range fr.expires {
+		if !at.After(now) {
+			delete(fr.values, key)
+			delete(fr.expires, key)
+		}
+	}
+
+	key := ""
+	if len(args) > 1 {
+		key = args[1]
+	}
+	_, exists := fr.values[key]
+
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		if !exists {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(fr.values[key]), fr.values[key])
+	case "SET":
+		fr.values[key] = args[2]
+		delete(fr.expires, key)
+		if len(args) == 5 {
+			n, _ := strconv.Atoi(args[4])
+			unit := time.Second
+			if strings.ToUpper(args[3]) == "PX" {
+				unit = time.Millisecond
+			}
+			fr.expires[key] = now.Add(time.Duration(n) * unit)
+		}
+		return "+OK\r\n"
+	case "INCR", "DECR":
+		n, _ := strconv.Atoi(fr.values[key])
+		if strings.ToUpper(args[0]) == "INCR" {
+			n++
+		} else {
+			n--
+		}
+		fr.values[key] = strconv.Itoa(n)
+		return fmt.Sprintf(":%d\r\n", n)
+	case "EXPIRE", "PEXPIRE":
+		if !exists {
+			return ":0\r\n"
+		}
+		n, _ := strconv.Atoi(args[2])
+		unit := time.Second
+		if strings.ToUpper(args[0]) == "PEXPIRE" {
+			unit = time.Millisecond
+		}
+		fr.expires[key] = now.Add(time.Duration(n) * unit)
+		return ":1\r\n"
+	case "TTL", "PTTL":
+		at, expires := fr.expires[key]
+		switch {
+		case !exists:
+			return ":-2\r\n"
+		case !expires:
+			return ":-1\r\n"
+		case strings.ToUpper(args[0]) == "PTTL":
+			return fmt.Sprintf(":%d\r\n", at.Sub(now)/time.Millisecond)
+		default:
+			return fmt.Sprintf(":%d\r\n", (at.Sub(now)+time.Second/2)/time.Second)
+		}
+	case "EXISTS":
+		if !exists {
+			return ":0\r\n"
+		}
+		return ":1\r\n"
+	case "DEL":
+		deleted := 0
+		for _, key := range args[1:] {
+			if _, ok := fr.values[key]; ok {
+				deleted++
+			}
+			delete(fr.values, key)
+			delete(fr.expires, key)
+		}
+		return fmt.Sprintf(":%d\r\n", deleted)
+	default:
+		return "-ERR unknown command '" + args[0] + "'\r\n"
+	}
+}
@@ -0,0 +1,238 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/middleware"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/go-redis/redis"
+	"go.uber.org/zap"
+)
+
+// Lockout kinds
+const (
+	LockoutUser = "users"
+	LockoutIP   = "ips"
+)
+
+// ActionLockout is the audit log action of a username or client IP being locked out
+const ActionLockout = "LOCKOUT"
+
+// ThrottleOption configures LoginThrottle
+type ThrottleOption struct {
+	// MaxAttempts is the number of failures allowed per username before it is locked out
+	MaxAttempts int
+	// MaxIPAttempts is the number of failures allowed per client IP before it is locked out
+	MaxIPAttempts int
+	// Lockout is the first lockout duration, it doubles on every further failure
+	Lockout time.Duration
+	// MaxLockout caps the lockout duration
+	MaxLockout time.Duration
+	// Window is how long failures are remembered after the last one
+	Window time.Duration
+}
+
+// Lockout is a username or client IP locked out after Attempts failed logins
+type Lockout struct {
+	Kind     string
+	Value    string
+	Attempts int64
+	Duration time.Duration
+}
+
+// LoginThrottle counts failed admin logins per username and per client IP in Redis
+// and locks them out with exponential backoff
+type LoginThrottle struct {
+	rds    *redis.Client
+	prefix string
+	opt    ThrottleOption
+	logger *zap.Logger
+}
+
+// NewLoginThrottle is a function to create LoginThrottle logging lockouts to logger
+func NewLoginThrottle(rds *redis.Client, prefix string, opt ThrottleOption, logger *zap.Logger) *LoginThrottle {
+	return &LoginThrottle{
+		rds:    rds,
+		prefix: prefix,
+		opt:    opt,
+		logger: logger,
+	}
+}
+
+func (lt *LoginThrottle) failKey(kind, value string) string {
+	return lt.prefix + "login:fail:" + kind + ":" + value
+}
+
+func (lt *LoginThrottle) lockKey(kind, value string) string {
+	return lt.prefix + "login:lock:" + kind + ":" + value
+}
+
+// Check returns response.LoginLockedError and the time left when username or ip is locked out
+func (lt *LoginThrottle) Check(username, ip string) (time.Duration, error) {
+	pipe := lt.rds.Pipeline()
+	userTTL := pipe.PTTL(lt.lockKey(LockoutUser, normalizeUsername(username)))
+	ipTTL := pipe.PTTL(lt.lockKey(LockoutIP, ip))
+	if _, err := pipe.Exec(); err != nil {
+		return 0, err
+	}
+
+	left := userTTL.Val()
+	if ipTTL.Val() > left {
+		left = ipTTL.Val()
+	}
+
+	if left > 0 {
+		return left, response.LoginLockedError
+	}
+
+	return 0, nil
+}
+
+// Fail counts a failed login of username from ip, locking them out once they run out of attempts.
+// It returns the lockouts it started.
+func (lt *LoginThrottle) Fail(username, ip string) ([]Lockout, error) {
+	var lockouts []Lockout
+
+	for _, l := range []Lockout{{Kind: LockoutUser, Value: normalizeUsername(username)}, {Kind: LockoutIP, Value: ip}} {
+		maxAttempts := lt.opt.MaxAttempts
+		if l.Kind == LockoutIP {
+			maxAttempts = lt.opt.MaxIPAttempts
+		}
+
+		locked, err := lt.fail(&l, maxAttempts)
+		if err != nil {
+			return lockouts, err
+		}
+		if locked {
+			lockouts = append(lockouts, l)
+		}
+	}
+
+	return lockouts, nil
+}
+
+// fail counts a failed login of l, setting its attempts and, when it is locked out, its duration
+func (lt *LoginThrottle) fail(l *Lockout, maxAttempts int) (bool, error) {
+	if l.Value == "" {
+		return false, nil
+	}
+
+	key := lt.failKey(l.Kind, l.Value)
+
+	pipe := lt.rds.TxPipeline()
+	incr := pipe.Incr(key)
+	pipe.Expire(key, lt.opt.Window)
+	if _, err := pipe.Exec(); err != nil {
+		return false, err
+	}
+
+	l.Attempts = incr.Val()
+	excess := int(l.Attempts) - maxAttempts
+	if excess < 0 {
+		return false, nil
+	}
+
+	lockout := lt.opt.Lockout
+	for i := 0; i < excess && lockout < lt.opt.MaxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > lt.opt.MaxLockout {
+		lockout = lt.opt.MaxLockout
+	}
+
+	l.Duration = lockout
+
+	return true, lt.rds.Set(lt.lockKey(l.Kind, l.Value), 1, lockout).Err()
+}
+
+// Succeed forgets the failed logins of username
+func (lt *LoginThrottle) Succeed(username string) error {
+	return lt.rds.Del(lt.failKey(LockoutUser, normalizeUsername(username))).Err()
+}
+
+// Clear lifts the lockout and forgets the failed logins of a username or IP
+func (lt *LoginThrottle) Clear(kind, value string) error {
+	if kind == LockoutUser {
+		value = normalizeUsername(value)
+	}
+
+	deleted, err := lt.rds.Del(lt.lockKey(kind, value), lt.failKey(kind, value)).Result()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return response.NotFoundError
+	}
+
+	return nil
+}
+
+// Guard runs attempt, a login of username, unless username or the client IP is locked out.
+// A failed attempt counts towards the lockout, a successful one resets the username count.
+func (a *Authenticator) Guard(w http.ResponseWriter, r *http.Request, username string, attempt func() error) error {
+	ip := middleware.ClientIP(r.Context())
+
+	left, err := a.throttle.Check(username, ip)
+	if err == response.LoginLockedError {
+		w.Header().Set("Retry-After", strconv.Itoa(int(left.Seconds())+1))
+	}
+	if err != nil {
+		return err
+	}
+
+	err = attempt()
+	if err == response.UserUnauthorizedError {
+		lockouts, ferr := a.throttle.Fail(username, ip)
+		for _, l := range lockouts {
+			a.lockedOut(r, l)
+		}
+		if ferr != nil {
+			return ferr
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	return a.throttle.Succeed(username)
+}
+
+// lockedOut logs l and records it in the audit log as the lockout action on kind/value
+func (a *Authenticator) lockedOut(r *http.Request, l Lockout) {
+	ctx := r.Context()
+
+	a.throttle.logger.Warn("admin login locked out",
+		zap.String("request_id", middleware.RequestID(ctx)),
+		zap.String("client_ip", middleware.ClientIP(ctx)),
+		zap.String("kind", l.Kind),
+		zap.String("value", l.Value),
+		zap.Int64("attempts", l.Attempts),
+		zap.String("lockout", l.Duration.String()),
+	)
+
+	err := a.audits.Record(ctx, rsvp.AuditEntry{
+		Action:    ActionLockout,
+		Target:    l.Kind + "/" + l.Value,
+		Status:    response.LoginLockedError.HTTPCode,
+		RequestID: middleware.RequestID(ctx),
+		IP:        middleware.ClientIP(ctx),
+	})
+	if err != nil {
+		log.Printf("failed to record lockout of request %s: %s\n", middleware.RequestID(ctx), err)
+	}
+}
+
+// ClearLockout lifts the lockout of a username or client IP, see LoginThrottle.Clear
+func (a *Authenticator) ClearLockout(kind, value string) error {
+	return a.throttle.Clear(kind, value)
+}
+
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
@@ -0,0 +1,199 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/handler"
+	"github.com/faris-arifiansyah/fws-rsvp/middleware"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLoginThrottleUsername(t *testing.T) {
+	assert := assert.New(t)
+
+	rds, stop := newFakeRedis(t)
+	defer stop()
+	throttle := handler.NewLoginThrottle(rds, "test:", handler.ThrottleOption{
+		MaxAttempts:   2,
+		MaxIPAttempts: 100,
+		Lockout:       time.Minute,
+		MaxLockout:    4 * time.Minute,
+		Window:        time.Hour,
+	}, zap.NewNop())
+
+	lockouts, err := throttle.Fail("budi", "192.0.2.1")
+	assert.NoError(err)
+	assert.Empty(lockouts)
+	_, err = throttle.Check("budi", "192.0.2.1")
+	assert.NoError(err)
+
+	// the lockout doubles with every further failure, up to the maximum
+	for i, expected := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 4 * time.Minute} {
+		lockouts, err = throttle.Fail(" Budi", "192.0.2.1")
+		assert.NoError(err)
+		assert.Equal([]handler.Lockout{{Kind: handler.LockoutUser, Value: "budi", Attempts: int64(i + 2), Duration: expected}}, lockouts)
+
+		left, err := throttle.Check("BUDI", "198.51.100.7")
+		assert.Equal(response.LoginLockedError, err)
+		assert.InDelta(float64(expected), float64(left), float64(time.Second))
+	}
+
+	_, err = throttle.Check("siti", "192.0.2.1")
+	assert.NoError(err)
+
+	assert.NoError(throttle.Clear(handler.LockoutUser, "Budi"))
+	_, err = throttle.Check("budi", "192.0.2.1")
+	assert.NoError(err)
+	assert.Equal(response.NotFoundError, throttle.Clear(handler.LockoutUser, "budi"))
+}
+
+func TestLoginThrottleIP(t *testing.T) {
+	assert := assert.New(t)
+
+	rds, stop := newFakeRedis(t)
+	defer stop()
+	throttle := handler.NewLoginThrottle(rds, "test:", handler.ThrottleOption{
+		MaxAttempts:   100,
+		MaxIPAttempts: 3,
+		Lockout:       time.Minute,
+		MaxLockout:    time.Hour,
+		Window:        time.Hour,
+	}, zap.NewNop())
+
+	// every username tried from an IP counts towards its lockout
+	for _, username := range []string{"budi", "siti"} {
+		lockouts, err := throttle.Fail(username, "192.0.2.1")
+		assert.NoError(err)
+		assert.Empty(lockouts)
+	}
+	lockouts, err := throttle.Fail("rudi", "192.0.2.1")
+	assert.NoError(err)
+	assert.Equal([]handler.Lockout{{Kind: handler.LockoutIP, Value: "192.0.2.1", Attempts: 3, Duration: time.Minute}}, lockouts)
+
+	_, err = throttle.Check("dewi", "192.0.2.1")
+	assert.Equal(response.LoginLockedError, err)
+	_, err = throttle.Check("budi", "198.51.100.7")
+	assert.NoError(err)
+
+	assert.NoError(throttle.Clear(handler.LockoutIP, "192.0.2.1"))
+	_, err = throttle.Check("dewi", "192.0.2.1")
+	assert.NoError(err)
+}
+
+func TestLoginThrottleSucceed(t *testing.T) {
+	assert := assert.New(t)
+
+	rds, stop := newFakeRedis(t)
+	defer stop()
+	throttle := handler.NewLoginThrottle(rds, "test:", handler.ThrottleOption{
+		MaxAttempts:   2,
+		MaxIPAttempts: 100,
+		Lockout:       time.Minute,
+		MaxLockout:    time.Hour,
+		Window:        time.Hour,
+	}, zap.NewNop())
+
+	_, err := throttle.Fail("budi", "192.0.2.1")
+	assert.NoError(err)
+	assert.NoError(throttle.Succeed("budi"))
+
+	// the failure before the successful login is forgotten
+	lockouts, err := throttle.Fail("budi", "192.0.2.1")
+	assert.NoError(err)
+	assert.Empty(lockouts)
+}
+
+func TestGuard(t *testing.T) {
+	assert := assert.New(t)
+
+	rds, stop := newFakeRedis(t)
+	defer stop()
+	core, logs := observer.New(zap.InfoLevel)
+	throttle := handler.NewLoginThrottle(rds, "test:", handler.ThrottleOption{
+		MaxAttempts:   1,
+		MaxIPAttempts: 100,
+		Lockout:       time.Minute,
+		MaxLockout:    time.Hour,
+		Window:        time.Hour,
+	}, zap.New(core))
+	audits := &fakeAudits{}
+	auth := handler.NewAuthenticator(nil, nil, nil, audits, throttle, nil)
+
+	resolver, err := middleware.NewClientIPResolver(nil)
+	assert.NoError(err)
+	attempts := 0
+	guard := middleware.ApplyDecorators(func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+		return auth.Guard(w, r, "budi", func() error {
+			attempts++
+			return response.UserUnauthorizedError
+		})
+	}, middleware.WithStandardContext(resolver))
+	login := func() (*httptest.ResponseRecorder, error) {
+		r := httptest.NewRequest(http.MethodPost, "/login", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		r.Header.Set("X-Request-ID", "req-1")
+		w := httptest.NewRecorder()
+		return w, guard(w, r, nil)
+	}
+
+	_, err = login()
+	assert.Equal(response.UserUnauthorizedError, err)
+	assert.Equal(1, attempts)
+
+	// the lockout is logged and recorded in the audit log
+	assert.Equal(1, logs.FilterMessage("admin login locked out").FilterField(zap.String("value", "budi")).Len())
+	assert.Equal([]rsvp.AuditEntry{{
+		Action:    handler.ActionLockout,
+		Target:    "users/budi",
+		Status:    http.StatusTooManyRequests,
+		RequestID: "req-1",
+		IP:        "192.0.2.1",
+	}}, audits.entries)
+
+	// locked out, the attempt is not made
+	w, err := login()
+	assert.Equal(response.LoginLockedError, err)
+	assert.Equal(1, attempts)
+	assert.Contains([]string{"60", "61"}, w.Header().Get("Retry-After"))
+}
+
+func TestBasicAuthWithTOTP(t *testing.T) {
+	assert := assert.New(t)
+
+	rds, stop := newFakeRedis(t)
+	defer stop()
+	throttle := handler.NewLoginThrottle(rds, "test:", handler.ThrottleOption{
+		MaxAttempts:   1,
+		MaxIPAttempts: 100,
+		Lockout:       time.Minute,
+		MaxLockout:    time.Hour,
+		Window:        time.Hour,
+	}, zap.NewNop())
+	auth := handler.NewAuthenticator(fakeAdmins{}, fakeRoles{}, nil, &fakeAudits{}, throttle, nil)
+
+	resolver, err := middleware.NewClientIPResolver(nil)
+	assert.NoError(err)
+	h := middleware.ApplyDecorators(auth.WithAuth(func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+		return nil
+	}, handler.Authenticated), middleware.WithStandardContext(resolver))
+	request := func(otp string) error {
+		r := httptest.NewRequest(http.MethodGet, "/rsvps", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		r.SetBasicAuth("budi", "budi-password")
+		r.Header.Set("X-OTP", otp)
+		return h(httptest.NewRecorder(), r, nil)
+	}
+
+	// a TOTP code is only accepted at login, and refusing Basic Auth does not lock budi out
+	for i := 0; i < 3; i++ {
+		assert.Equal(response.SessionRequiredError, request("123456"))
+	}
+}
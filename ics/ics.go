@@ -0,0 +1,126 @@
+// Package ics writes iCalendar (RFC 5545) files, so an event can be added to
+// any calendar application
+package ics
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ContentType is the media type of an iCalendar file
+const ContentType = "text/calendar; charset=utf-8"
+
+// ProductID identifies the application writing the calendar
+const ProductID = "-//FWS//FWS RSVP//EN"
+
+// maxLineOctets is the length a content line is folded at, not counting CRLF
+const maxLineOctets = 75
+
+const utcFormat = "20060102T150405Z"
+
+// Event is a VEVENT of a calendar
+type Event struct {
+	// UID identifies the event, a calendar updates the event with the same UID
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	Start       time.Time
+	End         time.Time
+	// Reminder is how long before Start the calendar alerts, zero for no alert
+	Reminder time.Duration
+}
+
+// Marshal returns a published calendar of events, stamped with stamp
+func Marshal(stamp time.Time, events ...Event) []byte {
+	var b bytes.Buffer
+
+	line(&b, "BEGIN", "VCALENDAR")
+	line(&b, "VERSION", "2.0")
+	line(&b, "PRODID", ProductID)
+	line(&b, "CALSCALE", "GREGORIAN")
+	line(&b, "METHOD", "PUBLISH")
+
+	for _, e := range events {
+		line(&b, "BEGIN", "VEVENT")
+		line(&b, "UID", escape(e.UID))
+		line(&b, "DTSTAMP", stamp.UTC().Format(utcFormat))
+		line(&b, "DTSTART", e.Start.UTC().Format(utcFormat))
+		if !e.End.IsZero() {
+			line(&b, "DTEND", e.End.UTC().Format(utcFormat))
+		}
+		line(&b, "SUMMARY", escape(e.Summary))
+		if e.Description != "" {
+			line(&b, "DESCRIPTION", escape(e.Description))
+		}
+		if e.Location != "" {
+			line(&b, "LOCATION", escape(e.Location))
+		}
+		if e.URL != "" {
+			// a URI is not escaped
+			line(&b, "URL", e.URL)
+		}
+		if e.Reminder > 0 {
+			line(&b, "BEGIN", "VALARM")
+			line(&b, "ACTION", "DISPLAY")
+			line(&b, "DESCRIPTION", escape(e.Summary))
+			line(&b, "TRIGGER", "-"+duration(e.Reminder))
+			line(&b, "END", "VALARM")
+		}
+		line(&b, "END", "VEVENT")
+	}
+
+	line(&b, "END", "VCALENDAR")
+
+	return b.Bytes()
+}
+
+// line writes a content line, folded so no line is longer than 75 octets
+func line(b *bytes.Buffer, name string, value string) {
+	s := name + ":" + value
+
+	width := 0
+	for len(s) > 0 {
+		_, size := utf8.DecodeRuneInString(s)
+		// a folded line starts with a space, and a character is never split
+		if width+size > maxLineOctets {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteString(s[:size])
+		width += size
+		s = s[size:]
+	}
+	b.WriteString("\r\n")
+}
+
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
+// escape returns s as a TEXT value
+func escape(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// duration returns d as a DURATION value, in whole minutes
+func duration(d time.Duration) string {
+	minutes := int64(d / time.Minute)
+	switch {
+	case minutes%(24*60) == 0:
+		return fmt.Sprintf("P%dD", minutes/(24*60))
+	case minutes%60 == 0:
+		return fmt.Sprintf("PT%dH", minutes/60)
+	default:
+		return fmt.Sprintf("PT%dM", minutes)
+	}
+}
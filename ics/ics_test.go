@@ -0,0 +1,47 @@
+package ics_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/faris-arifiansyah/fws-rsvp/ics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshal(t *testing.T) {
+	assert := assert.New(t)
+
+	wib := time.FixedZone("WIB", 7*60*60)
+	cal := string(ics.Marshal(time.Date(2019, 8, 1, 9, 0, 0, 0, time.UTC), ics.Event{
+		UID:         "20190817T030000Z@fws-rsvp",
+		Summary:     "Budi & Siti; Akad, Resepsi",
+		Description: "Akad nikah pukul 10.00\nResepsi pukul 11.00 sampai selesai di Gedung Serbaguna, Jl. Merdeka No. 17, Jakarta Pusat 🙏",
+		Location:    "Gedung Serbaguna",
+		URL:         "https://example.com/?a=1,2",
+		Start:       time.Date(2019, 8, 17, 10, 0, 0, 0, wib),
+		End:         time.Date(2019, 8, 17, 14, 0, 0, 0, wib),
+		Reminder:    24 * time.Hour,
+	}))
+
+	assert.True(strings.HasPrefix(cal, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(strings.HasSuffix(cal, "END:VEVENT\r\nEND:VCALENDAR\r\n"))
+	assert.Contains(cal, "\r\nDTSTAMP:20190801T090000Z\r\n")
+	assert.Contains(cal, "\r\nDTSTART:20190817T030000Z\r\n")
+	assert.Contains(cal, "\r\nDTEND:20190817T070000Z\r\n")
+	assert.Contains(cal, `SUMMARY:Budi & Siti\; Akad\, Resepsi`)
+	assert.Contains(cal, "\r\nURL:https://example.com/?a=1,2\r\n")
+	assert.Contains(cal, "\r\nTRIGGER:-P1D\r\n")
+
+	var unfolded []string
+	for _, l := range strings.Split(strings.TrimSuffix(cal, "\r\n"), "\r\n") {
+		assert.True(len(l) <= 75, "line %q is longer than 75 octets", l)
+		assert.NotContains(l, "\n")
+		if strings.HasPrefix(l, " ") {
+			unfolded[len(unfolded)-1] += l[1:]
+			continue
+		}
+		unfolded = append(unfolded, l)
+	}
+	assert.Contains(unfolded, `DESCRIPTION:Akad nikah pukul 10.00\nResepsi pukul 11.00 sampai selesai di Gedung Serbaguna\, Jl. Merdeka No. 17\, Jakarta Pusat 🙏`)
+}
@@ -0,0 +1,36 @@
+package rsvp
+
+// ImportOption configures a CSV import of RSVPs. Mapping maps headers of the file to
+// export column keys, headers matching a key or a column label are mapped by default.
+// TimeZone, DateFormat and Delimiter are read like in ExportOption.
+// A dry run validates the file without saving anything.
+type ImportOption struct {
+	Mapping    map[string]string
+	TimeZone   string
+	DateFormat string
+	Delimiter  string
+	DryRun     bool
+}
+
+// ImportResult summarizes an import of RSVPs, Errors lists the failed rows
+type ImportResult struct {
+	DryRun  bool          `json:"dry_run,omitempty"`
+	Created int           `json:"created"`
+	Updated int           `json:"updated"`
+	Skipped int           `json:"skipped"`
+	Failed  int           `json:"failed"`
+	Errors  []ImportError `json:"errors,omitempty"`
+}
+
+// ImportError describes why a row of an import failed, rows are counted from 1
+type ImportError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// Fail counts a failed row
+func (ir *ImportResult) Fail(row int, field string, message string) {
+	ir.Failed++
+	ir.Errors = append(ir.Errors, ImportError{Row: row, Field: field, Message: message})
+}
@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ClientIPResolver resolves the originating client IP of a request.
+// Proxy headers are honoured only when the request comes from a trusted proxy.
+type ClientIPResolver struct {
+	trusted []*net.IPNet
+}
+
+// NewClientIPResolver is a function to create ClientIPResolver trusting the given CIDRs.
+// A bare IP address is treated as a single host network.
+func NewClientIPResolver(cidrs []string) (*ClientIPResolver, error) {
+	resolver := &ClientIPResolver{}
+
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		resolver.trusted = append(resolver.trusted, network)
+	}
+
+	return resolver, nil
+}
+
+// Resolve returns the client IP of r.
+//
+// When the peer is a trusted proxy, the Forwarded, X-Forwarded-For and
+// X-Real-IP headers are consulted in that order. Forwarding chains are
+// walked from the nearest hop, skipping trusted proxies, so a client
+// cannot spoof its address by prepending entries. An unknown hop ends the
+// walk at the trusted hop in front of it, without consulting the other headers.
+func (c *ClientIPResolver) Resolve(r *http.Request) string {
+	peer := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(peer); err == nil {
+		peer = host
+	}
+
+	if !c.isTrusted(peer) {
+		return peer
+	}
+
+	if ip := c.fromChain(parseForwarded(r.Header.Get("Forwarded")), peer); ip != "" {
+		return ip
+	}
+
+	if ip := c.fromChain(splitList(r.Header.Get("X-Forwarded-For")), peer); ip != "" {
+		return ip
+	}
+
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+
+	return peer
+}
+
+// fromChain returns the client IP of chain, or "" when chain is empty, so the next header is consulted
+func (c *ClientIPResolver) fromChain(chain []string, peer string) string {
+	if len(chain) == 0 {
+		return ""
+	}
+
+	nearest := peer
+	for i := len(chain) - 1; i >= 0; i-- {
+		ip := net.ParseIP(chain[i])
+		if ip == nil { //Unknown or obfuscated hop, nothing behind it can be trusted
+			return nearest
+		}
+
+		if !c.isTrusted(ip.String()) {
+			return ip.String()
+		}
+		nearest = ip.String()
+	}
+
+	return nearest //Every hop is trusted, use the furthest one
+}
+
+func (c *ClientIPResolver) isTrusted(addr string) bool {
+	if c == nil {
+		return false
+	}
+
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range c.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseForwarded returns the for= node of each element in an RFC 7239 Forwarded header
+func parseForwarded(header string) []string {
+	var nodes []string
+
+	for _, element := range strings.Split(header, ",") {
+		for _, pair := range strings.Split(element, ";") {
+			kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+			if len(kv) != 2 || !strings.EqualFold(kv[0], "for") {
+				continue
+			}
+
+			node := strings.Trim(kv[1], `"`)
+			if strings.HasPrefix(node, "[") { //[IPv6]:port
+				node = strings.TrimPrefix(node, "[")
+				if end := strings.Index(node, "]"); end >= 0 {
+					node = node[:end]
+				}
+			} else if host, _, err := net.SplitHostPort(node); err == nil {
+				node = host
+			}
+
+			nodes = append(nodes, node)
+		}
+	}
+
+	return nodes
+}
+
+func splitList(header string) []string {
+	if header == "" {
+		return nil
+	}
+
+	items := strings.Split(header, ",")
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+
+	return items
+}
+
+// ClientIP returns the client IP resolved by WithStandardContext
+func ClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(ctxKey("X-Client-IP")).(string)
+	return ip
+}
@@ -0,0 +1,78 @@
+package middleware_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/faris-arifiansyah/fws-rsvp/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientIPResolverResolve(t *testing.T) {
+	assert := assert.New(t)
+
+	resolver, err := middleware.NewClientIPResolver([]string{"10.0.0.0/8", "127.0.0.1"})
+	assert.NoError(err)
+
+	testCases := []struct {
+		remoteAddr string
+		headers    map[string]string
+		expectedIP string
+	}{
+		{
+			remoteAddr: "203.0.113.7:5123",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			expectedIP: "203.0.113.7",
+		},
+		{
+			remoteAddr: "10.1.2.3:5123",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			expectedIP: "198.51.100.1",
+		},
+		{
+			remoteAddr: "10.1.2.3:5123",
+			headers:    map[string]string{"X-Forwarded-For": "1.1.1.1, 198.51.100.1, 10.0.0.2"},
+			expectedIP: "198.51.100.1",
+		},
+		{
+			remoteAddr: "127.0.0.1:5123",
+			headers:    map[string]string{"Forwarded": `for=192.0.2.60;proto=http, for="[2001:db8:cafe::17]:4711"`},
+			expectedIP: "2001:db8:cafe::17",
+		},
+		{
+			remoteAddr: "127.0.0.1:5123",
+			headers:    map[string]string{"X-Real-IP": "192.0.2.44"},
+			expectedIP: "192.0.2.44",
+		},
+		{
+			remoteAddr: "10.1.2.3:5123",
+			headers:    map[string]string{"X-Forwarded-For": "unknown"},
+			expectedIP: "10.1.2.3",
+		},
+		{
+			remoteAddr: "10.1.2.3:5123",
+			headers:    map[string]string{"X-Forwarded-For": "10.0.0.9, 10.0.0.2"},
+			expectedIP: "10.0.0.9",
+		},
+		{
+			remoteAddr: "127.0.0.1:5123",
+			headers:    map[string]string{"Forwarded": "for=_hidden", "X-Forwarded-For": "198.51.100.1", "X-Real-IP": "192.0.2.44"},
+			expectedIP: "127.0.0.1",
+		},
+		{
+			remoteAddr: "10.1.2.3:5123",
+			headers:    map[string]string{"Forwarded": "for=192.0.2.60, for=unknown, for=10.0.0.5", "X-Forwarded-For": "198.51.100.1"},
+			expectedIP: "10.0.0.5",
+		},
+	}
+
+	for _, tc := range testCases {
+		r := httptest.NewRequest("GET", "/rsvps", nil)
+		r.RemoteAddr = tc.remoteAddr
+		for k, v := range tc.headers {
+			r.Header.Set(k, v)
+		}
+
+		assert.Equal(tc.expectedIP, resolver.Resolve(r))
+	}
+}
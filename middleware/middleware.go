@@ -33,9 +33,11 @@ func WithLogging(logger *zap.Logger) Decorator {
 	return func(handle HandleWithError) HandleWithError {
 		return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
 			reqID := r.Header.Get("X-Request-ID")
+			clientIP := ClientIP(r.Context())
 			start := time.Now()
 
 			err := handle(w, r, params)
+			actor := Actor(r.Context())
 
 			// elapsed time in milliseconds
 			elapsed := time.Since(start).Seconds() * 1000
@@ -44,12 +46,16 @@ func WithLogging(logger *zap.Logger) Decorator {
 			if err != nil {
 				logger.Error(err.Error(),
 					zap.String("request_id", reqID),
+					zap.String("client_ip", clientIP),
+					zap.String("actor", actor),
 					zap.String("duration", elapsedStr),
 					zap.Strings("tags", []string{r.URL.Path, r.Method}),
 				)
 			} else {
 				logger.Info("everything is fine",
 					zap.String("request_id", reqID),
+					zap.String("client_ip", clientIP),
+					zap.String("actor", actor),
 					zap.String("duration", elapsedStr),
 					zap.Strings("tags", []string{r.URL.Path, r.Method}),
 				)
@@ -61,7 +67,9 @@ func WithLogging(logger *zap.Logger) Decorator {
 }
 
 // WithStandardContext decorates Decorator with standard context.
-func WithStandardContext() Decorator {
+// The client IP is resolved once here so every later decorator and handler
+// sees the same address.
+func WithStandardContext(resolver *ClientIPResolver) Decorator {
 	return func(handle HandleWithError) HandleWithError {
 		return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
 			ctx := r.Context()
@@ -77,22 +85,47 @@ func WithStandardContext() Decorator {
 			ctx = context.WithValue(ctx, ctxKey("X-Request-ID"), r.Header.Get("X-Request-ID"))
 			ctx = context.WithValue(ctx, ctxKey("Authorization"), r.Header.Get("Authorization"))
 			ctx = context.WithValue(ctx, ctxKey("Retry"), r.Header.Get("Retry"))
+			ctx = context.WithValue(ctx, ctxKey("X-Client-IP"), resolver.Resolve(r))
+			ctx = context.WithValue(ctx, ctxKey("Actor"), new(string))
 
 			return handle(w, r.WithContext(ctx), params)
 		}
 	}
 }
 
+// RequestID returns the request ID set by WithStandardContext
+func RequestID(ctx context.Context) string {
+	reqID, _ := ctx.Value(ctxKey("X-Request-ID")).(string)
+	return reqID
+}
+
+// SetActor records who is making the request, e.g. "admin:faris".
+// The actor is shared by every decorator of the request, so it can be
+// set by the handler and still be logged by WithLogging.
+func SetActor(ctx context.Context, actor string) {
+	if holder, ok := ctx.Value(ctxKey("Actor")).(*string); ok {
+		*holder = actor
+	}
+}
+
+// Actor returns the actor recorded by SetActor, or empty string for anonymous requests
+func Actor(ctx context.Context) string {
+	if holder, ok := ctx.Value(ctxKey("Actor")).(*string); ok {
+		return *holder
+	}
+	return ""
+}
+
 // StandardDecorators returns standard decorators.
 //
 // WithLogging(),
-// WithStandardContext()
-func StandardDecorators() []Decorator {
+// WithStandardContext(resolver)
+func StandardDecorators(resolver *ClientIPResolver) []Decorator {
 	l, _ := zap.NewProduction()
 
 	ds := []Decorator{
 		WithLogging(l),
-		WithStandardContext(),
+		WithStandardContext(resolver),
 	}
 	return ds
 }
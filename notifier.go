@@ -0,0 +1,10 @@
+package rsvp
+
+import (
+	"context"
+)
+
+// Notifier tells the couple about a new RSVP
+type Notifier interface {
+	NotifyRsvp(ctx context.Context, rp Rsvp) error
+}
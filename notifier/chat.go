@@ -0,0 +1,207 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+)
+
+// DefaultTelegramURL is the base URL of the Telegram Bot API
+const DefaultTelegramURL = "https://api.telegram.org"
+
+// chatTimeFormat formats the period of a digest
+const chatTimeFormat = "Mon, 02 Jan 15:04"
+
+// TelegramOption configures a Telegram ChatNotifier
+type TelegramOption struct {
+	// BaseURL is the Bot API base URL, DefaultTelegramURL when empty
+	BaseURL string
+	Token   string
+	ChatID  string
+	Timeout time.Duration
+}
+
+// SlackOption configures a ChatNotifier posting to a Slack-style incoming webhook
+type SlackOption struct {
+	WebhookURL string
+	Timeout    time.Duration
+}
+
+// ChatNotifier posts every new RSVP and digest report to a chat as a Markdown message
+type ChatNotifier struct {
+	client *http.Client
+	// post sends a message to the chat
+	post func(ctx context.Context, text string) error
+	// escape keeps text from being read as Markdown
+	escape func(string) string
+}
+
+// NewTelegramNotifier is a function to create ChatNotifier sending messages with a Telegram bot
+func NewTelegramNotifier(opt TelegramOption) (*ChatNotifier, error) {
+	if opt.Token == "" || opt.ChatID == "" {
+		return nil, fmt.Errorf("telegram bot token and chat ID cannot be empty")
+	}
+	if opt.BaseURL == "" {
+		opt.BaseURL = DefaultTelegramURL
+	}
+
+	cn := &ChatNotifier{client: &http.Client{Timeout: opt.Timeout}, escape: telegramEscaper.Replace}
+	endpoint := strings.TrimSuffix(opt.BaseURL, "/") + "/bot" + opt.Token + "/sendMessage"
+	cn.post = func(ctx context.Context, text string) error {
+		body := map[string]interface{}{
+			"chat_id":                  opt.ChatID,
+			"text":                     text,
+			"parse_mode":               "Markdown",
+			"disable_web_page_preview": true,
+		}
+
+		return cn.postJSON(ctx, endpoint, body, func(status int, r io.Reader) error {
+			var result struct {
+				OK          bool   `json:"ok"`
+				Description string `json:"description"`
+			}
+			if err := json.NewDecoder(r).Decode(&result); err != nil || !result.OK {
+				// the error leaves out the URL, which holds the token
+				return fmt.Errorf("telegram responded %d: %s", status, result.Description)
+			}
+			return nil
+		})
+	}
+
+	return cn, nil
+}
+
+// NewSlackNotifier is a function to create ChatNotifier posting to a Slack-style incoming webhook
+func NewSlackNotifier(opt SlackOption) (*ChatNotifier, error) {
+	if opt.WebhookURL == "" {
+		return nil, fmt.Errorf("slack webhook URL cannot be empty")
+	}
+
+	cn := &ChatNotifier{client: &http.Client{Timeout: opt.Timeout}, escape: slackEscaper.Replace}
+	cn.post = func(ctx context.Context, text string) error {
+		body := map[string]interface{}{
+			"text":   text,
+			"mrkdwn": true,
+		}
+
+		return cn.postJSON(ctx, opt.WebhookURL, body, func(status int, r io.Reader) error {
+			if status/100 != 2 {
+				b, _ := ioutil.ReadAll(io.LimitReader(r, 512))
+				return fmt.Errorf("slack webhook responded %d: %s", status, strings.TrimSpace(string(b)))
+			}
+			return nil
+		})
+	}
+
+	return cn, nil
+}
+
+// telegramEscaper escapes the entities of Telegram's Markdown
+var telegramEscaper = strings.NewReplacer("_", `\_`, "*", `\*`, "`", "\\`", "[", `\[`)
+
+// slackEscaper escapes the control characters of Slack's mrkdwn
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func (cn *ChatNotifier) NotifyRsvp(ctx context.Context, rp rsvp.Rsvp) error {
+	return cn.post(ctx, cn.RsvpMessage(rp))
+}
+
+func (cn *ChatNotifier) SendDigestReport(ctx context.Context, schedule string, report *rsvp.DigestReport) error {
+	return cn.post(ctx, cn.DigestMessage(schedule, report))
+}
+
+// RsvpMessage returns the message about rp
+func (cn *ChatNotifier) RsvpMessage(rp rsvp.Rsvp) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s *New RSVP from %s*\n", rp.Attend.Emoji(), cn.escape(rp.Name))
+	fmt.Fprintf(&b, "Attend: %s\n", rp.Attend)
+	fmt.Fprintf(&b, "Address: %s\n", cn.escape(rp.Address))
+	if msg := strings.TrimSpace(rp.Message); msg != "" {
+		fmt.Fprintf(&b, "\n💬 %s\n", cn.escape(msg))
+	}
+
+	return b.String()
+}
+
+// DigestMessage returns the message of a digest report on schedule
+func (cn *ChatNotifier) DigestMessage(schedule string, report *rsvp.DigestReport) string {
+	var b strings.Builder
+	location := report.To.Location()
+	totals := report.Totals
+
+	fmt.Fprintf(&b, "📊 *RSVP %s digest*\n", schedule)
+	fmt.Fprintf(&b, "%s to %s\n", report.From.In(location).Format(chatTimeFormat), report.To.Format(chatTimeFormat))
+	fmt.Fprintf(&b, "%d responses: ✅ %d attending, 🤔 %d maybe, ❌ %d not attending\n", totals.Total, totals.Yes, totals.Maybe, totals.No)
+
+	if report.IsEmpty() {
+		b.WriteString("\nNothing new since the last digest.\n")
+	}
+	if len(report.NewRsvps) > 0 {
+		fmt.Fprintf(&b, "\n*New responses (%d)*\n", len(report.NewRsvps))
+		for _, rp := range report.NewRsvps {
+			fmt.Fprintf(&b, "%s %s, %s\n", rp.Attend.Emoji(), cn.escape(rp.Name), cn.escape(rp.Address))
+		}
+	}
+	if len(report.Changes) > 0 {
+		fmt.Fprintf(&b, "\n*Attendance changes (%d)*\n", len(report.Changes))
+		for _, c := range report.Changes {
+			fmt.Fprintf(&b, "%s: %s %s → %s %s\n", cn.escape(c.Rsvp.Name), c.Previous.Emoji(), c.Previous, c.Rsvp.Attend.Emoji(), c.Rsvp.Attend)
+		}
+	}
+	if len(report.Cancelled) > 0 {
+		fmt.Fprintf(&b, "\n*Removed (%d)*\n", len(report.Cancelled))
+		for _, rp := range report.Cancelled {
+			fmt.Fprintf(&b, "• %s, %s\n", cn.escape(rp.Name), cn.escape(rp.Address))
+		}
+	}
+	if len(report.Messages) > 0 {
+		b.WriteString("\n*New messages*\n")
+		for _, rp := range report.Messages {
+			fmt.Fprintf(&b, "💬 %s: %s\n", cn.escape(rp.Name), cn.escape(strings.TrimSpace(rp.Message)))
+		}
+	}
+
+	return b.String()
+}
+
+// postJSON posts body to endpoint as JSON and checks the response with check
+func (cn *ChatNotifier) postJSON(ctx context.Context, endpoint string, body interface{}, check func(status int, r io.Reader) error) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := cn.client.Do(req)
+	if err != nil {
+		// a url.Error repeats the URL, which holds the Telegram token
+		return fmt.Errorf("chat post failed: %v", unwrapURLError(err))
+	}
+	defer resp.Body.Close()
+
+	return check(resp.StatusCode, resp.Body)
+}
+
+// unwrapURLError returns the cause of a url.Error, leaving out its URL
+func unwrapURLError(err error) error {
+	if ue, ok := err.(*url.Error); ok {
+		return ue.Err
+	}
+	return err
+}
@@ -0,0 +1,85 @@
+package notifier_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/enumeration"
+	"github.com/faris-arifiansyah/fws-rsvp/notifier"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTelegramNotifier(t *testing.T) {
+	assert := assert.New(t)
+
+	var path string
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["chat_id"] != "-100200" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true,"result":{}}`))
+	}))
+	defer server.Close()
+
+	cn, err := notifier.NewTelegramNotifier(notifier.TelegramOption{BaseURL: server.URL, Token: "123:abc", ChatID: "-100200", Timeout: time.Second})
+	assert.NoError(err)
+
+	rp := rsvp.Rsvp{Name: "Budi_Santoso", Address: "Jakarta", Attend: enumeration.AttendanceTypeYes, Message: "Selamat *menempuh* hidup baru"}
+	assert.NoError(cn.NotifyRsvp(context.Background(), rp))
+	assert.Equal("/bot123:abc/sendMessage", path)
+	assert.Equal("Markdown", body["parse_mode"])
+	assert.Equal("✅ *New RSVP from Budi\\_Santoso*\nAttend: Yes\nAddress: Jakarta\n\n💬 Selamat \\*menempuh\\* hidup baru\n", body["text"])
+
+	cn, err = notifier.NewTelegramNotifier(notifier.TelegramOption{BaseURL: server.URL, Token: "123:abc", ChatID: "42", Timeout: time.Second})
+	assert.NoError(err)
+	err = cn.NotifyRsvp(context.Background(), rp)
+	assert.EqualError(err, "telegram responded 400: Bad Request: chat not found")
+	assert.NotContains(err.Error(), "123:abc")
+}
+
+func TestSlackNotifier(t *testing.T) {
+	assert := assert.New(t)
+
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	cn, err := notifier.NewSlackNotifier(notifier.SlackOption{WebhookURL: server.URL + "/services/T000/B000/XXXX", Timeout: time.Second})
+	assert.NoError(err)
+
+	wib := time.FixedZone("WIB", 7*60*60)
+	report := &rsvp.DigestReport{
+		From:     time.Date(2019, 8, 16, 1, 0, 0, 0, time.UTC),
+		To:       time.Date(2019, 8, 17, 8, 0, 0, 0, wib),
+		NewRsvps: []rsvp.Rsvp{{Name: "Siti", Address: "Bandung <Jabar>", Attend: enumeration.AttendanceTypeNo}},
+		Changes: []rsvp.AttendanceChange{
+			{Rsvp: rsvp.Rsvp{Name: "Rudi", Attend: enumeration.AttendanceTypeMaybe}, Previous: enumeration.AttendanceTypeYes},
+		},
+		Totals: rsvp.AttendanceSummary{Yes: 10, No: 3, Maybe: 2, Total: 15},
+	}
+	assert.NoError(cn.SendDigestReport(context.Background(), rsvp.DigestDaily, report))
+	assert.Equal(true, body["mrkdwn"])
+	assert.Equal("📊 *RSVP daily digest*\n"+
+		"Fri, 16 Aug 08:00 to Sat, 17 Aug 08:00\n"+
+		"15 responses: ✅ 10 attending, 🤔 2 maybe, ❌ 3 not attending\n"+
+		"\n*New responses (1)*\n"+
+		"❌ Siti, Bandung &lt;Jabar&gt;\n"+
+		"\n*Attendance changes (1)*\n"+
+		"Rudi: ✅ Yes → 🤔 Maybe\n", body["text"])
+
+	server.Close()
+	assert.Error(cn.NotifyRsvp(context.Background(), rsvp.Rsvp{Name: "Budi"}))
+}
@@ -0,0 +1,130 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"net/mail"
+	texttemplate "text/template"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/enumeration"
+	"github.com/faris-arifiansyah/fws-rsvp/ics"
+)
+
+// CalendarFile is the name of the event attached to a confirmation
+const CalendarFile = "invitation.ics"
+
+// answers tell the guest what they answered
+var answers = map[enumeration.AttendanceType]string{
+	enumeration.AttendanceTypeYes:   "You will attend. We look forward to celebrating with you!",
+	enumeration.AttendanceTypeMaybe: "You might attend. We hope to see you there!",
+	enumeration.AttendanceTypeNo:    "You will not be able to attend. Thank you for letting us know.",
+}
+
+var textConfirmation = texttemplate.Must(texttemplate.New("text").Parse(`Dear {{.Rsvp.Name}},
+
+Thank you for your RSVP. {{.Answer}}
+{{with .Event}}{{if .Summary}}
+{{.Summary}}{{end}}
+When:  {{$.When}}{{if .Location}}
+Where: {{.Location}}{{end}}{{if .Description}}
+
+{{.Description}}{{end}}{{if .URL}}
+
+{{.URL}}{{end}}
+{{end}}{{if .Attached}}
+The event is attached, open it to add it to your calendar.
+{{end}}`))
+
+var htmlConfirmation = htmltemplate.Must(htmltemplate.New("html").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif">
+<p>Dear {{.Rsvp.Name}},</p>
+<p>Thank you for your RSVP. {{.Answer}}</p>
+{{with .Event}}{{if .Summary}}<h2>{{.Summary}}</h2>{{end}}
+<table>
+<tr><th align="left">When</th><td>{{$.When}}</td></tr>
+{{if .Location}}<tr><th align="left">Where</th><td>{{.Location}}</td></tr>{{end}}
+</table>
+{{if .Description}}<p style="white-space: pre-wrap">{{.Description}}</p>{{end}}
+{{if .URL}}<p><a href="{{.URL}}">{{.URL}}</a></p>{{end}}{{end}}
+{{if .Attached}}<p>The event is attached, open it to add it to your calendar.</p>{{end}}
+</body>
+</html>
+`))
+
+// Confirmer emails guests who gave their email a confirmation of their answer, with the event
+// attached as an iCalendar file when they may attend
+type Confirmer struct {
+	sn    *SMTPNotifier
+	event ics.Event
+}
+
+// NewConfirmer is a function to create Confirmer sending with sn, event is left out when its Start is zero
+func NewConfirmer(sn *SMTPNotifier, event ics.Event) *Confirmer {
+	return &Confirmer{sn, event}
+}
+
+// Publish confirms the RSVP of a rsvp.created event sent by a guest, and ignores other events
+func (c *Confirmer) Publish(ctx context.Context, e rsvp.Event) error {
+	if e.Type != rsvp.EventRsvpCreated || e.Bulk || e.Rsvp.Email == "" {
+		return nil
+	}
+
+	addr, err := mail.ParseAddress(e.Rsvp.Email)
+	if err != nil {
+		// the RSVP was saved with an address that cannot be sent to, retrying will not help
+		return nil
+	}
+
+	msg, err := c.Message(e.Rsvp, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return c.sn.send([]string{addr.Address}, msg)
+}
+
+// Message returns the confirmation of rp sent at date
+func (c *Confirmer) Message(rp rsvp.Rsvp, date time.Time) ([]byte, error) {
+	data := struct {
+		Rsvp     rsvp.Rsvp
+		Answer   string
+		Event    *ics.Event
+		When     string
+		Attached bool
+	}{Rsvp: rp, Answer: answers[rp.Attend]}
+
+	var attachments []attachment
+	if !c.event.Start.IsZero() {
+		data.Event = &c.event
+		data.When = c.event.Start.Format("Monday, 2 January 2006 15:04 MST")
+		if rp.Attend != enumeration.AttendanceTypeNo {
+			data.Attached = true
+			attachments = append(attachments, attachment{
+				name:        CalendarFile,
+				contentType: "text/calendar; charset=utf-8; method=PUBLISH",
+				content:     ics.Marshal(date, c.event),
+			})
+		}
+	}
+
+	var text, html bytes.Buffer
+	if err := textConfirmation.Execute(&text, data); err != nil {
+		return nil, err
+	}
+	if err := htmlConfirmation.Execute(&html, data); err != nil {
+		return nil, err
+	}
+
+	subject := "Your RSVP"
+	if c.event.Summary != "" {
+		subject = fmt.Sprintf("Your RSVP to %s", c.event.Summary)
+	}
+	to := (&mail.Address{Name: rp.Name, Address: rp.Email}).String()
+
+	return c.sn.message([]string{to}, subject, date, text.String(), html.String(), attachments...)
+}
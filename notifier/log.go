@@ -0,0 +1,32 @@
+package notifier
+
+import (
+	"context"
+	"log"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+)
+
+// LogSender writes digest reports to the log instead of sending them, to try out templates
+type LogSender struct{}
+
+func (LogSender) SendDigest(ctx context.Context, subject string, text string, html string) error {
+	log.Printf("digest: %s\n%s", subject, text)
+	return nil
+}
+
+// LogReminderSender writes reminders to the log instead of sending them, a local stand-in
+// for a channel such as SMS without a provider set up
+type LogReminderSender struct {
+	Channel string
+}
+
+func (ls LogReminderSender) SendReminder(ctx context.Context, inv rsvp.Invitee, text string) error {
+	to := inv.Email
+	if ls.Channel == rsvp.ReminderSMS {
+		to = inv.Phone
+	}
+
+	log.Printf("reminder via %s to %s <%s>: %s", ls.Channel, inv.Name, to, text)
+	return nil
+}
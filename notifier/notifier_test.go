@@ -0,0 +1,165 @@
+package notifier_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"testing"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/enumeration"
+	"github.com/faris-arifiansyah/fws-rsvp/ics"
+	"github.com/faris-arifiansyah/fws-rsvp/notifier"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSMTPNotifierMessage(t *testing.T) {
+	assert := assert.New(t)
+
+	sn, err := notifier.NewSMTPNotifier(notifier.SMTPOption{
+		Host: "smtp.example.com",
+		Port: 587,
+		TLS:  notifier.TLSStartTLS,
+		From: "FWS RSVP <rsvp@example.com>",
+		To:   []string{"bride@example.com", "groom@example.com"},
+	})
+	assert.NoError(err)
+
+	rp := rsvp.Rsvp{Name: "Budi <Pakdhe>", Address: "Jakarta", Attend: enumeration.AttendanceTypeYes, Message: "Selamat menempuh hidup baru, semoga sakinah 🙏"}
+	raw, err := sn.Message(rp, time.Date(2019, 8, 17, 10, 30, 0, 0, time.UTC))
+	assert.NoError(err)
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	assert.NoError(err)
+	assert.Equal("bride@example.com, groom@example.com", msg.Header.Get("To"))
+	assert.Equal("Sat, 17 Aug 2019 10:30:00 +0000", msg.Header.Get("Date"))
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	assert.NoError(err)
+	assert.Equal("New RSVP from Budi <Pakdhe> (Yes)", subject)
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	assert.NoError(err)
+	assert.Equal("multipart/alternative", mediaType)
+
+	bodies := map[string]string{}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		// multipart.Reader decodes quoted-printable parts itself
+		b, err := ioutil.ReadAll(part)
+		assert.NoError(err)
+		bodies[part.Header.Get("Content-Type")] = string(b)
+	}
+
+	assert.Contains(bodies["text/plain; charset=utf-8"], "Name:    Budi <Pakdhe>")
+	assert.Contains(bodies["text/plain; charset=utf-8"], "semoga sakinah 🙏")
+	assert.Contains(bodies["text/html; charset=utf-8"], "<h2>New RSVP from Budi &lt;Pakdhe&gt;</h2>")
+	assert.Contains(bodies["text/html; charset=utf-8"], "<td>Yes</td>")
+
+	_, err = notifier.NewSMTPNotifier(notifier.SMTPOption{TLS: "ssl", From: "rsvp@example.com", To: []string{"bride@example.com"}})
+	assert.Error(err)
+}
+
+// recordingNotifier records the RSVPs it is told about
+type recordingNotifier struct {
+	names []string
+}
+
+func (rn *recordingNotifier) NotifyRsvp(ctx context.Context, rp rsvp.Rsvp) error {
+	rn.names = append(rn.names, rp.Name)
+	return nil
+}
+
+func TestPublisher(t *testing.T) {
+	rn := &recordingNotifier{}
+	p := notifier.NewPublisher(rn)
+
+	for _, e := range []rsvp.Event{
+		{Type: rsvp.EventRsvpCreated, Rsvp: rsvp.Rsvp{Name: "Budi"}},
+		{Type: rsvp.EventRsvpUpdated, Rsvp: rsvp.Rsvp{Name: "Siti"}},
+		{Type: rsvp.EventRsvpDeleted, Rsvp: rsvp.Rsvp{Name: "Rudi"}},
+		// imported
+		{Type: rsvp.EventRsvpCreated, Bulk: true, Rsvp: rsvp.Rsvp{Name: "Dewi"}},
+	} {
+		assert.NoError(t, p.Publish(context.Background(), e))
+	}
+
+	assert.Equal(t, []string{"Budi"}, rn.names)
+}
+
+func TestConfirmerMessage(t *testing.T) {
+	assert := assert.New(t)
+
+	sn, err := notifier.NewSMTPNotifier(notifier.SMTPOption{
+		Host: "smtp.example.com",
+		Port: 587,
+		TLS:  notifier.TLSStartTLS,
+		From: "FWS RSVP <rsvp@example.com>",
+		To:   []string{"bride@example.com"},
+	})
+	assert.NoError(err)
+
+	wib := time.FixedZone("WIB", 7*60*60)
+	c := notifier.NewConfirmer(sn, ics.Event{
+		UID:      "20190817T030000Z@fws-rsvp",
+		Summary:  "Budi & Siti's Wedding",
+		Location: "Gedung Serbaguna, Jakarta",
+		Start:    time.Date(2019, 8, 17, 10, 0, 0, 0, wib),
+		End:      time.Date(2019, 8, 17, 14, 0, 0, 0, wib),
+	})
+
+	rp := rsvp.Rsvp{Name: "Rudi", Address: "Bogor", Attend: enumeration.AttendanceTypeYes, Email: "rudi@example.com"}
+	raw, err := c.Message(rp, time.Date(2019, 8, 1, 9, 0, 0, 0, time.UTC))
+	assert.NoError(err)
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	assert.NoError(err)
+	assert.Equal(`"Rudi" <rudi@example.com>`, msg.Header.Get("To"))
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	assert.NoError(err)
+	assert.Equal("multipart/mixed", mediaType)
+
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	part, err := mr.NextPart()
+	assert.NoError(err)
+	mediaType, params, err = mime.ParseMediaType(part.Header.Get("Content-Type"))
+	assert.NoError(err)
+	assert.Equal("multipart/alternative", mediaType)
+
+	text, err := multipart.NewReader(part, params["boundary"]).NextPart()
+	assert.NoError(err)
+	b, err := ioutil.ReadAll(text)
+	assert.NoError(err)
+	assert.Contains(string(b), "You will attend.")
+	assert.Contains(string(b), "When:  Saturday, 17 August 2019 10:00 WIB")
+
+	part, err = mr.NextPart()
+	assert.NoError(err)
+	assert.Equal(notifier.CalendarFile, part.FileName())
+	b, err = ioutil.ReadAll(base64.NewDecoder(base64.StdEncoding, part))
+	assert.NoError(err)
+	assert.Contains(string(b), "DTSTART:20190817T030000Z\r\n")
+
+	// a guest who cannot come gets no event
+	rp.Attend = enumeration.AttendanceTypeNo
+	raw, err = c.Message(rp, time.Now())
+	assert.NoError(err)
+	msg, err = mail.ReadMessage(bytes.NewReader(raw))
+	assert.NoError(err)
+	mediaType, _, err = mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	assert.NoError(err)
+	assert.Equal("multipart/alternative", mediaType)
+
+	// imported RSVPs are not confirmed, so nothing is sent
+	assert.NoError(c.Publish(context.Background(), rsvp.Event{Type: rsvp.EventRsvpCreated, Bulk: true, Rsvp: rp}))
+}
@@ -0,0 +1,28 @@
+// Package notifier delivers RSVP notifications outside of the service
+package notifier
+
+import (
+	"context"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+)
+
+// Publisher tells a Notifier about every created RSVP, so a notifier can handle outbox events
+type Publisher struct {
+	n rsvp.Notifier
+}
+
+// NewPublisher is a function to create Publisher notifying with n
+func NewPublisher(n rsvp.Notifier) *Publisher {
+	return &Publisher{n}
+}
+
+// Publish notifies about the RSVP of a rsvp.created event sent by a guest, and ignores other events
+// along with those of an import, which would flood the notifier
+func (p *Publisher) Publish(ctx context.Context, e rsvp.Event) error {
+	if e.Type != rsvp.EventRsvpCreated || e.Bulk {
+		return nil
+	}
+
+	return p.n.NotifyRsvp(ctx, e.Rsvp)
+}
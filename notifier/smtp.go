@@ -0,0 +1,348 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+)
+
+// TLS modes of an SMTP connection
+const (
+	// TLSNone sends in plain text, only for local relays
+	TLSNone = "none"
+	// TLSStartTLS upgrades the connection with STARTTLS, usually on port 587
+	TLSStartTLS = "starttls"
+	// TLSImplicit connects over TLS, usually on port 465
+	TLSImplicit = "tls"
+)
+
+const smtpTimeout = 30 * time.Second
+
+var textEmail = texttemplate.Must(texttemplate.New("text").Parse(`New RSVP from {{.Name}}
+
+Name:    {{.Name}}
+Address: {{.Address}}
+Attend:  {{.Attend}}
+{{if .Message}}
+{{.Message}}
+{{end}}`))
+
+var htmlEmail = htmltemplate.Must(htmltemplate.New("html").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif">
+<h2>New RSVP from {{.Name}}</h2>
+<table>
+<tr><th align="left">Name</th><td>{{.Name}}</td></tr>
+<tr><th align="left">Address</th><td>{{.Address}}</td></tr>
+<tr><th align="left">Attend</th><td>{{.Attend}}</td></tr>
+</table>
+{{if .Message}}<blockquote style="white-space: pre-wrap">{{.Message}}</blockquote>{{end}}
+</body>
+</html>
+`))
+
+// SMTPOption configures SMTPNotifier
+type SMTPOption struct {
+	Host     string
+	Port     int
+	TLS      string
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// SMTPNotifier emails an HTML and plain text summary of every new RSVP
+type SMTPNotifier struct {
+	opt SMTPOption
+	// from and to are the bare addresses of the envelope
+	from string
+	to   []string
+}
+
+// NewSMTPNotifier is a function to create SMTPNotifier, an unknown TLS mode is an error
+func NewSMTPNotifier(opt SMTPOption) (*SMTPNotifier, error) {
+	switch opt.TLS {
+	case TLSNone, TLSStartTLS, TLSImplicit:
+	default:
+		return nil, fmt.Errorf("unknown SMTP TLS mode %q", opt.TLS)
+	}
+
+	if len(opt.To) == 0 {
+		return nil, fmt.Errorf("SMTP recipients cannot be empty")
+	}
+
+	from, err := mail.ParseAddress(opt.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP sender %q: %v", opt.From, err)
+	}
+
+	sn := &SMTPNotifier{opt: opt, from: from.Address}
+	for _, to := range opt.To {
+		addr, err := mail.ParseAddress(to)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SMTP recipient %q: %v", to, err)
+		}
+		sn.to = append(sn.to, addr.Address)
+	}
+
+	return sn, nil
+}
+
+func (sn *SMTPNotifier) NotifyRsvp(ctx context.Context, rp rsvp.Rsvp) error {
+	msg, err := sn.Message(rp, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return sn.send(sn.to, msg)
+}
+
+// Message returns the email about rp sent at date
+func (sn *SMTPNotifier) Message(rp rsvp.Rsvp, date time.Time) ([]byte, error) {
+	var text, html bytes.Buffer
+	if err := textEmail.Execute(&text, rp); err != nil {
+		return nil, err
+	}
+	if err := htmlEmail.Execute(&html, rp); err != nil {
+		return nil, err
+	}
+
+	return sn.message(sn.opt.To, fmt.Sprintf("New RSVP from %s (%s)", rp.Name, rp.Attend), date, text.String(), html.String())
+}
+
+// SendDigest emails a digest report to the recipients
+func (sn *SMTPNotifier) SendDigest(ctx context.Context, subject string, text string, html string) error {
+	msg, err := sn.message(sn.opt.To, subject, time.Now(), text, html)
+	if err != nil {
+		return err
+	}
+
+	return sn.send(sn.to, msg)
+}
+
+// ReminderSubject is the subject of a reminder email
+const ReminderSubject = "A reminder to RSVP"
+
+// SendReminder emails a reminder to the invitee, as plain text and as HTML keeping its lines
+func (sn *SMTPNotifier) SendReminder(ctx context.Context, inv rsvp.Invitee, text string) error {
+	addr, err := mail.ParseAddress(inv.Email)
+	if err != nil {
+		return err
+	}
+
+	html := fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif">
+<p style="white-space: pre-wrap">%s</p>
+</body>
+</html>
+`, htmltemplate.HTMLEscapeString(text))
+	to := (&mail.Address{Name: inv.Name, Address: addr.Address}).String()
+
+	msg, err := sn.message([]string{to}, ReminderSubject, time.Now(), text, html)
+	if err != nil {
+		return err
+	}
+
+	return sn.send([]string{addr.Address}, msg)
+}
+
+// attachment is a file attached to an email
+type attachment struct {
+	name        string
+	contentType string
+	content     []byte
+}
+
+// message returns an email to to with subject sent at date, with a plain text and an HTML
+// alternative, followed by attachments
+func (sn *SMTPNotifier) message(to []string, subject string, date time.Time, text string, html string, attachments ...attachment) ([]byte, error) {
+	body, contentType, err := alternatives(text, html)
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments) > 0 {
+		if body, contentType, err = mixed(body, contentType, attachments); err != nil {
+			return nil, err
+		}
+	}
+
+	var msg bytes.Buffer
+	headers := [][2]string{
+		{"From", sn.opt.From},
+		{"To", strings.Join(to, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", subject)},
+		{"Date", date.Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", contentType},
+	}
+	for _, h := range headers {
+		fmt.Fprintf(&msg, "%s: %s\r\n", h[0], h[1])
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body)
+
+	return msg.Bytes(), nil
+}
+
+// alternatives returns the multipart/alternative body of text and html, and its content type
+func alternatives(text string, html string) ([]byte, string, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	}
+	for _, part := range parts {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, "", err
+		}
+
+		qw := quotedprintable.NewWriter(pw)
+		if _, err = qw.Write([]byte(part.content)); err != nil {
+			return nil, "", err
+		}
+		if err = qw.Close(); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return body.Bytes(), fmt.Sprintf(`multipart/alternative; boundary="%s"`, mw.Boundary()), nil
+}
+
+// mixed returns the multipart/mixed body of a body of contentType followed by attachments,
+// and its content type
+func mixed(content []byte, contentType string, attachments []attachment) ([]byte, string, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	pw, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {contentType}})
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err = pw.Write(content); err != nil {
+		return nil, "", err
+	}
+
+	for _, a := range attachments {
+		mediaType, params, err := mime.ParseMediaType(a.contentType)
+		if err != nil {
+			return nil, "", err
+		}
+		params["name"] = a.name
+
+		pw, err = mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(mediaType, params)},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.name})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, "", err
+		}
+
+		// base64 lines of an email are at most 76 characters
+		encoded := base64.StdEncoding.EncodeToString(a.content)
+		for len(encoded) > 76 {
+			if _, err = io.WriteString(pw, encoded[:76]+"\r\n"); err != nil {
+				return nil, "", err
+			}
+			encoded = encoded[76:]
+		}
+		if _, err = io.WriteString(pw, encoded+"\r\n"); err != nil {
+			return nil, "", err
+		}
+	}
+	if err = mw.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return body.Bytes(), fmt.Sprintf(`multipart/mixed; boundary="%s"`, mw.Boundary()), nil
+}
+
+// send delivers msg to the bare addresses of to
+func (sn *SMTPNotifier) send(to []string, msg []byte) error {
+	addr := net.JoinHostPort(sn.opt.Host, strconv.Itoa(sn.opt.Port))
+	tlsConfig := &tls.Config{ServerName: sn.opt.Host}
+
+	var conn net.Conn
+	var err error
+	if sn.opt.TLS == TLSImplicit {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: smtpTimeout}, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp", addr, smtpTimeout)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	c, err := smtp.NewClient(conn, sn.opt.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if sn.opt.TLS == TLSStartTLS {
+		if err = c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+
+	if sn.opt.Username != "" {
+		if err = c.Auth(smtp.PlainAuth("", sn.opt.Username, sn.opt.Password, sn.opt.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err = c.Mail(sn.from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err = c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+
+	wc, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = wc.Write(msg); err != nil {
+		return err
+	}
+	if err = wc.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
@@ -0,0 +1,49 @@
+package rsvp
+
+import (
+	"context"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+// Outbox statuses of an entry
+const (
+	OutboxPending = "pending"
+	OutboxDone    = "done"
+	OutboxFailed  = "failed"
+	// OutboxDiscarded is an entry whose change was never saved
+	OutboxDiscarded = "discarded"
+)
+
+// OutboxEntry is an event written ahead of the change it tells about, kept until
+// every handler has been told about it
+type OutboxEntry struct {
+	ID    bson.ObjectId `json:"id" bson:"_id"`
+	Event Event         `json:"event" bson:"event"`
+	// RsvpID is the RSVP that was changed, kept apart from Event so it can be looked up
+	RsvpID        bson.ObjectId `json:"rsvp_id" bson:"rsvp_id"`
+	Status        string        `json:"status" bson:"status"`
+	Handled       []string      `json:"handled" bson:"handled"`
+	Attempts      int           `json:"attempts" bson:"attempts"`
+	Error         string        `json:"error,omitempty" bson:"error,omitempty"`
+	NextAttemptAt *time.Time    `json:"next_attempt_at,omitempty" bson:"next_attempt_at,omitempty"`
+	CreatedAt     time.Time     `json:"created_at" bson:"created_at"`
+	DoneAt        *time.Time    `json:"done_at,omitempty" bson:"done_at,omitempty"`
+}
+
+// OutboxRepo provides data interchange between
+// application and data provider.
+type OutboxRepo interface {
+	// ClaimOutboxEntry returns the pending entry due the longest, hidden from other claims until lease
+	ClaimOutboxEntry(ctx context.Context, now time.Time, lease time.Time) (*OutboxEntry, error)
+	UpdateOutboxEntry(ctx context.Context, e OutboxEntry) error
+	// GetOutboxEvents returns the events of eventTypes written from from until to, oldest first,
+	// whether or not the handlers have been told about them, leaving out those whose change was never saved
+	GetOutboxEvents(ctx context.Context, eventTypes []string, from time.Time, to time.Time) ([]Event, error)
+}
+
+type OutboxUsecase interface {
+	// RunOutboxDispatchers starts workers delivering outbox entries to the handlers until ctx is done
+	RunOutboxDispatchers(ctx context.Context, workers int)
+}
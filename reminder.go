@@ -0,0 +1,156 @@
+package rsvp
+
+import (
+	"context"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+// Reminder channels
+const (
+	ReminderEmail = "email"
+	ReminderSMS   = "sms"
+)
+
+// Reminder campaign statuses
+const (
+	CampaignPending   = "pending"
+	CampaignRunning   = "running"
+	CampaignDone      = "done"
+	CampaignCancelled = "cancelled"
+	CampaignFailed    = "failed"
+)
+
+// Reminder statuses
+const (
+	ReminderSent   = "sent"
+	ReminderFailed = "failed"
+)
+
+// Invitee Entity, a guest on the invitation list.
+// RespondedAt is set once an RSVP of the invitee is found, after which they are not reminded.
+type Invitee struct {
+	ID             bson.ObjectId `json:"id" bson:"_id,omitempty"`
+	Name           string        `json:"name,required" bson:"name"`
+	Address        string        `json:"address" bson:"address"`
+	Email          string        `json:"email,omitempty" bson:"email,omitempty"`
+	Phone          string        `json:"phone,omitempty" bson:"phone,omitempty"`
+	RespondedAt    *time.Time    `json:"responded_at,omitempty" bson:"responded_at,omitempty"`
+	Reminders      int           `json:"reminders" bson:"reminders"`
+	LastRemindedAt *time.Time    `json:"last_reminded_at,omitempty" bson:"last_reminded_at,omitempty"`
+	CreatedAt      time.Time     `json:"created_at" bson:"created_at"`
+}
+
+// InviteeResult is a struct container to put result
+type InviteeResult struct {
+	Data  []*Invitee
+	Total int64
+}
+
+// ReminderCampaign Entity, reminders sent in the background to every invitee who has not responded.
+// Message is a Go template executed with the invitee. A running campaign is leased to a worker
+// until LeaseUntil, after which another worker takes it over.
+type ReminderCampaign struct {
+	ID         bson.ObjectId `json:"id" bson:"_id,omitempty"`
+	Channel    string        `json:"channel" bson:"channel"`
+	Message    string        `json:"message" bson:"message"`
+	Status     string        `json:"status" bson:"status"`
+	Total      int           `json:"total" bson:"total"`
+	Sent       int           `json:"sent" bson:"sent"`
+	Skipped    int           `json:"skipped" bson:"skipped"`
+	Failed     int           `json:"failed" bson:"failed"`
+	Error      string        `json:"error,omitempty" bson:"error,omitempty"`
+	CreatedBy  string        `json:"created_by" bson:"created_by"`
+	CreatedAt  time.Time     `json:"created_at" bson:"created_at"`
+	StartedAt  *time.Time    `json:"started_at,omitempty" bson:"started_at,omitempty"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty" bson:"finished_at,omitempty"`
+	LeaseUntil *time.Time    `json:"-" bson:"lease_until,omitempty"`
+}
+
+// ReminderProgress counts reminders of a campaign by outcome
+type ReminderProgress struct {
+	Sent    int
+	Skipped int
+	Failed  int
+}
+
+// ReminderCampaignRequest holds data submitted to start a reminder campaign, the default message is used when Message is empty
+type ReminderCampaignRequest struct {
+	Channel string `json:"channel,required"`
+	Message string `json:"message"`
+}
+
+// Reminder Entity, a reminder sent to an invitee
+type Reminder struct {
+	ID         bson.ObjectId `json:"id" bson:"_id,omitempty"`
+	CampaignID bson.ObjectId `json:"campaign_id" bson:"campaign_id"`
+	InviteeID  bson.ObjectId `json:"invitee_id" bson:"invitee_id"`
+	Channel    string        `json:"channel" bson:"channel"`
+	To         string        `json:"to" bson:"to"`
+	Status     string        `json:"status" bson:"status"`
+	Error      string        `json:"error,omitempty" bson:"error,omitempty"`
+	SentAt     time.Time     `json:"sent_at" bson:"sent_at"`
+}
+
+// ReminderSender delivers a reminder to an invitee through one channel
+type ReminderSender interface {
+	SendReminder(ctx context.Context, inv Invitee, text string) error
+}
+
+// InviteeRepo provides data interchange between
+// application and invitee data provider.
+type InviteeRepo interface {
+	// CreateInvitees inserts invitees at once, setting their ID and creation time
+	CreateInvitees(ctx context.Context, invitees []Invitee) error
+	GetInvitee(ctx context.Context, id string) (*Invitee, error)
+	// GetInvitees returns invitees by name, only those who have not responded when pending is set
+	GetInvitees(ctx context.Context, p *Parameter, pending bool) (*InviteeResult, error)
+	DeleteInvitee(ctx context.Context, id string) error
+	// MarkInviteesResponded sets the response time of the invitees who have not responded
+	// and are named name at address, or have email when it is not empty
+	MarkInviteesResponded(ctx context.Context, name string, address string, email string, at time.Time) error
+	// RecordInviteeReminder counts a reminder sent to the invitee with id at at
+	RecordInviteeReminder(ctx context.Context, id bson.ObjectId, at time.Time) error
+}
+
+// ReminderRepo provides data interchange between
+// application and reminder data provider.
+type ReminderRepo interface {
+	CreateReminderCampaign(ctx context.Context, c ReminderCampaign) (ReminderCampaign, error)
+	GetReminderCampaign(ctx context.Context, id string) (*ReminderCampaign, error)
+	GetReminderCampaigns(ctx context.Context) ([]*ReminderCampaign, error)
+	// ClaimReminderCampaign marks the oldest pending campaign, or a running one whose lease is over
+	// at now, as running until lease and returns it, or returns response.NotFoundError when there is none
+	ClaimReminderCampaign(ctx context.Context, now time.Time, lease time.Time) (*ReminderCampaign, error)
+	// StartReminderCampaign sets the total of the running campaign with id and counts from zero,
+	// and the following methods change a campaign only while it is running. They extend its lease
+	// to lease, and return response.NotFoundError once it is not running, as it was cancelled.
+	StartReminderCampaign(ctx context.Context, id bson.ObjectId, total int, lease time.Time) error
+	// AddReminderProgress adds p to the counts of the running campaign with id
+	AddReminderProgress(ctx context.Context, id bson.ObjectId, p ReminderProgress, lease time.Time) error
+	// FinishReminderCampaign sets the status of the running campaign with id to status, recording errMsg
+	FinishReminderCampaign(ctx context.Context, id bson.ObjectId, status string, errMsg string, at time.Time) error
+	// CancelReminderCampaign cancels the pending or running campaign with id and returns it,
+	// response.CampaignFinishedError when it has finished
+	CancelReminderCampaign(ctx context.Context, id string, at time.Time) (*ReminderCampaign, error)
+	CreateReminder(ctx context.Context, r Reminder) error
+	// GetReminders returns the reminders sent to the invitee with inviteeID, newest first
+	GetReminders(ctx context.Context, inviteeID string) ([]*Reminder, error)
+}
+
+type ReminderUsecase interface {
+	// Publisher marks invitees as responded when their RSVP is created or updated
+	Publisher
+	CreateInvitees(ctx context.Context, invitees []Invitee) ([]Invitee, error)
+	GetInvitees(ctx context.Context, p *Parameter, pending bool) (*InviteeResult, error)
+	DeleteInvitee(ctx context.Context, id string) error
+	GetReminders(ctx context.Context, inviteeID string) ([]*Reminder, error)
+	CreateReminderCampaign(ctx context.Context, req ReminderCampaignRequest, createdBy string) (ReminderCampaign, error)
+	GetReminderCampaign(ctx context.Context, id string) (*ReminderCampaign, error)
+	GetReminderCampaigns(ctx context.Context) ([]*ReminderCampaign, error)
+	// CancelReminderCampaign stops a campaign, response.CampaignFinishedError when it has finished
+	CancelReminderCampaign(ctx context.Context, id string) (*ReminderCampaign, error)
+	// RunReminderWorker sends the reminders of pending campaigns, one campaign at a time, until ctx is done
+	RunReminderWorker(ctx context.Context)
+}
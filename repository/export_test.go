@@ -0,0 +1,4 @@
+package repository
+
+// SessionKey exposes sessionKey to the tests
+var SessionKey = sessionKey
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+)
+
+// localFileStore keeps files in a directory of the local file system
+type localFileStore struct {
+	dir string
+}
+
+// NewLocalFileStore is a function to create rsvp.FileStore keeping files in dir, creating it when missing
+func NewLocalFileStore(dir string) (rsvp.FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	return &localFileStore{dir}, nil
+}
+
+func (ls *localFileStore) Create(ctx context.Context, name string) (io.WriteCloser, error) {
+	return os.OpenFile(ls.path(name), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+}
+
+func (ls *localFileStore) Open(ctx context.Context, name string) (rsvp.StoredFile, error) {
+	f, err := os.Open(ls.path(name))
+	if os.IsNotExist(err) {
+		return nil, response.NotFoundError
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (ls *localFileStore) Remove(ctx context.Context, name string) error {
+	err := os.Remove(ls.path(name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+// path keeps name inside the directory of the store
+func (ls *localFileStore) path(name string) string {
+	return filepath.Join(ls.dir, filepath.Base(filepath.Clean("/"+name)))
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/faris-arifiansyah/mgoi"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+type mongoAdminUser struct {
+	db mgoi.DatabaseManager
+}
+
+func NewMongoAdminUser(db mgoi.DatabaseManager) rsvp.AdminUserRepo {
+	return &mongoAdminUser{db}
+}
+
+// EnsureAdminUserIndexes makes usernames unique, so two admins created at once cannot share one.
+// It fails while the collection already holds duplicate usernames.
+func EnsureAdminUserIndexes(db mgoi.DatabaseManager) error {
+	return db.Run(bson.D{
+		{Name: "createIndexes", Value: "admin_users"},
+		{Name: "indexes", Value: []bson.M{
+			{"key": bson.M{"username": 1}, "name": "username_unique", "unique": true},
+		}},
+	}, nil)
+}
+
+func (ma *mongoAdminUser) CreateAdminUser(ctx context.Context, au rsvp.AdminUser) (rsvp.AdminUser, error) {
+	au.ID = bson.NewObjectId()
+	au.CreatedAt = time.Now()
+	au.UpdatedAt = au.CreatedAt
+
+	err := ma.db.C("admin_users").Insert(au)
+	if mgo.IsDup(err) {
+		return au, response.AdminUserExistsError
+	}
+
+	return au, err
+}
+
+func (ma *mongoAdminUser) GetAdminUser(ctx context.Context, username string) (*rsvp.AdminUser, error) {
+	var au rsvp.AdminUser
+
+	err := ma.db.C("admin_users").Find(bson.M{"username": username}).One(&au)
+	if err == mgo.ErrNotFound {
+		return nil, response.NotFoundError
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &au, nil
+}
+
+func (ma *mongoAdminUser) GetAdminUsers(ctx context.Context) ([]*rsvp.AdminUser, error) {
+	var admins []*rsvp.AdminUser
+
+	err := ma.db.C("admin_users").Find(nil).Sort("username").All(&admins)
+
+	return admins, err
+}
+
+func (ma *mongoAdminUser) CountAdminUsers(ctx context.Context) (int, error) {
+	return ma.db.C("admin_users").Count()
+}
+
+func (ma *mongoAdminUser) CountAdminUsersWithRole(ctx context.Context, role string) (int, error) {
+	return ma.db.C("admin_users").Find(bson.M{"role": role}).Count()
+}
+
+func (ma *mongoAdminUser) UpdateAdminUser(ctx context.Context, au rsvp.AdminUser) error {
+	au.UpdatedAt = time.Now()
+
+	err := ma.db.C("admin_users").UpdateId(au.ID, au)
+	if err == mgo.ErrNotFound {
+		return response.NotFoundError
+	}
+
+	return err
+}
+
+func (ma *mongoAdminUser) AdvanceTOTPStep(ctx context.Context, username string, step int64) error {
+	err := ma.db.C("admin_users").Update(bson.M{
+		"username":       username,
+		"totp_last_step": bson.M{"$not": bson.M{"$gte": step}},
+	}, bson.M{"$set": bson.M{"totp_last_step": step}})
+	if err == mgo.ErrNotFound {
+		return response.NotFoundError
+	}
+
+	return err
+}
+
+func (ma *mongoAdminUser) UseRecoveryCode(ctx context.Context, username string, hash string) error {
+	err := ma.db.C("admin_users").Update(bson.M{
+		"username":       username,
+		"recovery_codes": hash,
+	}, bson.M{
+		"$pull": bson.M{"recovery_codes": hash},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+	if err == mgo.ErrNotFound {
+		return response.NotFoundError
+	}
+
+	return err
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/faris-arifiansyah/mgoi"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+type mongoAPIKey struct {
+	db mgoi.DatabaseManager
+}
+
+func NewMongoAPIKey(db mgoi.DatabaseManager) rsvp.APIKeyRepo {
+	return &mongoAPIKey{db}
+}
+
+func (mk *mongoAPIKey) CreateAPIKey(ctx context.Context, k rsvp.APIKey) (rsvp.APIKey, error) {
+	k.ID = bson.NewObjectId()
+	k.CreatedAt = time.Now()
+
+	return k, mk.db.C("api_keys").Insert(k)
+}
+
+func (mk *mongoAPIKey) GetAPIKeyByHash(ctx context.Context, hash string) (*rsvp.APIKey, error) {
+	var k rsvp.APIKey
+
+	err := mk.db.C("api_keys").Find(bson.M{"key_hash": hash}).One(&k)
+	if err == mgo.ErrNotFound {
+		return nil, response.NotFoundError
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &k, nil
+}
+
+func (mk *mongoAPIKey) GetAPIKeys(ctx context.Context) ([]*rsvp.APIKey, error) {
+	var keys []*rsvp.APIKey
+
+	err := mk.db.C("api_keys").Find(nil).Sort("-created_at").All(&keys)
+
+	return keys, err
+}
+
+func (mk *mongoAPIKey) RevokeAPIKey(ctx context.Context, id string) error {
+	if !bson.IsObjectIdHex(id) {
+		return response.NotFoundError
+	}
+
+	err := mk.db.C("api_keys").Update(
+		bson.M{"_id": bson.ObjectIdHex(id), "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	if err == mgo.ErrNotFound {
+		return response.NotFoundError
+	}
+
+	return err
+}
+
+func (mk *mongoAPIKey) TouchAPIKey(ctx context.Context, id bson.ObjectId, usedAt time.Time) error {
+	return mk.db.C("api_keys").UpdateId(id, bson.M{"$set": bson.M{"last_used_at": usedAt}})
+}
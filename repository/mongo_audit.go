@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/constants"
+	"github.com/faris-arifiansyah/mgoi"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+type mongoAudit struct {
+	db mgoi.DatabaseManager
+}
+
+func NewMongoAudit(db mgoi.DatabaseManager) rsvp.AuditRepo {
+	return &mongoAudit{db}
+}
+
+func (ma *mongoAudit) CreateAuditEntry(ctx context.Context, e rsvp.AuditEntry) (rsvp.AuditEntry, error) {
+	e.ID = bson.NewObjectId()
+	e.CreatedAt = time.Now()
+
+	return e, ma.db.C("audit_log").Insert(e)
+}
+
+// auditSelector matches the entries passing f
+func auditSelector(f *rsvp.AuditFilter) bson.M {
+	selector := bson.M{}
+	if f.Actor != "" {
+		selector["actor"] = f.Actor
+	}
+	if f.Action != "" {
+		selector["action"] = bson.RegEx{Pattern: regexp.QuoteMeta(f.Action), Options: "i"}
+	}
+	if f.Target != "" {
+		selector["target"] = f.Target
+	}
+
+	createdAt := bson.M{}
+	if !f.From.IsZero() {
+		createdAt["$gte"] = f.From
+	}
+	if !f.To.IsZero() {
+		createdAt["$lt"] = f.To
+	}
+	if len(createdAt) > 0 {
+		selector["created_at"] = createdAt
+	}
+
+	return selector
+}
+
+func (ma *mongoAudit) GetAuditEntries(ctx context.Context, f *rsvp.AuditFilter) (*rsvp.AuditResult, error) {
+	var auditResult rsvp.AuditResult
+
+	query := ma.db.C("audit_log").Find(auditSelector(f))
+	query.Sort(f.Sort)
+
+	if f.Limit != constants.NoLimit {
+		query.Skip(f.Offset)
+		query.Limit(f.Limit)
+	}
+
+	total, err := query.Count()
+	if err != nil {
+		return nil, err
+	}
+
+	err = query.All(&auditResult.Data)
+	auditResult.Total = int64(total)
+
+	return &auditResult, err
+}
+
+// mongoAuditIterator adapts mgo.Iter to rsvp.AuditIterator
+type mongoAuditIterator struct {
+	*mgo.Iter
+}
+
+func (it mongoAuditIterator) Next(e *rsvp.AuditEntry) bool {
+	return it.Iter.Next(e)
+}
+
+func (ma *mongoAudit) IterateAuditEntries(ctx context.Context, f *rsvp.AuditFilter) rsvp.AuditIterator {
+	query := ma.db.C("audit_log").Find(auditSelector(f))
+	query.Sort(f.Sort)
+
+	if f.Limit != constants.NoLimit {
+		query.Skip(f.Offset)
+		query.Limit(f.Limit)
+	}
+
+	return mongoAuditIterator{query.Iter()}
+}
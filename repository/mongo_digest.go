@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/faris-arifiansyah/mgoi"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+type mongoDigest struct {
+	db mgoi.DatabaseManager
+}
+
+func NewMongoDigest(db mgoi.DatabaseManager) rsvp.DigestRepo {
+	return &mongoDigest{db}
+}
+
+func (md *mongoDigest) ClaimDigest(ctx context.Context, d rsvp.Digest) (bool, error) {
+	change := mgo.Change{
+		Update: bson.M{"$setOnInsert": d},
+		Upsert: true,
+	}
+	info, err := md.db.C("digests").Find(bson.M{"_id": d.ID}).Apply(change, nil)
+	if err != nil {
+		return false, err
+	}
+
+	return info.UpsertedId != nil, nil
+}
+
+func (md *mongoDigest) UpdateDigest(ctx context.Context, d rsvp.Digest) error {
+	err := md.db.C("digests").UpdateId(d.ID, d)
+	if err == mgo.ErrNotFound {
+		return response.NotFoundError
+	}
+
+	return err
+}
+
+func (md *mongoDigest) GetLastSentDigest(ctx context.Context) (*rsvp.Digest, error) {
+	var d rsvp.Digest
+
+	err := md.db.C("digests").Find(bson.M{"status": rsvp.DigestSent}).Sort("-to").One(&d)
+	if err == mgo.ErrNotFound {
+		return nil, response.NotFoundError
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
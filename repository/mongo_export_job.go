@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/faris-arifiansyah/mgoi"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+type mongoExportJob struct {
+	db mgoi.DatabaseManager
+}
+
+func NewMongoExportJob(db mgoi.DatabaseManager) rsvp.ExportJobRepo {
+	return &mongoExportJob{db}
+}
+
+func (mj *mongoExportJob) CreateExportJob(ctx context.Context, job rsvp.ExportJob) (rsvp.ExportJob, error) {
+	job.ID = bson.NewObjectId()
+	job.CreatedAt = time.Now()
+
+	return job, mj.db.C("export_jobs").Insert(job)
+}
+
+func (mj *mongoExportJob) GetExportJob(ctx context.Context, id string) (*rsvp.ExportJob, error) {
+	if !bson.IsObjectIdHex(id) {
+		return nil, response.NotFoundError
+	}
+
+	var job rsvp.ExportJob
+
+	err := mj.db.C("export_jobs").Find(bson.M{"_id": bson.ObjectIdHex(id)}).One(&job)
+	if err == mgo.ErrNotFound {
+		return nil, response.NotFoundError
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func (mj *mongoExportJob) ClaimExportJob(ctx context.Context, now time.Time, lease time.Time) (*rsvp.ExportJob, error) {
+	var job rsvp.ExportJob
+
+	change := mgo.Change{
+		Update: bson.M{"$set": bson.M{
+			"status":      rsvp.ExportJobRunning,
+			"rows":        0,
+			"started_at":  now,
+			"lease_until": lease,
+			"claim":       bson.NewObjectId().Hex(),
+		}},
+		ReturnNew: true,
+	}
+	_, err := mj.db.C("export_jobs").Find(bson.M{"$or": []bson.M{
+		{"status": rsvp.ExportJobPending},
+		// jobs started before leases existed have none
+		{"status": rsvp.ExportJobRunning, "lease_until": bson.M{"$not": bson.M{"$gt": now}}},
+	}}).Sort("created_at").Apply(change, &job)
+	if err == mgo.ErrNotFound {
+		return nil, response.NotFoundError
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func (mj *mongoExportJob) UpdateExportJob(ctx context.Context, job rsvp.ExportJob) error {
+	err := mj.db.C("export_jobs").UpdateId(job.ID, job)
+	if err == mgo.ErrNotFound {
+		return response.NotFoundError
+	}
+
+	return err
+}
+
+func (mj *mongoExportJob) UpdateClaimedExportJob(ctx context.Context, job rsvp.ExportJob) error {
+	err := mj.db.C("export_jobs").Update(bson.M{"_id": job.ID, "claim": job.Claim}, job)
+	if err == mgo.ErrNotFound {
+		return response.NotFoundError
+	}
+
+	return err
+}
+
+func (mj *mongoExportJob) UpdateExportJobRows(ctx context.Context, id bson.ObjectId, claim string, rows int64, lease time.Time) error {
+	err := mj.db.C("export_jobs").Update(bson.M{"_id": id, "claim": claim}, bson.M{"$set": bson.M{"rows": rows, "lease_until": lease}})
+	if err == mgo.ErrNotFound {
+		return response.NotFoundError
+	}
+
+	return err
+}
+
+func (mj *mongoExportJob) GetExpiredExportJobs(ctx context.Context, now time.Time) ([]*rsvp.ExportJob, error) {
+	var jobs []*rsvp.ExportJob
+
+	err := mj.db.C("export_jobs").Find(bson.M{
+		"status":     rsvp.ExportJobDone,
+		"expires_at": bson.M{"$lte": now},
+	}).All(&jobs)
+
+	return jobs, err
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/constants"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/faris-arifiansyah/mgoi"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+type mongoInvitee struct {
+	db mgoi.DatabaseManager
+}
+
+func NewMongoInvitee(db mgoi.DatabaseManager) rsvp.InviteeRepo {
+	return &mongoInvitee{db}
+}
+
+func (mi *mongoInvitee) CreateInvitees(ctx context.Context, invitees []rsvp.Invitee) error {
+	now := time.Now()
+
+	docs := make([]interface{}, len(invitees))
+	for i := range invitees {
+		invitees[i].ID = bson.NewObjectId()
+		invitees[i].CreatedAt = now
+		docs[i] = invitees[i]
+	}
+
+	return mi.db.C("invitees").Insert(docs...)
+}
+
+func (mi *mongoInvitee) GetInvitee(ctx context.Context, id string) (*rsvp.Invitee, error) {
+	if !bson.IsObjectIdHex(id) {
+		return nil, response.NotFoundError
+	}
+
+	var inv rsvp.Invitee
+
+	err := mi.db.C("invitees").Find(bson.M{"_id": bson.ObjectIdHex(id)}).One(&inv)
+	if err == mgo.ErrNotFound {
+		return nil, response.NotFoundError
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &inv, nil
+}
+
+func (mi *mongoInvitee) GetInvitees(ctx context.Context, p *rsvp.Parameter, pending bool) (*rsvp.InviteeResult, error) {
+	var result rsvp.InviteeResult
+
+	selector := bson.M{}
+	if pending {
+		selector["responded_at"] = bson.M{"$exists": false}
+	}
+
+	query := mi.db.C("invitees").Find(selector)
+	query.Sort("name")
+
+	if p.Limit != constants.NoLimit {
+		query.Skip(p.Offset)
+		query.Limit(p.Limit)
+	}
+
+	total, err := query.Count()
+	if err != nil {
+		return nil, err
+	}
+
+	err = query.All(&result.Data)
+	result.Total = int64(total)
+
+	return &result, err
+}
+
+func (mi *mongoInvitee) DeleteInvitee(ctx context.Context, id string) error {
+	if !bson.IsObjectIdHex(id) {
+		return response.NotFoundError
+	}
+
+	_, err := mi.db.C("invitees").Find(bson.M{"_id": bson.ObjectIdHex(id)}).Apply(mgo.Change{Remove: true}, nil)
+	if err == mgo.ErrNotFound {
+		return response.NotFoundError
+	}
+
+	return err
+}
+
+func (mi *mongoInvitee) MarkInviteesResponded(ctx context.Context, name string, address string, email string, at time.Time) error {
+	matches := []bson.M{{"name": name, "address": address}}
+	if email != "" {
+		matches = append(matches, bson.M{"email": email})
+	}
+
+	var invitees []rsvp.Invitee
+	err := mi.db.C("invitees").Find(bson.M{"$or": matches, "responded_at": bson.M{"$exists": false}}).All(&invitees)
+	if err != nil {
+		return err
+	}
+
+	for _, inv := range invitees {
+		if err = mi.db.C("invitees").UpdateId(inv.ID, bson.M{"$set": bson.M{"responded_at": at}}); err != nil && err != mgo.ErrNotFound {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (mi *mongoInvitee) RecordInviteeReminder(ctx context.Context, id bson.ObjectId, at time.Time) error {
+	err := mi.db.C("invitees").UpdateId(id, bson.M{
+		"$inc": bson.M{"reminders": 1},
+		"$set": bson.M{"last_reminded_at": at},
+	})
+	if err == mgo.ErrNotFound {
+		return response.NotFoundError
+	}
+
+	return err
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/faris-arifiansyah/mgoi"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+type mongoOutbox struct {
+	db mgoi.DatabaseManager
+}
+
+func NewMongoOutbox(db mgoi.DatabaseManager) rsvp.OutboxRepo {
+	return &mongoOutbox{db}
+}
+
+func (mo *mongoOutbox) ClaimOutboxEntry(ctx context.Context, now time.Time, lease time.Time) (*rsvp.OutboxEntry, error) {
+	var e rsvp.OutboxEntry
+
+	change := mgo.Change{
+		Update:    bson.M{"$set": bson.M{"next_attempt_at": lease}},
+		ReturnNew: true,
+	}
+	_, err := mo.db.C("outbox").Find(bson.M{
+		"status":          rsvp.OutboxPending,
+		"next_attempt_at": bson.M{"$lte": now},
+	}).Sort("next_attempt_at").Apply(change, &e)
+	if err == mgo.ErrNotFound {
+		return nil, response.NotFoundError
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+func (mo *mongoOutbox) UpdateOutboxEntry(ctx context.Context, e rsvp.OutboxEntry) error {
+	err := mo.db.C("outbox").UpdateId(e.ID, e)
+	if err == mgo.ErrNotFound {
+		return response.NotFoundError
+	}
+
+	return err
+}
+
+func (mo *mongoOutbox) GetOutboxEvents(ctx context.Context, eventTypes []string, from time.Time, to time.Time) ([]rsvp.Event, error) {
+	var entries []rsvp.OutboxEntry
+
+	err := mo.db.C("outbox").Find(bson.M{
+		"event.type": bson.M{"$in": eventTypes},
+		"created_at": bson.M{"$gte": from, "$lt": to},
+		"status":     bson.M{"$ne": rsvp.OutboxDiscarded},
+	}).Sort("created_at").All(&entries)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]rsvp.Event, len(entries))
+	for i, e := range entries {
+		events[i] = e.Event
+	}
+
+	return events, nil
+}
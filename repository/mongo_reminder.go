@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/faris-arifiansyah/mgoi"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+type mongoReminder struct {
+	db mgoi.DatabaseManager
+}
+
+func NewMongoReminder(db mgoi.DatabaseManager) rsvp.ReminderRepo {
+	return &mongoReminder{db}
+}
+
+func (mr *mongoReminder) CreateReminderCampaign(ctx context.Context, c rsvp.ReminderCampaign) (rsvp.ReminderCampaign, error) {
+	c.ID = bson.NewObjectId()
+	c.CreatedAt = time.Now()
+
+	return c, mr.db.C("reminder_campaigns").Insert(c)
+}
+
+func (mr *mongoReminder) GetReminderCampaign(ctx context.Context, id string) (*rsvp.ReminderCampaign, error) {
+	if !bson.IsObjectIdHex(id) {
+		return nil, response.NotFoundError
+	}
+
+	var c rsvp.ReminderCampaign
+
+	err := mr.db.C("reminder_campaigns").Find(bson.M{"_id": bson.ObjectIdHex(id)}).One(&c)
+	if err == mgo.ErrNotFound {
+		return nil, response.NotFoundError
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+func (mr *mongoReminder) GetReminderCampaigns(ctx context.Context) ([]*rsvp.ReminderCampaign, error) {
+	var campaigns []*rsvp.ReminderCampaign
+
+	err := mr.db.C("reminder_campaigns").Find(nil).Sort("-created_at").All(&campaigns)
+
+	return campaigns, err
+}
+
+func (mr *mongoReminder) ClaimReminderCampaign(ctx context.Context, now time.Time, lease time.Time) (*rsvp.ReminderCampaign, error) {
+	var c rsvp.ReminderCampaign
+
+	change := mgo.Change{
+		Update: bson.M{
+			"$set": bson.M{"status": rsvp.CampaignRunning, "lease_until": lease},
+			// a campaign taken over keeps the time it first started
+			"$min": bson.M{"started_at": now},
+		},
+		ReturnNew: true,
+	}
+	_, err := mr.db.C("reminder_campaigns").Find(bson.M{"$or": []bson.M{
+		{"status": rsvp.CampaignPending},
+		// campaigns started before leases existed have none
+		{"status": rsvp.CampaignRunning, "lease_until": bson.M{"$not": bson.M{"$gt": now}}},
+	}}).Sort("created_at").Apply(change, &c)
+	if err == mgo.ErrNotFound {
+		return nil, response.NotFoundError
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+func (mr *mongoReminder) StartReminderCampaign(ctx context.Context, id bson.ObjectId, total int, lease time.Time) error {
+	return mr.updateRunning(id, bson.M{
+		"$set": bson.M{"total": total, "sent": 0, "skipped": 0, "failed": 0, "lease_until": lease},
+	})
+}
+
+func (mr *mongoReminder) AddReminderProgress(ctx context.Context, id bson.ObjectId, p rsvp.ReminderProgress, lease time.Time) error {
+	return mr.updateRunning(id, bson.M{
+		"$inc": bson.M{"sent": p.Sent, "skipped": p.Skipped, "failed": p.Failed},
+		"$set": bson.M{"lease_until": lease},
+	})
+}
+
+func (mr *mongoReminder) FinishReminderCampaign(ctx context.Context, id bson.ObjectId, status string, errMsg string, at time.Time) error {
+	set := bson.M{"status": status, "finished_at": at}
+	if errMsg != "" {
+		set["error"] = errMsg
+	}
+
+	return mr.updateRunning(id, bson.M{"$set": set, "$unset": bson.M{"lease_until": ""}})
+}
+
+// updateRunning applies update to the campaign with id while it is running
+func (mr *mongoReminder) updateRunning(id bson.ObjectId, update bson.M) error {
+	err := mr.db.C("reminder_campaigns").Update(bson.M{"_id": id, "status": rsvp.CampaignRunning}, update)
+	if err == mgo.ErrNotFound {
+		return response.NotFoundError
+	}
+
+	return err
+}
+
+func (mr *mongoReminder) CancelReminderCampaign(ctx context.Context, id string, at time.Time) (*rsvp.ReminderCampaign, error) {
+	if !bson.IsObjectIdHex(id) {
+		return nil, response.NotFoundError
+	}
+
+	var c rsvp.ReminderCampaign
+
+	change := mgo.Change{
+		Update: bson.M{
+			"$set":   bson.M{"status": rsvp.CampaignCancelled, "finished_at": at},
+			"$unset": bson.M{"lease_until": ""},
+		},
+		ReturnNew: true,
+	}
+	_, err := mr.db.C("reminder_campaigns").Find(bson.M{
+		"_id":    bson.ObjectIdHex(id),
+		"status": bson.M{"$in": []string{rsvp.CampaignPending, rsvp.CampaignRunning}},
+	}).Apply(change, &c)
+	if err == mgo.ErrNotFound {
+		if _, err = mr.GetReminderCampaign(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, response.CampaignFinishedError
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+func (mr *mongoReminder) CreateReminder(ctx context.Context, r rsvp.Reminder) error {
+	r.ID = bson.NewObjectId()
+
+	return mr.db.C("reminders").Insert(r)
+}
+
+func (mr *mongoReminder) GetReminders(ctx context.Context, inviteeID string) ([]*rsvp.Reminder, error) {
+	if !bson.IsObjectIdHex(inviteeID) {
+		return nil, response.NotFoundError
+	}
+
+	var reminders []*rsvp.Reminder
+
+	err := mr.db.C("reminders").Find(bson.M{"invitee_id": bson.ObjectIdHex(inviteeID)}).Sort("-sent_at").All(&reminders)
+
+	return reminders, err
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/faris-arifiansyah/mgoi"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+type mongoRole struct {
+	db mgoi.DatabaseManager
+}
+
+func NewMongoRole(db mgoi.DatabaseManager) rsvp.RoleRepo {
+	return &mongoRole{db}
+}
+
+func (mr *mongoRole) GetRole(ctx context.Context, name string) (*rsvp.Role, error) {
+	var role rsvp.Role
+
+	err := mr.db.C("admin_roles").Find(bson.M{"_id": name}).One(&role)
+	if err == mgo.ErrNotFound {
+		return nil, response.NotFoundError
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &role, nil
+}
+
+func (mr *mongoRole) GetRoles(ctx context.Context) ([]*rsvp.Role, error) {
+	var roles []*rsvp.Role
+
+	err := mr.db.C("admin_roles").Find(nil).Sort("_id").All(&roles)
+
+	return roles, err
+}
+
+func (mr *mongoRole) SaveRole(ctx context.Context, role rsvp.Role) error {
+	_, err := mr.db.C("admin_roles").Find(bson.M{"_id": role.Name}).Apply(mgo.Change{
+		Update: role,
+		Upsert: true,
+	}, nil)
+
+	return err
+}
+
+func (mr *mongoRole) DeleteRole(ctx context.Context, name string) error {
+	_, err := mr.db.C("admin_roles").Find(bson.M{"_id": name}).Apply(mgo.Change{Remove: true}, nil)
+	if err == mgo.ErrNotFound {
+		return response.NotFoundError
+	}
+
+	return err
+}
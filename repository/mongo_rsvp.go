@@ -5,11 +5,15 @@ import (
 	"time"
 
 	"github.com/faris-arifiansyah/fws-rsvp/constants"
+	"github.com/faris-arifiansyah/fws-rsvp/enumeration"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
 
+	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
 
 	rsvp "github.com/faris-arifiansyah/fws-rsvp"
 	"github.com/faris-arifiansyah/mgoi"
+	"github.com/google/uuid"
 )
 
 type mongoRsvp struct {
@@ -20,13 +24,79 @@ func NewMongoRsvp(db mgoi.DatabaseManager) rsvp.RsvpRepo {
 	return &mongoRsvp{db}
 }
 
+// Every change of an RSVP is written along with an entry in the outbox collection,
+// which the outbox dispatcher delivers to the handlers. Mongo cannot write both at once,
+// so the entry is written first and only delivered once the change is visible. An update
+// also sets the revision of the RSVP to the ID of its event, showing that it was applied.
+
 func (mr *mongoRsvp) CreateRsvp(ctx context.Context, rp rsvp.Rsvp) (rsvp.Rsvp, error) {
 	rp.ID = bson.NewObjectId()
 	rp.CreatedAt = time.Now()
 
+	if err := mr.stage(rsvp.EventRsvpCreated, false, rp); err != nil {
+		return rp, err
+	}
+
 	return rp, mr.db.C("rsvps").Insert(rp)
 }
 
+func (mr *mongoRsvp) GetRsvp(ctx context.Context, id string) (*rsvp.Rsvp, error) {
+	if !bson.IsObjectIdHex(id) {
+		return nil, response.NotFoundError
+	}
+
+	var rp rsvp.Rsvp
+
+	err := mr.db.C("rsvps").Find(bson.M{"_id": bson.ObjectIdHex(id)}).One(&rp)
+	if err == mgo.ErrNotFound {
+		return nil, response.NotFoundError
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &rp, nil
+}
+
+// CreateRsvps inserts rps at once, setting their ID and keeping their creation time when set
+func (mr *mongoRsvp) CreateRsvps(ctx context.Context, rps []rsvp.Rsvp) error {
+	docs := make([]interface{}, len(rps))
+	for i := range rps {
+		rps[i].ID = bson.NewObjectId()
+		if rps[i].CreatedAt.IsZero() {
+			rps[i].CreatedAt = time.Now()
+		}
+		docs[i] = rps[i]
+	}
+
+	if err := mr.stage(rsvp.EventRsvpCreated, true, rps...); err != nil {
+		return err
+	}
+
+	return mr.db.C("rsvps").Insert(docs...)
+}
+
+// GetRsvpsCreatedBetween returns the RSVPs created from from until to, oldest first
+func (mr *mongoRsvp) GetRsvpsCreatedBetween(ctx context.Context, from time.Time, to time.Time) ([]rsvp.Rsvp, error) {
+	var rps []rsvp.Rsvp
+
+	err := mr.db.C("rsvps").Find(bson.M{"created_at": bson.M{"$gte": from, "$lt": to}}).Sort("created_at").All(&rps)
+
+	return rps, err
+}
+
+func (mr *mongoRsvp) ExistsRsvp(ctx context.Context, name string, address string) (bool, error) {
+	count, err := mr.db.C("rsvps").Find(bson.M{"name": name, "address": address}).Count()
+
+	return count > 0, err
+}
+
+func (mr *mongoRsvp) ExistsRsvpWithEmail(ctx context.Context, email string) (bool, error) {
+	count, err := mr.db.C("rsvps").Find(bson.M{"email": email}).Count()
+
+	return count > 0, err
+}
+
 func (mr *mongoRsvp) GetRsvps(ctx context.Context, p *rsvp.Parameter) (*rsvp.RsvpResult, error) {
 	var rsvpResult rsvp.RsvpResult
 
@@ -48,3 +118,220 @@ func (mr *mongoRsvp) GetRsvps(ctx context.Context, p *rsvp.Parameter) (*rsvp.Rsv
 
 	return &rsvpResult, err
 }
+
+// mongoRsvpIterator adapts mgo.Iter to rsvp.RsvpIterator
+type mongoRsvpIterator struct {
+	*mgo.Iter
+}
+
+func (it mongoRsvpIterator) Next(rp *rsvp.Rsvp) bool {
+	return it.Iter.Next(rp)
+}
+
+func (mr *mongoRsvp) IterateRsvps(ctx context.Context, p *rsvp.Parameter) rsvp.RsvpIterator {
+	query := mr.db.C("rsvps").Find(nil)
+	query.Sort(p.Sort)
+
+	if p.Limit != constants.NoLimit {
+		query.Skip(p.Offset)
+		query.Limit(p.Limit)
+	}
+
+	return mongoRsvpIterator{query.Iter()}
+}
+
+// UpsertRsvp saves rp under its ID, reporting whether it was created
+func (mr *mongoRsvp) UpsertRsvp(ctx context.Context, rp rsvp.Rsvp) (bool, error) {
+	var previous rsvp.Rsvp
+
+	revision, err := mr.nextRevision()
+	if err != nil {
+		return false, err
+	}
+	rp.Revision = revision
+
+	entry := newOutboxEntry(rsvp.EventRsvpCreated, rp, time.Now())
+	entry.Event.Bulk = true
+	err = mr.db.C("rsvps").Find(bson.M{"_id": rp.ID}).One(&previous)
+	switch err {
+	case nil:
+		entry.Event.Type = rsvp.EventRsvpUpdated
+		entry.Event.Previous = &previous
+	case mgo.ErrNotFound:
+	default:
+		return false, err
+	}
+	if err = mr.db.C("outbox").Insert(entry); err != nil {
+		return false, err
+	}
+
+	// a later update saved first is kept, failing to insert the RSVP again
+	info, err := mr.db.C("rsvps").Find(bson.M{
+		"_id": rp.ID,
+		"rev": bson.M{"$not": bson.M{"$gt": rp.Revision}},
+	}).Apply(mgo.Change{Update: rp, Upsert: true}, nil)
+	if mgo.IsDup(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return info.UpsertedId != nil, nil
+}
+
+// DeleteRsvp removes the RSVP with id and returns it
+func (mr *mongoRsvp) DeleteRsvp(ctx context.Context, id string) (rsvp.Rsvp, error) {
+	var rp rsvp.Rsvp
+
+	if !bson.IsObjectIdHex(id) {
+		return rp, response.NotFoundError
+	}
+
+	query := mr.db.C("rsvps").Find(bson.M{"_id": bson.ObjectIdHex(id)})
+
+	err := query.One(&rp)
+	if err == mgo.ErrNotFound {
+		return rp, response.NotFoundError
+	}
+	if err != nil {
+		return rp, err
+	}
+
+	if err = mr.stage(rsvp.EventRsvpDeleted, false, rp); err != nil {
+		return rp, err
+	}
+
+	_, err = query.Apply(mgo.Change{Remove: true}, nil)
+	if err == mgo.ErrNotFound {
+		return rp, response.NotFoundError
+	}
+
+	return rp, err
+}
+
+// SetRsvpApproval sets or withdraws the approval of the message of the RSVP with id
+func (mr *mongoRsvp) SetRsvpApproval(ctx context.Context, id string, approvedAt *time.Time) (rsvp.Rsvp, error) {
+	var previous rsvp.Rsvp
+
+	if !bson.IsObjectIdHex(id) {
+		return previous, response.NotFoundError
+	}
+
+	err := mr.db.C("rsvps").Find(bson.M{"_id": bson.ObjectIdHex(id)}).One(&previous)
+	if err == mgo.ErrNotFound {
+		return previous, response.NotFoundError
+	}
+	if err != nil {
+		return previous, err
+	}
+
+	rp := previous
+	rp.ApprovedAt = approvedAt
+	if rp.Revision, err = mr.nextRevision(); err != nil {
+		return rp, err
+	}
+
+	entry := newOutboxEntry(rsvp.EventRsvpUpdated, rp, time.Now())
+	entry.Event.Previous = &previous
+	if err = mr.db.C("outbox").Insert(entry); err != nil {
+		return rp, err
+	}
+
+	// a later update saved first keeps its revision
+	update := bson.M{"$max": bson.M{"rev": rp.Revision}, "$unset": bson.M{"approved_at": ""}}
+	if approvedAt != nil {
+		update = bson.M{"$max": bson.M{"rev": rp.Revision}, "$set": bson.M{"approved_at": approvedAt}}
+	}
+
+	err = mr.db.C("rsvps").UpdateId(rp.ID, update)
+	if err == mgo.ErrNotFound {
+		return rp, response.NotFoundError
+	}
+
+	return rp, err
+}
+
+func (mr *mongoRsvp) GetApprovedRsvps(ctx context.Context) ([]rsvp.Rsvp, error) {
+	var rps []rsvp.Rsvp
+
+	err := mr.db.C("rsvps").Find(bson.M{"approved_at": bson.M{"$ne": nil}}).Sort("approved_at").All(&rps)
+
+	return rps, err
+}
+
+func (mr *mongoRsvp) CountRsvpsByAttendance(ctx context.Context) (*rsvp.AttendanceSummary, error) {
+	var groups []struct {
+		Attend enumeration.AttendanceType `bson:"_id"`
+		Count  int64                      `bson:"count"`
+	}
+
+	err := mr.db.C("rsvps").Pipe([]bson.M{
+		{"$group": bson.M{"_id": "$attend", "count": bson.M{"$sum": 1}}},
+	}).All(&groups)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := new(rsvp.AttendanceSummary)
+	for _, g := range groups {
+		switch g.Attend {
+		case enumeration.AttendanceTypeYes:
+			summary.Yes = g.Count
+		case enumeration.AttendanceTypeNo:
+			summary.No = g.Count
+		case enumeration.AttendanceTypeMaybe:
+			summary.Maybe = g.Count
+		}
+		summary.Total += g.Count
+	}
+
+	return summary, nil
+}
+
+// nextRevision returns the next number of the revision counter shared by every RSVP
+func (mr *mongoRsvp) nextRevision() (int64, error) {
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+
+	_, err := mr.db.C("counters").Find(bson.M{"_id": "rsvp_revision"}).Apply(mgo.Change{
+		Update:    bson.M{"$inc": bson.M{"seq": 1}},
+		Upsert:    true,
+		ReturnNew: true,
+	}, &counter)
+
+	return counter.Seq, err
+}
+
+// stage writes an outbox entry of eventType for every RSVP of rps, ahead of their change.
+// bulk marks the events of an import.
+func (mr *mongoRsvp) stage(eventType string, bulk bool, rps ...rsvp.Rsvp) error {
+	now := time.Now()
+
+	docs := make([]interface{}, len(rps))
+	for i, rp := range rps {
+		entry := newOutboxEntry(eventType, rp, now)
+		entry.Event.Bulk = bulk
+		docs[i] = entry
+	}
+
+	return mr.db.C("outbox").Insert(docs...)
+}
+
+func newOutboxEntry(eventType string, rp rsvp.Rsvp, now time.Time) rsvp.OutboxEntry {
+	return rsvp.OutboxEntry{
+		ID: bson.NewObjectId(),
+		Event: rsvp.Event{
+			ID:        uuid.New().String(),
+			Type:      eventType,
+			CreatedAt: now,
+			Rsvp:      rp,
+		},
+		RsvpID:        rp.ID,
+		Status:        rsvp.OutboxPending,
+		Handled:       []string{},
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+	}
+}
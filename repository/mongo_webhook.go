@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/constants"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/faris-arifiansyah/mgoi"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+type mongoWebhook struct {
+	db mgoi.DatabaseManager
+}
+
+func NewMongoWebhook(db mgoi.DatabaseManager) rsvp.WebhookRepo {
+	return &mongoWebhook{db}
+}
+
+// EnsureWebhookIndexes queues an event once per webhook, leaving out redeliveries.
+// It fails while the collection already holds duplicate deliveries.
+func EnsureWebhookIndexes(db mgoi.DatabaseManager) error {
+	return db.Run(bson.D{
+		{Name: "createIndexes", Value: "webhook_deliveries"},
+		{Name: "indexes", Value: []bson.M{
+			{
+				"key":                     bson.D{{Name: "webhook_id", Value: 1}, {Name: "event_id", Value: 1}},
+				"name":                    "webhook_event_unique",
+				"unique":                  true,
+				"partialFilterExpression": bson.M{"redelivery_of": bson.M{"$exists": false}},
+			},
+		}},
+	}, nil)
+}
+
+func (mw *mongoWebhook) CreateWebhook(ctx context.Context, wh rsvp.Webhook) (rsvp.Webhook, error) {
+	wh.ID = bson.NewObjectId()
+	wh.CreatedAt = time.Now()
+
+	return wh, mw.db.C("webhooks").Insert(wh)
+}
+
+func (mw *mongoWebhook) GetWebhook(ctx context.Context, id string) (*rsvp.Webhook, error) {
+	if !bson.IsObjectIdHex(id) {
+		return nil, response.NotFoundError
+	}
+
+	var wh rsvp.Webhook
+
+	err := mw.db.C("webhooks").Find(bson.M{"_id": bson.ObjectIdHex(id)}).One(&wh)
+	if err == mgo.ErrNotFound {
+		return nil, response.NotFoundError
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &wh, nil
+}
+
+func (mw *mongoWebhook) GetWebhooks(ctx context.Context) ([]*rsvp.Webhook, error) {
+	var webhooks []*rsvp.Webhook
+
+	err := mw.db.C("webhooks").Find(nil).Sort("-created_at").All(&webhooks)
+
+	return webhooks, err
+}
+
+func (mw *mongoWebhook) GetWebhooksForEvent(ctx context.Context, eventType string) ([]*rsvp.Webhook, error) {
+	var webhooks []*rsvp.Webhook
+
+	err := mw.db.C("webhooks").Find(bson.M{"events": eventType}).All(&webhooks)
+
+	return webhooks, err
+}
+
+func (mw *mongoWebhook) DeleteWebhook(ctx context.Context, id string) error {
+	if !bson.IsObjectIdHex(id) {
+		return response.NotFoundError
+	}
+
+	_, err := mw.db.C("webhooks").Find(bson.M{"_id": bson.ObjectIdHex(id)}).Apply(mgo.Change{Remove: true}, nil)
+	if err == mgo.ErrNotFound {
+		return response.NotFoundError
+	}
+
+	return err
+}
+
+func (mw *mongoWebhook) CreateWebhookDelivery(ctx context.Context, d rsvp.WebhookDelivery) (rsvp.WebhookDelivery, error) {
+	d.ID = bson.NewObjectId()
+	d.CreatedAt = time.Now()
+
+	return d, mw.db.C("webhook_deliveries").Insert(d)
+}
+
+func (mw *mongoWebhook) UpsertWebhookDelivery(ctx context.Context, d rsvp.WebhookDelivery) error {
+	d.ID = bson.NewObjectId()
+	d.CreatedAt = time.Now()
+
+	_, err := mw.db.C("webhook_deliveries").Find(bson.M{
+		"webhook_id":    d.WebhookID,
+		"event_id":      d.EventID,
+		"redelivery_of": bson.M{"$exists": false},
+	}).Apply(mgo.Change{Update: bson.M{"$setOnInsert": d}, Upsert: true}, nil)
+	// queued at the same time by another dispatcher
+	if mgo.IsDup(err) {
+		return nil
+	}
+
+	return err
+}
+
+func (mw *mongoWebhook) GetWebhookDelivery(ctx context.Context, webhookID string, id string) (*rsvp.WebhookDelivery, error) {
+	if !bson.IsObjectIdHex(webhookID) || !bson.IsObjectIdHex(id) {
+		return nil, response.NotFoundError
+	}
+
+	var d rsvp.WebhookDelivery
+
+	err := mw.db.C("webhook_deliveries").Find(bson.M{
+		"_id":        bson.ObjectIdHex(id),
+		"webhook_id": bson.ObjectIdHex(webhookID),
+	}).One(&d)
+	if err == mgo.ErrNotFound {
+		return nil, response.NotFoundError
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+func (mw *mongoWebhook) GetWebhookDeliveries(ctx context.Context, webhookID string, p *rsvp.Parameter) (*rsvp.WebhookDeliveryResult, error) {
+	if !bson.IsObjectIdHex(webhookID) {
+		return nil, response.NotFoundError
+	}
+
+	var result rsvp.WebhookDeliveryResult
+
+	query := mw.db.C("webhook_deliveries").Find(bson.M{"webhook_id": bson.ObjectIdHex(webhookID)})
+	query.Sort("-created_at")
+
+	if p.Limit != constants.NoLimit {
+		query.Skip(p.Offset)
+		query.Limit(p.Limit)
+	}
+
+	total, err := query.Count()
+	if err != nil {
+		return nil, err
+	}
+
+	err = query.All(&result.Data)
+	result.Total = int64(total)
+
+	return &result, err
+}
+
+func (mw *mongoWebhook) ClaimWebhookDelivery(ctx context.Context, now time.Time, lease time.Time) (*rsvp.WebhookDelivery, error) {
+	var d rsvp.WebhookDelivery
+
+	change := mgo.Change{
+		Update:    bson.M{"$set": bson.M{"next_attempt_at": lease}},
+		ReturnNew: true,
+	}
+	_, err := mw.db.C("webhook_deliveries").Find(bson.M{
+		"status":          rsvp.DeliveryPending,
+		"next_attempt_at": bson.M{"$lte": now},
+	}).Sort("next_attempt_at").Apply(change, &d)
+	if err == mgo.ErrNotFound {
+		return nil, response.NotFoundError
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+func (mw *mongoWebhook) UpdateWebhookDelivery(ctx context.Context, d rsvp.WebhookDelivery) error {
+	err := mw.db.C("webhook_deliveries").UpdateId(d.ID, d)
+	if err == mgo.ErrNotFound {
+		return response.NotFoundError
+	}
+
+	return err
+}
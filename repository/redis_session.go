@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/constants"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/go-redis/redis"
+)
+
+type redisSession struct {
+	rds *redis.Client
+}
+
+func NewRedisSession(rds *redis.Client) rsvp.SessionRepo {
+	return &redisSession{rds}
+}
+
+// Sessions are keyed by the token hash so a Redis dump does not leak usable tokens
+func sessionKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return constants.RedisPrefix + "session:" + hex.EncodeToString(sum[:])
+}
+
+func userSessionsKey(username string) string {
+	return constants.RedisPrefix + "sessions:" + username
+}
+
+func (rs *redisSession) CreateSession(ctx context.Context, s rsvp.Session) error {
+	key := sessionKey(s.Token)
+	ttl := time.Until(s.ExpiresAt)
+
+	s.Token = ""
+	value, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	pipe := rs.rds.TxPipeline()
+	pipe.Set(key, value, ttl)
+	pipe.SAdd(userSessionsKey(s.Username), key)
+	pipe.Expire(userSessionsKey(s.Username), ttl)
+	_, err = pipe.Exec()
+
+	return err
+}
+
+func (rs *redisSession) GetSession(ctx context.Context, token string) (*rsvp.Session, error) {
+	var s rsvp.Session
+
+	value, err := rs.rds.Get(sessionKey(token)).Bytes()
+	if err == redis.Nil {
+		return nil, response.NotFoundError
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(value, &s); err != nil {
+		return nil, err
+	}
+	s.Token = token
+
+	return &s, nil
+}
+
+func (rs *redisSession) DeleteSession(ctx context.Context, token string) error {
+	s, err := rs.GetSession(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	key := sessionKey(token)
+
+	pipe := rs.rds.TxPipeline()
+	pipe.Del(key)
+	pipe.SRem(userSessionsKey(s.Username), key)
+	_, err = pipe.Exec()
+
+	return err
+}
+
+func (rs *redisSession) DeleteSessions(ctx context.Context, username string) error {
+	keys, err := rs.rds.SMembers(userSessionsKey(username)).Result()
+	if err != nil {
+		return err
+	}
+
+	return rs.rds.Del(append(keys, userSessionsKey(username))...).Err()
+}
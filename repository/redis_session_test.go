@@ -0,0 +1,23 @@
+package repository_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/faris-arifiansyah/fws-rsvp/constants"
+	"github.com/faris-arifiansyah/fws-rsvp/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionKey(t *testing.T) {
+	assert := assert.New(t)
+
+	token := "dGhpcy1pcy1hLXNlc3Npb24tdG9rZW4"
+	key := repository.SessionKey(token)
+
+	// the key is the SHA-256 of the token, so a Redis dump does not leak usable tokens
+	assert.Equal(constants.RedisPrefix+"session:a569f44af7a541786800a86763642ad2a63378441ee9dde0d84a7bea16071870", key)
+	assert.False(strings.Contains(key, token))
+	assert.Equal(key, repository.SessionKey(token))
+	assert.NotEqual(key, repository.SessionKey(token+"x"))
+}
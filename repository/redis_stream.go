@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/constants"
+	"github.com/go-redis/redis"
+)
+
+type redisEventStream struct {
+	rds  *redis.Client
+	size int64
+	ttl  time.Duration
+}
+
+// NewRedisEventStream keeps the latest size events for ttl after the last one
+func NewRedisEventStream(rds *redis.Client, size int, ttl time.Duration) rsvp.EventStreamRepo {
+	return &redisEventStream{rds: rds, size: int64(size), ttl: ttl}
+}
+
+func streamChannel() string {
+	return constants.RedisPrefix + "stream"
+}
+
+func streamBufferKey() string {
+	return constants.RedisPrefix + "stream:buffer"
+}
+
+func (rs *redisEventStream) PublishEvent(ctx context.Context, e rsvp.Event) error {
+	value, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	pipe := rs.rds.TxPipeline()
+	if rs.size > 0 {
+		pipe.RPush(streamBufferKey(), value)
+		pipe.LTrim(streamBufferKey(), -rs.size, -1)
+		pipe.Expire(streamBufferKey(), rs.ttl)
+	}
+	pipe.Publish(streamChannel(), value)
+	_, err = pipe.Exec()
+
+	return err
+}
+
+func (rs *redisEventStream) ReplayEvents(ctx context.Context, lastID string) ([]rsvp.Event, error) {
+	values, err := rs.rds.LRange(streamBufferKey(), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]rsvp.Event, 0, len(values))
+	for _, value := range values {
+		var e rsvp.Event
+		if err := json.Unmarshal([]byte(value), &e); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	for i, e := range events {
+		if e.ID == lastID {
+			return events[i+1:], nil
+		}
+	}
+	return events, nil
+}
+
+func (rs *redisEventStream) LastEventID(ctx context.Context) (string, error) {
+	value, err := rs.rds.LIndex(streamBufferKey(), -1).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var e rsvp.Event
+	if err = json.Unmarshal([]byte(value), &e); err != nil {
+		return "", err
+	}
+	return e.ID, nil
+}
+
+func (rs *redisEventStream) SubscribeEvents(ctx context.Context) (<-chan rsvp.Event, error) {
+	ps := rs.rds.Subscribe(streamChannel())
+	// waits for the subscription so no event published after returning is missed
+	if _, err := ps.Receive(); err != nil {
+		ps.Close()
+		return nil, err
+	}
+
+	messages := ps.Channel()
+	events := make(chan rsvp.Event)
+	go func() {
+		defer close(events)
+		defer ps.Close()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+
+				var e rsvp.Event
+				if err := json.Unmarshal([]byte(msg.Payload), &e); err != nil {
+					log.Printf("stream: drop malformed event: %s", err)
+					continue
+				}
+
+				select {
+				case events <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
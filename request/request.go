@@ -4,6 +4,8 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // QueryHelper represent helper to get query string data
@@ -36,3 +38,39 @@ func (q *QueryHelper) GetInt(p string, defValue int) int {
 	}
 	return defValue
 }
+
+// GetTime to get query string value in RFC 3339 format with time data type, return defValue if query url not found or invalid
+func (q *QueryHelper) GetTime(p string, defValue time.Time) time.Time {
+	sv := q.uv.Get(p)
+	if sv != "" {
+		if v, err := time.Parse(time.RFC3339, sv); err == nil {
+			return v
+		}
+	}
+	return defValue
+}
+
+// GetBool to get query string value with boolean data type, return defValue if query url not found or invalid
+func (q *QueryHelper) GetBool(p string, defValue bool) bool {
+	sv := q.uv.Get(p)
+	if sv != "" {
+		if v, err := strconv.ParseBool(sv); err == nil {
+			return v
+		}
+	}
+	return defValue
+}
+
+// GetStrings to get comma separated query string values, return defValue if query url not found
+func (q *QueryHelper) GetStrings(p string, defValue []string) []string {
+	sv := q.uv.Get(p)
+	if sv == "" {
+		return defValue
+	}
+
+	values := strings.Split(sv, ",")
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+	return values
+}
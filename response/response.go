@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 )
 
 // SuccessResponse holds response body for success response
@@ -28,6 +29,8 @@ type MetaInfo struct {
 	Total      int64       `json:"total,omitempty"`
 	Sort       string      `json:"sort,omitempty"`
 	Facets     interface{} `json:"facets,omitempty"`
+
+	RateLimitReset *time.Time `json:"rate_limit_reset,omitempty"`
 }
 
 // ErrorInfo holds error detail
@@ -73,6 +76,102 @@ var (
 		Code:     9004,
 		HTTPCode: http.StatusTooManyRequests,
 	}
+
+	// NotFoundError represents requested resource not found error
+	NotFoundError = CustomError{
+		Message:  "Resource Not Found",
+		Code:     9005,
+		HTTPCode: http.StatusNotFound,
+	}
+
+	// AdminUserExistsError represents duplicate admin username error
+	AdminUserExistsError = CustomError{
+		Message:  "Admin User Already Exists",
+		Field:    "username",
+		Code:     9006,
+		HTTPCode: http.StatusConflict,
+	}
+
+	// WeakPasswordError represents password not satisfying the password policy error
+	WeakPasswordError = CustomError{
+		Message:  "Password must be at least 8 characters",
+		Field:    "password",
+		Code:     9007,
+		HTTPCode: http.StatusBadRequest,
+	}
+
+	// RoleInUseError represents deleting a role still assigned to admin users error
+	RoleInUseError = CustomError{
+		Message:  "Role Is Still Assigned to Admin Users",
+		Field:    "name",
+		Code:     9008,
+		HTTPCode: http.StatusConflict,
+	}
+
+	// LoginLockedError represents login locked out after too many failed attempts error
+	LoginLockedError = CustomError{
+		Message:  "Too Many Failed Login Attempts. Please try again later",
+		Code:     9009,
+		HTTPCode: http.StatusTooManyRequests,
+	}
+
+	// OTPRequiredError represents missing TOTP or recovery code of an admin with TOTP enabled error
+	OTPRequiredError = CustomError{
+		Message:  "One-Time Password Required",
+		Field:    "otp",
+		Code:     9010,
+		HTTPCode: http.StatusUnauthorized,
+	}
+
+	// TOTPAlreadyEnabledError represents enrolling TOTP that is already enabled error
+	TOTPAlreadyEnabledError = CustomError{
+		Message:  "Two-Factor Authentication Is Already Enabled",
+		Code:     9011,
+		HTTPCode: http.StatusConflict,
+	}
+
+	// InvalidLinkError represents signed link with invalid signature, expired or already used error
+	InvalidLinkError = CustomError{
+		Message:  "Link Is Invalid or Expired",
+		Code:     9012,
+		HTTPCode: http.StatusForbidden,
+	}
+
+	// ExportNotReadyError represents downloading an export job that has not finished error
+	ExportNotReadyError = CustomError{
+		Message:  "Export Is Not Ready Yet",
+		Code:     9013,
+		HTTPCode: http.StatusConflict,
+	}
+
+	// ExportExpiredError represents downloading an export job whose file has been removed error
+	ExportExpiredError = CustomError{
+		Message:  "Export Has Expired",
+		Code:     9014,
+		HTTPCode: http.StatusGone,
+	}
+
+	// CampaignFinishedError represents cancelling a reminder campaign that has finished error
+	CampaignFinishedError = CustomError{
+		Message:  "Reminder Campaign Has Finished",
+		Code:     9015,
+		HTTPCode: http.StatusConflict,
+	}
+
+	// LastOwnerError represents demoting or disabling the last enabled owner error
+	LastOwnerError = CustomError{
+		Message:  "The Last Owner Cannot Be Demoted or Disabled",
+		Field:    "username",
+		Code:     9016,
+		HTTPCode: http.StatusConflict,
+	}
+
+	// SessionRequiredError represents Basic Auth of an admin with TOTP enabled, who must log in for a session token error
+	SessionRequiredError = CustomError{
+		Message:  "Two-Factor Authentication Is Enabled. Please log in for a session token",
+		Code:     9017,
+		HTTPCode: http.StatusUnauthorized,
+	}
 )
 
 func (c CustomError) Error() string {
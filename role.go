@@ -0,0 +1,98 @@
+package rsvp
+
+import (
+	"context"
+)
+
+// Permission names an action an admin can be granted
+type Permission string
+
+const (
+	PermissionAll             Permission = "*"
+	PermissionRsvpRead        Permission = "rsvps:read"
+	PermissionRsvpExport      Permission = "rsvps:export"
+	PermissionRsvpDelete      Permission = "rsvps:delete"
+	PermissionRsvpImport      Permission = "rsvps:import"
+	PermissionReportCatering  Permission = "reports:catering"
+	PermissionAdminUserManage Permission = "admin-users:manage"
+	PermissionAPIKeyManage    Permission = "api-keys:manage"
+	PermissionAuditRead       Permission = "audit:read"
+	PermissionWebhookManage   Permission = "webhooks:manage"
+	PermissionInviteeManage   Permission = "invitees:manage"
+	PermissionMessageApprove  Permission = "messages:approve"
+)
+
+// Permissions lists every permission that can be granted to a custom role
+var Permissions = []Permission{
+	PermissionRsvpRead,
+	PermissionRsvpExport,
+	PermissionRsvpDelete,
+	PermissionRsvpImport,
+	PermissionReportCatering,
+	PermissionAdminUserManage,
+	PermissionAPIKeyManage,
+	PermissionAuditRead,
+	PermissionWebhookManage,
+	PermissionInviteeManage,
+	PermissionMessageApprove,
+}
+
+// Built-in role names
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// Role Entity
+type Role struct {
+	Name        string       `json:"name,required" bson:"_id"`
+	Permissions []Permission `json:"permissions" bson:"permissions"`
+	BuiltIn     bool         `json:"built_in" bson:"-"`
+}
+
+// BuiltInRoles are always available and cannot be changed
+var BuiltInRoles = []Role{
+	{
+		Name:        RoleOwner,
+		Permissions: []Permission{PermissionAll},
+		BuiltIn:     true,
+	},
+	{
+		Name:        RoleEditor,
+		Permissions: []Permission{PermissionRsvpRead, PermissionRsvpExport, PermissionRsvpDelete, PermissionRsvpImport, PermissionReportCatering, PermissionMessageApprove},
+		BuiltIn:     true,
+	},
+	{
+		Name:        RoleViewer,
+		Permissions: []Permission{PermissionRsvpRead, PermissionRsvpExport, PermissionReportCatering},
+		BuiltIn:     true,
+	},
+}
+
+// Allows reports whether the role grants p
+func (r Role) Allows(p Permission) bool {
+	for _, granted := range r.Permissions {
+		if granted == PermissionAll || granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleRepo provides data interchange between
+// application and custom role data provider.
+type RoleRepo interface {
+	GetRole(ctx context.Context, name string) (*Role, error)
+	GetRoles(ctx context.Context) ([]*Role, error)
+	SaveRole(ctx context.Context, role Role) error
+	DeleteRole(ctx context.Context, name string) error
+}
+
+type RoleUsecase interface {
+	GetRole(ctx context.Context, name string) (*Role, error)
+	GetRoles(ctx context.Context) ([]*Role, error)
+	SaveRole(ctx context.Context, role Role) (*Role, error)
+	DeleteRole(ctx context.Context, name string) error
+	Authorize(ctx context.Context, admin *AdminUser, p Permission) error
+}
@@ -0,0 +1,53 @@
+package rsvp_test
+
+import (
+	"testing"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleAllows(t *testing.T) {
+	assert := assert.New(t)
+
+	caterer := rsvp.Role{
+		Name:        "caterer",
+		Permissions: []rsvp.Permission{rsvp.PermissionReportCatering},
+	}
+
+	testCases := []struct {
+		role       rsvp.Role
+		permission rsvp.Permission
+		expected   bool
+	}{
+		{
+			role:       rsvp.BuiltInRoles[0],
+			permission: rsvp.PermissionRsvpDelete,
+			expected:   true,
+		},
+		{
+			role:       rsvp.BuiltInRoles[2],
+			permission: rsvp.PermissionRsvpExport,
+			expected:   true,
+		},
+		{
+			role:       rsvp.BuiltInRoles[2],
+			permission: rsvp.PermissionRsvpDelete,
+			expected:   false,
+		},
+		{
+			role:       caterer,
+			permission: rsvp.PermissionReportCatering,
+			expected:   true,
+		},
+		{
+			role:       caterer,
+			permission: rsvp.PermissionRsvpRead,
+			expected:   false,
+		},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(tc.expected, tc.role.Allows(tc.permission), "%s %s", tc.role.Name, tc.permission)
+	}
+}
@@ -2,6 +2,7 @@ package rsvp
 
 import (
 	"context"
+	"io"
 	"time"
 
 	"github.com/faris-arifiansyah/fws-rsvp/enumeration"
@@ -21,14 +22,39 @@ type RsvpResult struct {
 	Total int64
 }
 
-// Rsvp Entity
+// Rsvp Entity. ApprovedAt is set once an admin approves the message to be shown on the display.
 type Rsvp struct {
-	ID        bson.ObjectId              `json:"-" bson:"_id,omitempty"`
-	Name      string                     `json:"name,required" bson:"name"`
-	Address   string                     `json:"address,required" bson:"address"`
-	Attend    enumeration.AttendanceType `json:"attend" bson:"attend"`
-	Message   string                     `json:"message" bson:"message"`
-	CreatedAt time.Time                  `json:"created_at" bson:"created_at"`
+	ID         bson.ObjectId              `json:"id" bson:"_id,omitempty"`
+	Name       string                     `json:"name,required" bson:"name"`
+	Address    string                     `json:"address,required" bson:"address"`
+	Attend     enumeration.AttendanceType `json:"attend" bson:"attend"`
+	Message    string                     `json:"message" bson:"message"`
+	Email      string                     `json:"email,omitempty" bson:"email,omitempty"`
+	ApprovedAt *time.Time                 `json:"approved_at,omitempty" bson:"approved_at,omitempty"`
+	CreatedAt  time.Time                  `json:"created_at" bson:"created_at"`
+	// Revision numbers the updates of every RSVP in the order they were made, telling whether an update was applied
+	Revision int64 `json:"-" bson:"rev,omitempty"`
+}
+
+// AttendanceSummary holds number of RSVP per attendance type
+type AttendanceSummary struct {
+	Yes   int64 `json:"yes"`
+	No    int64 `json:"no"`
+	Maybe int64 `json:"maybe"`
+	Total int64 `json:"total"`
+}
+
+// ExportOption customizes an RSVP export, zero values keep the default layout.
+// Columns are keys of the export columns in output order, Language is "en" or "id",
+// DateFormat is a preset name or a Go time layout and Delimiter is a single character
+// or one of "comma", "semicolon" and "tab".
+type ExportOption struct {
+	Columns    []string
+	Language   string
+	TimeZone   string
+	DateFormat string
+	Delimiter  string
+	BOM        bool
 }
 
 //File represents file
@@ -37,15 +63,45 @@ type File struct {
 	Name    string
 }
 
+// RsvpIterator iterates over RSVPs without loading them all in memory
+type RsvpIterator interface {
+	Next(rp *Rsvp) bool
+	Err() error
+	Close() error
+}
+
 // RsvpRepo provides data interchange between
 // application and data provider.
 type RsvpRepo interface {
 	CreateRsvp(ctx context.Context, rp Rsvp) (Rsvp, error)
+	GetRsvp(ctx context.Context, id string) (*Rsvp, error)
 	GetRsvps(ctx context.Context, p *Parameter) (*RsvpResult, error)
+	IterateRsvps(ctx context.Context, p *Parameter) RsvpIterator
+	GetRsvpsCreatedBetween(ctx context.Context, from time.Time, to time.Time) ([]Rsvp, error)
+	// CreateRsvps inserts rps, setting the ID of each
+	CreateRsvps(ctx context.Context, rps []Rsvp) error
+	ExistsRsvp(ctx context.Context, name string, address string) (bool, error)
+	ExistsRsvpWithEmail(ctx context.Context, email string) (bool, error)
+	UpsertRsvp(ctx context.Context, rp Rsvp) (created bool, err error)
+	DeleteRsvp(ctx context.Context, id string) (Rsvp, error)
+	// SetRsvpApproval sets the approval time of the message of the RSVP with id, nil to withdraw it,
+	// and returns the RSVP as updated
+	SetRsvpApproval(ctx context.Context, id string, approvedAt *time.Time) (Rsvp, error)
+	// GetApprovedRsvps returns the RSVPs whose message is approved, oldest approval first
+	GetApprovedRsvps(ctx context.Context) ([]Rsvp, error)
+	CountRsvpsByAttendance(ctx context.Context) (*AttendanceSummary, error)
 }
 
 type Usecase interface {
 	CreateRsvp(ctx context.Context, rp Rsvp) (Rsvp, error)
 	GetRsvps(ctx context.Context, p *Parameter) (*RsvpResult, error)
-	WriteRsvpsCsv(ctx context.Context, p *Parameter) (*File, error)
+	DeleteRsvp(ctx context.Context, id string) error
+	// SetMessageApproval approves the message of the RSVP with id for the display, or withdraws it
+	SetMessageApproval(ctx context.Context, id string, approved bool) (Rsvp, error)
+	GetAttendanceSummary(ctx context.Context) (*AttendanceSummary, error)
+	WriteRsvpsCsv(ctx context.Context, p *Parameter, opt ExportOption, w io.Writer) error
+	WriteRsvpsXlsx(ctx context.Context, p *Parameter, opt ExportOption, w io.Writer) error
+	WriteRsvpsNdjson(ctx context.Context, p *Parameter, w io.Writer) error
+	ImportRsvpsNdjson(ctx context.Context, r io.Reader) (*ImportResult, error)
+	ImportRsvpsCsv(ctx context.Context, r io.Reader, opt ImportOption) (*ImportResult, error)
 }
@@ -0,0 +1,22 @@
+package rsvp
+
+import (
+	"context"
+	"time"
+)
+
+// Session Entity
+type Session struct {
+	Token     string    `json:"token,omitempty" bson:"-"`
+	Username  string    `json:"username" bson:"username"`
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"`
+}
+
+// SessionRepo provides data interchange between
+// application and admin session data provider.
+type SessionRepo interface {
+	CreateSession(ctx context.Context, s Session) error
+	GetSession(ctx context.Context, token string) (*Session, error)
+	DeleteSession(ctx context.Context, token string) error
+	DeleteSessions(ctx context.Context, username string) error
+}
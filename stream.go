@@ -0,0 +1,25 @@
+package rsvp
+
+import "context"
+
+// EventStreamRepo fans events out to the subscribers of every instance,
+// keeping the latest events so a subscriber can catch up after reconnecting
+type EventStreamRepo interface {
+	// PublishEvent adds e to the replay buffer and sends it to every subscriber
+	PublishEvent(ctx context.Context, e Event) error
+	// ReplayEvents returns the buffered events after the one with lastID, oldest first,
+	// or every buffered event when lastID is no longer buffered
+	ReplayEvents(ctx context.Context, lastID string) ([]Event, error)
+	// LastEventID returns the ID of the latest buffered event, empty when none is buffered
+	LastEventID(ctx context.Context) (string, error)
+	// SubscribeEvents returns the events published from now on, the channel is closed once ctx is done
+	SubscribeEvents(ctx context.Context) (<-chan Event, error)
+}
+
+type StreamUsecase interface {
+	// Publisher streams created and updated RSVPs
+	Publisher
+	// StreamEvents returns the events after the one with lastID followed by live events,
+	// only live events when lastID is empty. The channel is closed once ctx is done.
+	StreamEvents(ctx context.Context, lastID string) (<-chan Event, error)
+}
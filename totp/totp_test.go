@@ -0,0 +1,58 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/faris-arifiansyah/fws-rsvp/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+// secret of the RFC 6238 SHA1 test vectors
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	assert := assert.New(t)
+
+	testCases := []struct {
+		unix         int64
+		expectedCode string
+	}{
+		{unix: 59, expectedCode: "287082"},
+		{unix: 1111111109, expectedCode: "081804"},
+		{unix: 1111111111, expectedCode: "050471"},
+		{unix: 1234567890, expectedCode: "005924"},
+		{unix: 2000000000, expectedCode: "279037"},
+	}
+
+	for _, tc := range testCases {
+		code, err := totp.Code(rfcSecret, time.Unix(tc.unix, 0))
+		assert.NoError(err)
+		assert.Equal(tc.expectedCode, code)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Unix(1111111109, 0)
+
+	assert.True(totp.Validate(rfcSecret, "081804", now))
+	assert.True(totp.Validate(rfcSecret, "081804", now.Add(totp.Period*time.Second)))
+	assert.False(totp.Validate(rfcSecret, "081804", now.Add(3*totp.Period*time.Second)))
+	assert.False(totp.Validate(rfcSecret, "000000", now))
+	assert.False(totp.Validate(rfcSecret, "81804", now))
+	assert.False(totp.Validate("not base32!", "081804", now))
+}
+
+func TestValidateStep(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Unix(1111111109, 0)
+	step, ok := totp.ValidateStep(rfcSecret, "081804", now.Add(totp.Period*time.Second))
+	assert.True(ok)
+	assert.Equal(int64(1111111109/totp.Period), step)
+
+	_, ok = totp.ValidateStep(rfcSecret, "000000", now)
+	assert.False(ok)
+}
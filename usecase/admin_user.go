@@ -0,0 +1,250 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const minPasswordLength = 8
+
+// dummyHash is compared against when the username is unknown,
+// so a failed login takes the same time whether or not the user exists
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("fws-rsvp-dummy-password"), bcrypt.DefaultCost)
+
+// AdminOption configures admin usecase
+type AdminOption struct {
+	// SessionTTL is the lifetime of a session token
+	SessionTTL time.Duration
+	// TOTPIssuer names this service in authenticator apps
+	TOTPIssuer string
+}
+
+type adminUsecase struct {
+	*AccessProvider
+	opt AdminOption
+}
+
+func NewAdminUsecase(pvd *AccessProvider, opt AdminOption) rsvp.AdminUsecase {
+	return &adminUsecase{pvd, opt}
+}
+
+// CreateAdminUser creates an admin with req.Role. Creating an owner needs owner rights.
+func (au *adminUsecase) CreateAdminUser(ctx context.Context, actor *rsvp.AdminUser, req rsvp.AdminUserRequest) (rsvp.AdminUser, error) {
+	if len(req.Password) < minPasswordLength {
+		return rsvp.AdminUser{}, response.WeakPasswordError
+	}
+
+	if err := au.checkRole(ctx, req.Role); err != nil {
+		return rsvp.AdminUser{}, err
+	}
+	if isOwner(&rsvp.AdminUser{Role: req.Role}) && !isOwner(actor) {
+		return rsvp.AdminUser{}, response.UserUnauthorizedError
+	}
+
+	return au.createAdminUser(ctx, req)
+}
+
+func (au *adminUsecase) createAdminUser(ctx context.Context, req rsvp.AdminUserRequest) (rsvp.AdminUser, error) {
+	username := normalizeUsername(req.Username)
+
+	_, err := au.AdminUserRepo.GetAdminUser(ctx, username)
+	if err == nil {
+		return rsvp.AdminUser{}, response.AdminUserExistsError
+	}
+	if err != response.NotFoundError {
+		return rsvp.AdminUser{}, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return rsvp.AdminUser{}, err
+	}
+
+	return au.AdminUserRepo.CreateAdminUser(ctx, rsvp.AdminUser{
+		Username:     username,
+		PasswordHash: string(hash),
+		Role:         req.Role,
+	})
+}
+
+func (au *adminUsecase) GetAdminUsers(ctx context.Context) ([]*rsvp.AdminUser, error) {
+	return au.AdminUserRepo.GetAdminUsers(ctx)
+}
+
+func (au *adminUsecase) Authenticate(ctx context.Context, cred rsvp.AdminCredential) (*rsvp.AdminUser, error) {
+	user, err := au.AdminUserRepo.GetAdminUser(ctx, normalizeUsername(cred.Username))
+	if err != nil && err != response.NotFoundError {
+		return nil, err
+	}
+
+	hash := dummyHash
+	if user != nil {
+		hash = []byte(user.PasswordHash)
+	}
+
+	// bcrypt compares in constant time, and it always runs to keep timing uniform
+	if bcrypt.CompareHashAndPassword(hash, []byte(cred.Password)) != nil || user == nil || user.Disabled {
+		return nil, response.UserUnauthorizedError
+	}
+
+	if user.TOTPEnabled {
+		if err = au.verifySecondFactor(ctx, user, cred.OTP); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+// ChangePassword sets the password of req.Username. Admins changing their own password confirm the
+// current one, and changing the password of another owner needs owner rights.
+func (au *adminUsecase) ChangePassword(ctx context.Context, actor *rsvp.AdminUser, req rsvp.PasswordChangeRequest) error {
+	if len(req.Password) < minPasswordLength {
+		return response.WeakPasswordError
+	}
+
+	user, err := au.AdminUserRepo.GetAdminUser(ctx, normalizeUsername(req.Username))
+	if err != nil {
+		return err
+	}
+
+	self := actor != nil && actor.Username == user.Username
+	if self && bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)) != nil {
+		err := response.UserUnauthorizedError
+		err.Field = "current_password"
+		return err
+	}
+	if !self && isOwner(user) && !isOwner(actor) {
+		return response.UserUnauthorizedError
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	user.PasswordHash = string(hash)
+	if err = au.AdminUserRepo.UpdateAdminUser(ctx, *user); err != nil {
+		return err
+	}
+
+	return au.SessionRepo.DeleteSessions(ctx, user.Username)
+}
+
+// SetAdminUserDisabled disables or enables username, which needs owner rights when it is an owner
+func (au *adminUsecase) SetAdminUserDisabled(ctx context.Context, actor *rsvp.AdminUser, username string, disabled bool) error {
+	user, err := au.AdminUserRepo.GetAdminUser(ctx, normalizeUsername(username))
+	if err != nil {
+		return err
+	}
+
+	if isOwner(user) && !isOwner(actor) {
+		return response.UserUnauthorizedError
+	}
+	if disabled {
+		if err = au.checkLastOwner(ctx, user); err != nil {
+			return err
+		}
+	}
+
+	user.Disabled = disabled
+	if err = au.AdminUserRepo.UpdateAdminUser(ctx, *user); err != nil {
+		return err
+	}
+
+	if disabled {
+		return au.SessionRepo.DeleteSessions(ctx, user.Username)
+	}
+
+	return nil
+}
+
+// SetAdminUserRole assigns role to username. Granting or revoking the owner role needs owner rights.
+func (au *adminUsecase) SetAdminUserRole(ctx context.Context, actor *rsvp.AdminUser, username string, role string) error {
+	if err := au.checkRole(ctx, role); err != nil {
+		return err
+	}
+
+	user, err := au.AdminUserRepo.GetAdminUser(ctx, normalizeUsername(username))
+	if err != nil {
+		return err
+	}
+
+	if (isOwner(user) || role == rsvp.RoleOwner) && !isOwner(actor) {
+		return response.UserUnauthorizedError
+	}
+	if role != rsvp.RoleOwner {
+		if err = au.checkLastOwner(ctx, user); err != nil {
+			return err
+		}
+	}
+
+	user.Role = role
+	return au.AdminUserRepo.UpdateAdminUser(ctx, *user)
+}
+
+// checkLastOwner returns response.LastOwnerError when user is the only enabled owner,
+// who is about to lose owner rights
+func (au *adminUsecase) checkLastOwner(ctx context.Context, user *rsvp.AdminUser) error {
+	if !isOwner(user) || user.Disabled {
+		return nil
+	}
+
+	admins, err := au.AdminUserRepo.GetAdminUsers(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, admin := range admins {
+		if admin.Username != user.Username && isOwner(admin) && !admin.Disabled {
+			return nil
+		}
+	}
+
+	return response.LastOwnerError
+}
+
+// isOwner reports whether admin is an owner. Admins created before roles existed have no role and are owners.
+func isOwner(admin *rsvp.AdminUser) bool {
+	return admin != nil && (admin.Role == "" || admin.Role == rsvp.RoleOwner)
+}
+
+func (au *adminUsecase) checkRole(ctx context.Context, role string) error {
+	_, err := getRole(ctx, au.RoleRepo, role)
+	if err == response.NotFoundError {
+		err := response.BadRequestError
+		err.Field = "role"
+		return err
+	}
+
+	return err
+}
+
+// BootstrapAdminUser creates the first admin user from cred as owner when the store is empty.
+// The password policy is not applied, the operator configured it explicitly.
+func (au *adminUsecase) BootstrapAdminUser(ctx context.Context, cred rsvp.AdminCredential) error {
+	if cred.Username == "" || cred.Password == "" {
+		return nil
+	}
+
+	count, err := au.AdminUserRepo.CountAdminUsers(ctx)
+	if err != nil || count > 0 {
+		return err
+	}
+
+	_, err = au.createAdminUser(ctx, rsvp.AdminUserRequest{
+		Username: cred.Username,
+		Password: cred.Password,
+		Role:     rsvp.RoleOwner,
+	})
+	return err
+}
+
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
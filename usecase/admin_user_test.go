@@ -0,0 +1,418 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/faris-arifiansyah/fws-rsvp/usecase"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeAdminUserRepo serves admin users from memory, handing out copies like a database would
+type fakeAdminUserRepo struct {
+	data map[string]rsvp.AdminUser
+}
+
+func newFakeAdminUserRepo(users ...rsvp.AdminUser) *fakeAdminUserRepo {
+	fr := &fakeAdminUserRepo{data: map[string]rsvp.AdminUser{}}
+	for _, u := range users {
+		fr.data[u.Username] = u
+	}
+	return fr
+}
+
+func (fr *fakeAdminUserRepo) CreateAdminUser(ctx context.Context, au rsvp.AdminUser) (rsvp.AdminUser, error) {
+	if _, ok := fr.data[au.Username]; ok {
+		return rsvp.AdminUser{}, response.AdminUserExistsError
+	}
+	fr.data[au.Username] = au
+	return au, nil
+}
+
+func (fr *fakeAdminUserRepo) GetAdminUser(ctx context.Context, username string) (*rsvp.AdminUser, error) {
+	u, ok := fr.data[username]
+	if !ok {
+		return nil, response.NotFoundError
+	}
+	return &u, nil
+}
+
+func (fr *fakeAdminUserRepo) GetAdminUsers(ctx context.Context) ([]*rsvp.AdminUser, error) {
+	var users []*rsvp.AdminUser
+	for _, u := range fr.data {
+		u := u
+		users = append(users, &u)
+	}
+	return users, nil
+}
+
+func (fr *fakeAdminUserRepo) CountAdminUsers(ctx context.Context) (int, error) {
+	return len(fr.data), nil
+}
+
+func (fr *fakeAdminUserRepo) CountAdminUsersWithRole(ctx context.Context, role string) (int, error) {
+	count := 0
+	for _, u := range fr.data {
+		if u.Role == role {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (fr *fakeAdminUserRepo) UpdateAdminUser(ctx context.Context, au rsvp.AdminUser) error {
+	if _, ok := fr.data[au.Username]; !ok {
+		return response.NotFoundError
+	}
+	fr.data[au.Username] = au
+	return nil
+}
+
+func (fr *fakeAdminUserRepo) AdvanceTOTPStep(ctx context.Context, username string, step int64) error {
+	u, ok := fr.data[username]
+	if !ok || u.TOTPLastStep >= step {
+		return response.NotFoundError
+	}
+	u.TOTPLastStep = step
+	fr.data[username] = u
+	return nil
+}
+
+func (fr *fakeAdminUserRepo) UseRecoveryCode(ctx context.Context, username string, hash string) error {
+	u, ok := fr.data[username]
+	if !ok {
+		return response.NotFoundError
+	}
+	for i, h := range u.RecoveryCodes {
+		if h == hash {
+			u.RecoveryCodes = append(append([]string{}, u.RecoveryCodes[:i]...), u.RecoveryCodes[i+1:]...)
+			fr.data[username] = u
+			return nil
+		}
+	}
+	return response.NotFoundError
+}
+
+// fakeSessionRepo keeps sessions in memory by token
+type fakeSessionRepo struct {
+	data map[string]rsvp.Session
+}
+
+func newFakeSessionRepo() *fakeSessionRepo {
+	return &fakeSessionRepo{data: map[string]rsvp.Session{}}
+}
+
+func (fr *fakeSessionRepo) CreateSession(ctx context.Context, s rsvp.Session) error {
+	fr.data[s.Token] = s
+	return nil
+}
+
+func (fr *fakeSessionRepo) GetSession(ctx context.Context, token string) (*rsvp.Session, error) {
+	s, ok := fr.data[token]
+	if !ok {
+		return nil, response.NotFoundError
+	}
+	return &s, nil
+}
+
+func (fr *fakeSessionRepo) DeleteSession(ctx context.Context, token string) error {
+	if _, ok := fr.data[token]; !ok {
+		return response.NotFoundError
+	}
+	delete(fr.data, token)
+	return nil
+}
+
+func (fr *fakeSessionRepo) DeleteSessions(ctx context.Context, username string) error {
+	for token, s := range fr.data {
+		if s.Username == username {
+			delete(fr.data, token)
+		}
+	}
+	return nil
+}
+
+// fakeRoleRepo has no custom roles
+type fakeRoleRepo struct {
+	rsvp.RoleRepo
+}
+
+func (fakeRoleRepo) GetRole(ctx context.Context, name string) (*rsvp.Role, error) {
+	return nil, response.NotFoundError
+}
+
+func newAdminUsecase(users *fakeAdminUserRepo, sessions *fakeSessionRepo) rsvp.AdminUsecase {
+	return usecase.NewAdminUsecase(&usecase.AccessProvider{
+		AdminUserRepo: users,
+		SessionRepo:   sessions,
+		RoleRepo:      fakeRoleRepo{},
+	}, usecase.AdminOption{})
+}
+
+func TestSetAdminUserRole(t *testing.T) {
+	owner := rsvp.AdminUser{Username: "owner", Role: rsvp.RoleOwner}
+	legacy := rsvp.AdminUser{Username: "legacy"}
+	manager := rsvp.AdminUser{Username: "manager", Role: "manager"}
+	editor := rsvp.AdminUser{Username: "editor", Role: rsvp.RoleEditor}
+	disabledOwner := rsvp.AdminUser{Username: "former", Role: rsvp.RoleOwner, Disabled: true}
+
+	tests := []struct {
+		name     string
+		users    []rsvp.AdminUser
+		actor    *rsvp.AdminUser
+		username string
+		role     string
+		expected error
+	}{
+		{"owner grants owner", []rsvp.AdminUser{owner, editor}, &owner, "editor", rsvp.RoleOwner, nil},
+		{"non-owner grants owner", []rsvp.AdminUser{owner, manager, editor}, &manager, "editor", rsvp.RoleOwner, response.UserUnauthorizedError},
+		{"non-owner grants owner to itself", []rsvp.AdminUser{owner, manager}, &manager, "manager", rsvp.RoleOwner, response.UserUnauthorizedError},
+		{"api key grants owner", []rsvp.AdminUser{owner, editor}, nil, "editor", rsvp.RoleOwner, response.UserUnauthorizedError},
+		{"non-owner demotes owner", []rsvp.AdminUser{owner, legacy, manager}, &manager, "owner", rsvp.RoleViewer, response.UserUnauthorizedError},
+		{"non-owner changes other role", []rsvp.AdminUser{owner, manager, editor}, &manager, "editor", rsvp.RoleViewer, nil},
+		{"owner demotes owner", []rsvp.AdminUser{owner, legacy}, &owner, "legacy", rsvp.RoleEditor, nil},
+		{"last owner demotes itself", []rsvp.AdminUser{owner, editor}, &owner, "owner", rsvp.RoleEditor, response.LastOwnerError},
+		{"last enabled owner", []rsvp.AdminUser{owner, disabledOwner}, &owner, "owner", rsvp.RoleEditor, response.LastOwnerError},
+		{"unknown role", []rsvp.AdminUser{owner, editor}, &owner, "editor", "janitor", response.BadRequestError},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			users := newFakeAdminUserRepo(test.users...)
+			uc := newAdminUsecase(users, newFakeSessionRepo())
+
+			err := uc.SetAdminUserRole(context.Background(), test.actor, test.username, test.role)
+			if test.expected == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, test.role, users.data[test.username].Role)
+				return
+			}
+
+			assert.Equal(t, test.expected.(response.CustomError).Code, err.(response.CustomError).Code)
+			assert.Equal(t, test.users, sortedUsers(users, test.users))
+		})
+	}
+}
+
+func TestSetAdminUserDisabled(t *testing.T) {
+	owner := rsvp.AdminUser{Username: "owner", Role: rsvp.RoleOwner}
+	second := rsvp.AdminUser{Username: "second", Role: rsvp.RoleOwner}
+	manager := rsvp.AdminUser{Username: "manager", Role: "manager"}
+	editor := rsvp.AdminUser{Username: "editor", Role: rsvp.RoleEditor}
+	disabledOwner := rsvp.AdminUser{Username: "former", Role: rsvp.RoleOwner, Disabled: true}
+
+	tests := []struct {
+		name     string
+		users    []rsvp.AdminUser
+		actor    *rsvp.AdminUser
+		username string
+		disabled bool
+		expected error
+	}{
+		{"non-owner disables editor", []rsvp.AdminUser{owner, manager, editor}, &manager, "editor", true, nil},
+		{"non-owner disables owner", []rsvp.AdminUser{owner, second, manager}, &manager, "second", true, response.UserUnauthorizedError},
+		{"non-owner enables owner", []rsvp.AdminUser{owner, disabledOwner, manager}, &manager, "former", false, response.UserUnauthorizedError},
+		{"owner disables owner", []rsvp.AdminUser{owner, second}, &owner, "second", true, nil},
+		{"owner enables owner", []rsvp.AdminUser{owner, disabledOwner}, &owner, "former", false, nil},
+		{"last enabled owner", []rsvp.AdminUser{owner, disabledOwner, editor}, &owner, "owner", true, response.LastOwnerError},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			users := newFakeAdminUserRepo(test.users...)
+			sessions := newFakeSessionRepo()
+			sessions.data["token"] = rsvp.Session{Token: "token", Username: test.username}
+			uc := newAdminUsecase(users, sessions)
+
+			err := uc.SetAdminUserDisabled(context.Background(), test.actor, test.username, test.disabled)
+			if test.expected == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, test.disabled, users.data[test.username].Disabled)
+				// disabling signs the admin out everywhere
+				assert.Equal(t, !test.disabled, len(sessions.data) == 1)
+				return
+			}
+
+			assert.Equal(t, test.expected, err)
+			assert.Equal(t, test.users, sortedUsers(users, test.users))
+			assert.Len(t, sessions.data, 1)
+		})
+	}
+}
+
+func TestChangeOwnerPassword(t *testing.T) {
+	owner := rsvp.AdminUser{Username: "owner", Role: rsvp.RoleOwner}
+	second := rsvp.AdminUser{Username: "second", Role: rsvp.RoleOwner}
+	manager := rsvp.AdminUser{Username: "manager", Role: "manager"}
+	editor := rsvp.AdminUser{Username: "editor", Role: rsvp.RoleEditor}
+	cred := func(username string) rsvp.PasswordChangeRequest {
+		return rsvp.PasswordChangeRequest{Username: username, Password: "a-new-password"}
+	}
+
+	users := newFakeAdminUserRepo(owner, second, manager, editor)
+	uc := newAdminUsecase(users, newFakeSessionRepo())
+	ctx := context.Background()
+
+	assert.Equal(t, response.UserUnauthorizedError, uc.ChangePassword(ctx, &manager, cred("owner")))
+	assert.Equal(t, response.UserUnauthorizedError, uc.ChangePassword(ctx, nil, cred("owner")))
+	assert.Empty(t, users.data["owner"].PasswordHash)
+
+	assert.NoError(t, uc.ChangePassword(ctx, &manager, cred("editor")))
+	assert.NoError(t, uc.ChangePassword(ctx, &second, cred("owner")))
+	assert.NotEmpty(t, users.data["owner"].PasswordHash)
+}
+
+func TestChangeOwnPassword(t *testing.T) {
+	assert := assert.New(t)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("current-password"), bcrypt.MinCost)
+	budi := rsvp.AdminUser{Username: "budi", PasswordHash: string(hash), Role: rsvp.RoleEditor}
+	users := newFakeAdminUserRepo(budi)
+	sessions := newFakeSessionRepo()
+	sessions.data["token"] = rsvp.Session{Token: "token", Username: "budi"}
+	uc := newAdminUsecase(users, sessions)
+	ctx := context.Background()
+
+	err := uc.ChangePassword(ctx, &budi, rsvp.PasswordChangeRequest{Username: "budi", Password: "a-new-password"})
+	assert.Equal("current_password", err.(response.CustomError).Field)
+
+	err = uc.ChangePassword(ctx, &budi, rsvp.PasswordChangeRequest{Username: "budi", CurrentPassword: "wrong-password", Password: "a-new-password"})
+	assert.Equal("current_password", err.(response.CustomError).Field)
+	assert.Equal(budi, users.data["budi"])
+
+	err = uc.ChangePassword(ctx, &budi, rsvp.PasswordChangeRequest{Username: "budi", CurrentPassword: "current-password", Password: "short"})
+	assert.Equal(response.WeakPasswordError, err)
+
+	err = uc.ChangePassword(ctx, &budi, rsvp.PasswordChangeRequest{Username: "Budi ", CurrentPassword: "current-password", Password: "a-new-password"})
+	assert.NoError(err)
+	assert.NoError(bcrypt.CompareHashAndPassword([]byte(users.data["budi"].PasswordHash), []byte("a-new-password")))
+	// changing password signs the admin out everywhere
+	assert.Empty(sessions.data)
+}
+
+func TestAuthenticate(t *testing.T) {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("budi-password"), bcrypt.MinCost)
+	users := newFakeAdminUserRepo(
+		rsvp.AdminUser{Username: "budi", PasswordHash: string(hash), Role: rsvp.RoleEditor},
+		rsvp.AdminUser{Username: "siti", PasswordHash: string(hash), Role: rsvp.RoleEditor, Disabled: true},
+	)
+	uc := newAdminUsecase(users, newFakeSessionRepo())
+
+	tests := []struct {
+		name     string
+		cred     rsvp.AdminCredential
+		expected string
+	}{
+		{"valid", rsvp.AdminCredential{Username: "budi", Password: "budi-password"}, "budi"},
+		{"username is normalized", rsvp.AdminCredential{Username: " BUDI", Password: "budi-password"}, "budi"},
+		{"wrong password", rsvp.AdminCredential{Username: "budi", Password: "siti-password"}, ""},
+		{"empty password", rsvp.AdminCredential{Username: "budi"}, ""},
+		{"unknown user", rsvp.AdminCredential{Username: "rudi", Password: "budi-password"}, ""},
+		{"disabled", rsvp.AdminCredential{Username: "siti", Password: "budi-password"}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			user, err := uc.Authenticate(context.Background(), test.cred)
+			if test.expected == "" {
+				assert.Equal(t, response.UserUnauthorizedError, err)
+				assert.Nil(t, user)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, user.Username)
+		})
+	}
+}
+
+func TestAuthenticateUnknownUserTakesAsLong(t *testing.T) {
+	// the known user has a hash of the default cost like the dummy hash
+	hash, _ := bcrypt.GenerateFromPassword([]byte("budi-password"), bcrypt.DefaultCost)
+	uc := newAdminUsecase(newFakeAdminUserRepo(rsvp.AdminUser{Username: "budi", PasswordHash: string(hash)}), newFakeSessionRepo())
+	ctx := context.Background()
+
+	elapsed := func(username string) time.Duration {
+		start := time.Now()
+		_, err := uc.Authenticate(ctx, rsvp.AdminCredential{Username: username, Password: "wrong-password"})
+		assert.Equal(t, response.UserUnauthorizedError, err)
+		return time.Since(start)
+	}
+
+	known, unknown := elapsed("budi"), elapsed("rudi")
+	// bcrypt at the default cost takes tens of milliseconds, skipping it would be far quicker
+	assert.True(t, unknown > known/4, "unknown user took %s, known user %s", unknown, known)
+}
+
+func TestCreateAdminUser(t *testing.T) {
+	owner := rsvp.AdminUser{Username: "owner", Role: rsvp.RoleOwner}
+	manager := rsvp.AdminUser{Username: "manager", Role: "manager"}
+
+	tests := []struct {
+		name     string
+		actor    *rsvp.AdminUser
+		req      rsvp.AdminUserRequest
+		expected error
+	}{
+		{"valid", &manager, rsvp.AdminUserRequest{Username: " Siti ", Password: "12345678", Role: rsvp.RoleViewer}, nil},
+		{"owner creates owner", &owner, rsvp.AdminUserRequest{Username: "siti", Password: "12345678", Role: rsvp.RoleOwner}, nil},
+		{"short password", &manager, rsvp.AdminUserRequest{Username: "rudi", Password: "1234567", Role: rsvp.RoleViewer}, response.WeakPasswordError},
+		{"existing username", &manager, rsvp.AdminUserRequest{Username: "BUDI", Password: "12345678", Role: rsvp.RoleViewer}, response.AdminUserExistsError},
+		{"non-owner creates owner", &manager, rsvp.AdminUserRequest{Username: "rudi", Password: "12345678", Role: rsvp.RoleOwner}, response.UserUnauthorizedError},
+		{"api key creates owner", nil, rsvp.AdminUserRequest{Username: "rudi", Password: "12345678", Role: rsvp.RoleOwner}, response.UserUnauthorizedError},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			users := newFakeAdminUserRepo(owner, manager, rsvp.AdminUser{Username: "budi", Role: rsvp.RoleEditor})
+			uc := newAdminUsecase(users, newFakeSessionRepo())
+
+			user, err := uc.CreateAdminUser(context.Background(), test.actor, test.req)
+			if test.expected != nil {
+				assert.Equal(t, test.expected, err)
+				assert.Len(t, users.data, 3)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, "siti", user.Username)
+			assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(users.data["siti"].PasswordHash), []byte(test.req.Password)))
+		})
+	}
+}
+
+func TestBootstrapAdminUser(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	// the configured password is used even when the policy would refuse it
+	users := newFakeAdminUserRepo()
+	uc := newAdminUsecase(users, newFakeSessionRepo())
+	assert.NoError(uc.BootstrapAdminUser(ctx, rsvp.AdminCredential{Username: "Admin", Password: "secret"}))
+	assert.Equal(rsvp.RoleOwner, users.data["admin"].Role)
+	user, err := uc.Authenticate(ctx, rsvp.AdminCredential{Username: "admin", Password: "secret"})
+	assert.NoError(err)
+	assert.Equal("admin", user.Username)
+
+	// later startups leave the admins alone
+	assert.NoError(uc.BootstrapAdminUser(ctx, rsvp.AdminCredential{Username: "other", Password: "secret"}))
+	assert.Len(users.data, 1)
+
+	users = newFakeAdminUserRepo()
+	uc = newAdminUsecase(users, newFakeSessionRepo())
+	assert.NoError(uc.BootstrapAdminUser(ctx, rsvp.AdminCredential{Username: "admin"}))
+	assert.Empty(users.data)
+}
+
+// sortedUsers returns the users in repo in the order of like
+func sortedUsers(repo *fakeAdminUserRepo, like []rsvp.AdminUser) []rsvp.AdminUser {
+	users := make([]rsvp.AdminUser, 0, len(like))
+	for _, u := range like {
+		users = append(users, repo.data[u.Username])
+	}
+	return users
+}
@@ -0,0 +1,181 @@
+package usecase_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/faris-arifiansyah/fws-rsvp/usecase"
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAPIKeyRepo keeps keys in memory and counts the writes of last_used_at
+type fakeAPIKeyRepo struct {
+	data    []rsvp.APIKey
+	touches int
+}
+
+func (fr *fakeAPIKeyRepo) CreateAPIKey(ctx context.Context, k rsvp.APIKey) (rsvp.APIKey, error) {
+	k.ID = bson.NewObjectId()
+	k.CreatedAt = time.Now()
+	fr.data = append(fr.data, k)
+	return k, nil
+}
+
+func (fr *fakeAPIKeyRepo) GetAPIKeyByHash(ctx context.Context, hash string) (*rsvp.APIKey, error) {
+	for _, k := range fr.data {
+		if k.KeyHash == hash {
+			return &k, nil
+		}
+	}
+	return nil, response.NotFoundError
+}
+
+func (fr *fakeAPIKeyRepo) GetAPIKeys(ctx context.Context) ([]*rsvp.APIKey, error) {
+	var keys []*rsvp.APIKey
+	for _, k := range fr.data {
+		k := k
+		keys = append(keys, &k)
+	}
+	return keys, nil
+}
+
+func (fr *fakeAPIKeyRepo) RevokeAPIKey(ctx context.Context, id string) error {
+	for i := range fr.data {
+		if fr.data[i].ID.Hex() == id {
+			now := time.Now()
+			fr.data[i].RevokedAt = &now
+			return nil
+		}
+	}
+	return response.NotFoundError
+}
+
+func (fr *fakeAPIKeyRepo) TouchAPIKey(ctx context.Context, id bson.ObjectId, usedAt time.Time) error {
+	for i := range fr.data {
+		if fr.data[i].ID == id {
+			fr.data[i].LastUsedAt = &usedAt
+			fr.touches++
+			return nil
+		}
+	}
+	return response.NotFoundError
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name  string
+		req   rsvp.APIKeyRequest
+		field string
+	}{
+		{"no scopes", rsvp.APIKeyRequest{Name: "ci"}, "scopes"},
+		{"unknown scope", rsvp.APIKeyRequest{Name: "ci", Scopes: []rsvp.Permission{"rsvps:write"}}, "scopes"},
+		{"every permission", rsvp.APIKeyRequest{Name: "ci", Scopes: []rsvp.Permission{rsvp.PermissionAll}}, "scopes"},
+		{"expired", rsvp.APIKeyRequest{Name: "ci", Scopes: []rsvp.Permission{rsvp.PermissionRsvpRead}, ExpiresAt: &past}, "expires_at"},
+		{"valid", rsvp.APIKeyRequest{Name: "ci", Scopes: []rsvp.Permission{rsvp.PermissionRsvpRead}}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo := &fakeAPIKeyRepo{}
+			uc := usecase.NewAPIKeyUsecase(&usecase.AccessProvider{APIKeyRepo: repo})
+
+			k, err := uc.CreateAPIKey(context.Background(), test.req, "admin:budi")
+			if test.field != "" {
+				assert.Equal(t, test.field, err.(response.CustomError).Field)
+				assert.Empty(t, repo.data)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(k.Key, "fws_"))
+			assert.Equal(t, "admin:budi", k.CreatedBy)
+
+			// the store keeps the SHA-256 of the key, never the key itself
+			sum := sha256.Sum256([]byte(k.Key))
+			assert.Equal(t, hex.EncodeToString(sum[:]), repo.data[0].KeyHash)
+			assert.Empty(t, repo.data[0].Key)
+		})
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	repo := &fakeAPIKeyRepo{}
+	uc := usecase.NewAPIKeyUsecase(&usecase.AccessProvider{APIKeyRepo: repo})
+	ctx := context.Background()
+	scopes := []rsvp.Permission{rsvp.PermissionRsvpRead}
+
+	valid, err := uc.CreateAPIKey(ctx, rsvp.APIKeyRequest{Name: "valid", Scopes: scopes}, "admin:budi")
+	assert.NoError(t, err)
+	revoked, err := uc.CreateAPIKey(ctx, rsvp.APIKeyRequest{Name: "revoked", Scopes: scopes}, "admin:budi")
+	assert.NoError(t, err)
+	assert.NoError(t, uc.RevokeAPIKey(ctx, revoked.ID.Hex()))
+	soon := time.Now().Add(time.Hour)
+	expired, err := uc.CreateAPIKey(ctx, rsvp.APIKeyRequest{Name: "expired", Scopes: scopes, ExpiresAt: &soon}, "admin:budi")
+	assert.NoError(t, err)
+	past := time.Now().Add(-time.Second)
+	repo.data[2].ExpiresAt = &past
+
+	tests := []struct {
+		name     string
+		key      string
+		expected string
+	}{
+		{"valid", valid.Key, "valid"},
+		{"unknown", "fws_unknown", ""},
+		{"hash of a key", repo.data[0].KeyHash, ""},
+		{"revoked", revoked.Key, ""},
+		{"expired", expired.Key, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			k, err := uc.AuthenticateAPIKey(ctx, test.key)
+			if test.expected == "" {
+				assert.Equal(t, response.UserUnauthorizedError, err)
+				assert.Nil(t, k)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, k.Name)
+			assert.Equal(t, scopes, k.Scopes)
+		})
+	}
+}
+
+func TestTouchAPIKey(t *testing.T) {
+	assert := assert.New(t)
+
+	repo := &fakeAPIKeyRepo{}
+	uc := usecase.NewAPIKeyUsecase(&usecase.AccessProvider{APIKeyRepo: repo})
+	ctx := context.Background()
+
+	created, err := uc.CreateAPIKey(ctx, rsvp.APIKeyRequest{Name: "ci", Scopes: []rsvp.Permission{rsvp.PermissionRsvpRead}}, "admin:budi")
+	assert.NoError(err)
+
+	k, err := uc.AuthenticateAPIKey(ctx, created.Key)
+	assert.NoError(err)
+	assert.NotNil(k.LastUsedAt)
+	assert.Equal(1, repo.touches)
+
+	// used again within a minute, last_used_at is not written
+	_, err = uc.AuthenticateAPIKey(ctx, created.Key)
+	assert.NoError(err)
+	assert.Equal(1, repo.touches)
+
+	stale := time.Now().Add(-2 * time.Minute)
+	repo.data[0].LastUsedAt = &stale
+	k, err = uc.AuthenticateAPIKey(ctx, created.Key)
+	assert.NoError(err)
+	assert.True(k.LastUsedAt.After(stale))
+	assert.Equal(2, repo.touches)
+}
@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+)
+
+type auditUsecase struct {
+	*AccessProvider
+}
+
+func NewAuditUsecase(pvd *AccessProvider) rsvp.AuditUsecase {
+	return &auditUsecase{pvd}
+}
+
+func (au *auditUsecase) Record(ctx context.Context, e rsvp.AuditEntry) error {
+	_, err := au.AuditRepo.CreateAuditEntry(ctx, e)
+	return err
+}
+
+func (au *auditUsecase) GetAuditEntries(ctx context.Context, f *rsvp.AuditFilter) (*rsvp.AuditResult, error) {
+	f.Sort = auditSort(f.Sort)
+
+	return au.AuditRepo.GetAuditEntries(ctx, f)
+}
+
+// auditSort returns sort when it is oldest first, and newest first otherwise
+func auditSort(sort string) string {
+	if sort != "created_at" {
+		return "-created_at"
+	}
+	return sort
+}
+
+// WriteAuditCsv streams the audit entries as CSV rows to w, flushing every
+// csvFlushRows rows like WriteRsvpsCsv
+func (au *auditUsecase) WriteAuditCsv(ctx context.Context, f *rsvp.AuditFilter, w io.Writer) error {
+	f.Sort = auditSort(f.Sort)
+
+	iter := au.AuditRepo.IterateAuditEntries(ctx, f)
+	defer iter.Close()
+
+	writer := csv.NewWriter(w)
+	flusher, canFlush := w.(interface{ Flush() })
+
+	//Set Header
+	if err := writer.Write([]string{"Time", "Actor", "Action", "Target", "Query", "Status", "Request ID", "IP"}); err != nil {
+		return err
+	}
+
+	var item rsvp.AuditEntry
+	for i := 1; iter.Next(&item); i++ {
+		err := writer.Write([]string{
+			item.CreatedAt.Format(time.RFC3339),
+			item.Actor,
+			item.Action,
+			item.Target,
+			item.Query,
+			strconv.Itoa(item.Status),
+			item.RequestID,
+			item.IP,
+		})
+		if err != nil {
+			return err
+		}
+
+		if i%csvFlushRows == 0 {
+			writer.Flush()
+			if canFlush {
+				flusher.Flush()
+			}
+		}
+
+		item = rsvp.AuditEntry{}
+	}
+
+	if err := iter.Err(); err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,86 @@
+package usecase_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/usecase"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAuditRepo returns data whatever the filter, remembering the last one
+type fakeAuditRepo struct {
+	rsvp.AuditRepo
+	data   []*rsvp.AuditEntry
+	filter rsvp.AuditFilter
+}
+
+func (fr *fakeAuditRepo) GetAuditEntries(ctx context.Context, f *rsvp.AuditFilter) (*rsvp.AuditResult, error) {
+	fr.filter = *f
+	return &rsvp.AuditResult{Data: fr.data, Total: int64(len(fr.data))}, nil
+}
+
+type fakeAuditIterator struct {
+	data []*rsvp.AuditEntry
+}
+
+func (it *fakeAuditIterator) Next(e *rsvp.AuditEntry) bool {
+	if len(it.data) == 0 {
+		return false
+	}
+	*e, it.data = *it.data[0], it.data[1:]
+	return true
+}
+
+func (it *fakeAuditIterator) Err() error   { return nil }
+func (it *fakeAuditIterator) Close() error { return nil }
+
+func (fr *fakeAuditRepo) IterateAuditEntries(ctx context.Context, f *rsvp.AuditFilter) rsvp.AuditIterator {
+	fr.filter = *f
+	return &fakeAuditIterator{fr.data}
+}
+
+func TestGetAuditEntriesSort(t *testing.T) {
+	tests := []struct {
+		sort     string
+		expected string
+	}{
+		{"", "-created_at"},
+		{"created_at", "created_at"},
+		{"-created_at", "-created_at"},
+		{"actor", "-created_at"},
+	}
+
+	for _, test := range tests {
+		repo := &fakeAuditRepo{}
+		uc := usecase.NewAuditUsecase(&usecase.AccessProvider{AuditRepo: repo})
+
+		_, err := uc.GetAuditEntries(context.Background(), &rsvp.AuditFilter{Parameter: rsvp.Parameter{Sort: test.sort}})
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, repo.filter.Sort, test.sort)
+	}
+}
+
+func TestWriteAuditCsv(t *testing.T) {
+	assert := assert.New(t)
+
+	createdAt := time.Date(2019, 8, 17, 10, 30, 0, 0, time.UTC)
+	repo := &fakeAuditRepo{data: []*rsvp.AuditEntry{
+		{Actor: "admin:faris", Action: "DELETE /rsvps/5c4a", Target: "5c4a", Status: 200, RequestID: "req-1", IP: "192.0.2.1", CreatedAt: createdAt},
+		{Action: "GET /audit", Query: "format=csv&actor=admin,faris", Status: 403, RequestID: "req-2", IP: "192.0.2.2", CreatedAt: createdAt},
+	}}
+	uc := usecase.NewAuditUsecase(&usecase.AccessProvider{AuditRepo: repo})
+
+	buffer := &bytes.Buffer{}
+	f := &rsvp.AuditFilter{Parameter: rsvp.Parameter{Limit: -1}, Actor: "admin:faris"}
+	assert.NoError(uc.WriteAuditCsv(context.Background(), f, buffer))
+
+	assert.Equal("Time,Actor,Action,Target,Query,Status,Request ID,IP\n"+
+		"2019-08-17T10:30:00Z,admin:faris,DELETE /rsvps/5c4a,5c4a,,200,req-1,192.0.2.1\n"+
+		"2019-08-17T10:30:00Z,,GET /audit,,\"format=csv&actor=admin,faris\",403,req-2,192.0.2.2\n", buffer.String())
+	assert.Equal("-created_at", repo.filter.Sort)
+	assert.Equal("admin:faris", repo.filter.Actor)
+}
@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/request/validator"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+)
+
+// maxNdjsonLine is the longest line an NDJSON import accepts
+const maxNdjsonLine = 1 << 20
+
+// WriteRsvpsNdjson streams RSVPs to w as JSON, one per line, with every field
+// so ImportRsvpsNdjson restores them as they were
+func (ru *rsvpUsecase) WriteRsvpsNdjson(ctx context.Context, p *rsvp.Parameter, w io.Writer) error {
+	p.Sort = ru.GetValidSortField(p.Sort)
+
+	iter := ru.RsvpRepo.IterateRsvps(ctx, p)
+	defer iter.Close()
+
+	bw := bufio.NewWriter(w)
+	encoder := json.NewEncoder(bw)
+	flusher, canFlush := w.(interface{ Flush() })
+
+	var item rsvp.Rsvp
+	for i := 1; iter.Next(&item); i++ {
+		if err := encoder.Encode(item); err != nil {
+			return err
+		}
+
+		if i%csvFlushRows == 0 {
+			if err := bw.Flush(); err != nil {
+				return err
+			}
+			if canFlush {
+				flusher.Flush()
+			}
+		}
+
+		item = rsvp.Rsvp{}
+	}
+
+	if err := iter.Err(); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// ImportRsvpsNdjson upserts the RSVPs read from r, one JSON object per line, by their ID.
+// Importing the same lines again only updates the RSVPs, it never duplicates them.
+// Invalid lines are reported in the result and do not stop the import.
+func (ru *rsvpUsecase) ImportRsvpsNdjson(ctx context.Context, r io.Reader) (*rsvp.ImportResult, error) {
+	result := new(rsvp.ImportResult)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxNdjsonLine)
+
+	for row := 1; scanner.Scan(); row++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			result.Skipped++
+			continue
+		}
+
+		var item rsvp.Rsvp
+		if err := json.Unmarshal([]byte(line), &item); err != nil {
+			result.Fail(row, "", "invalid JSON")
+			continue
+		}
+
+		if field := invalidBackupField(item); field != "" {
+			result.Fail(row, field, response.BadRequestError.Message)
+			continue
+		}
+
+		created, err := ru.RsvpRepo.UpsertRsvp(ctx, item)
+		if err != nil {
+			return nil, err
+		}
+
+		if created {
+			result.Created++
+		} else {
+			result.Updated++
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		if err == bufio.ErrTooLong {
+			return nil, badRequest("body")
+		}
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// invalidBackupField returns the first field of item that cannot be restored as is
+func invalidBackupField(item rsvp.Rsvp) string {
+	if !item.ID.Valid() {
+		return "id"
+	}
+
+	if errs := validator.Validate(item); len(errs) > 0 {
+		return errs[0].(response.CustomError).Field
+	}
+
+	if !item.Attend.IsValid() {
+		return "attend"
+	}
+
+	if item.CreatedAt.IsZero() {
+		return "created_at"
+	}
+
+	return ""
+}
@@ -0,0 +1,349 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	htmltemplate "html/template"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+)
+
+// Files of DigestTemplates in a template directory
+const (
+	DigestSubjectFile = "subject.txt"
+	DigestTextFile    = "digest.txt"
+	DigestHTMLFile    = "digest.html"
+)
+
+const defaultDigestSubject = `RSVP {{.Schedule}} digest: {{len .NewRsvps}} new, {{.Totals.Yes}} attending`
+
+const defaultDigestText = `RSVP {{.Schedule}} digest, {{time .From}} to {{time .To}}
+
+Totals: {{.Totals.Total}} responses, {{.Totals.Yes}} attending, {{.Totals.Maybe}} maybe, {{.Totals.No}} not attending
+{{if .IsEmpty}}
+Nothing new since the last digest.
+{{end}}{{with .NewRsvps}}
+New responses ({{len .}}):
+{{range .}}- {{.Name}}, {{.Address}}: {{.Attend}}
+{{end}}{{end}}{{with .Changes}}
+Attendance changes ({{len .}}):
+{{range .}}- {{.Rsvp.Name}}: {{.Previous}} -> {{.Rsvp.Attend}}
+{{end}}{{end}}{{with .Cancelled}}
+Removed ({{len .}}):
+{{range .}}- {{.Name}}, {{.Address}}
+{{end}}{{end}}{{with .Messages}}
+New messages:
+{{range .}}
+{{.Name}}, {{time .CreatedAt}}:
+{{.Message}}
+{{end}}{{end}}`
+
+const defaultDigestHTML = `<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif">
+<h2>RSVP {{.Schedule}} digest</h2>
+<p>{{time .From}} to {{time .To}}</p>
+<table>
+<tr><th align="left">Responses</th><td>{{.Totals.Total}}</td></tr>
+<tr><th align="left">Attending</th><td>{{.Totals.Yes}}</td></tr>
+<tr><th align="left">Maybe</th><td>{{.Totals.Maybe}}</td></tr>
+<tr><th align="left">Not attending</th><td>{{.Totals.No}}</td></tr>
+</table>
+{{if .IsEmpty}}<p>Nothing new since the last digest.</p>{{end}}
+{{with .NewRsvps}}<h3>New responses ({{len .}})</h3>
+<ul>{{range .}}<li>{{.Name}}, {{.Address}}: {{.Attend}}</li>{{end}}</ul>{{end}}
+{{with .Changes}}<h3>Attendance changes ({{len .}})</h3>
+<ul>{{range .}}<li>{{.Rsvp.Name}}: {{.Previous}} &rarr; {{.Rsvp.Attend}}</li>{{end}}</ul>{{end}}
+{{with .Cancelled}}<h3>Removed ({{len .}})</h3>
+<ul>{{range .}}<li>{{.Name}}, {{.Address}}</li>{{end}}</ul>{{end}}
+{{with .Messages}}<h3>New messages</h3>
+{{range .}}<p><b>{{.Name}}</b>, {{time .CreatedAt}}</p>
+<blockquote style="white-space: pre-wrap">{{.Message}}</blockquote>{{end}}{{end}}
+</body>
+</html>
+`
+
+// DigestTemplates render a digest report, executed with the report, its Schedule and a
+// "time" function formatting times in the digest time zone
+type DigestTemplates struct {
+	Subject *texttemplate.Template
+	Text    *texttemplate.Template
+	HTML    *htmltemplate.Template
+}
+
+// LoadDigestTemplates parses the digest templates of dir, keeping the default of every
+// template whose file is missing, or every default when dir is empty
+func LoadDigestTemplates(dir string) (*DigestTemplates, error) {
+	sources := map[string]string{
+		DigestSubjectFile: defaultDigestSubject,
+		DigestTextFile:    defaultDigestText,
+		DigestHTMLFile:    defaultDigestHTML,
+	}
+	if dir != "" {
+		for name := range sources {
+			b, err := ioutil.ReadFile(filepath.Join(dir, name))
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			sources[name] = string(b)
+		}
+	}
+
+	// time is replaced on execution with the digest time zone
+	textFuncs := texttemplate.FuncMap{"time": formatDigestTime(time.UTC)}
+	htmlFuncs := htmltemplate.FuncMap{"time": formatDigestTime(time.UTC)}
+
+	var dt DigestTemplates
+	var err error
+	if dt.Subject, err = texttemplate.New(DigestSubjectFile).Funcs(textFuncs).Parse(sources[DigestSubjectFile]); err != nil {
+		return nil, err
+	}
+	if dt.Text, err = texttemplate.New(DigestTextFile).Funcs(textFuncs).Parse(sources[DigestTextFile]); err != nil {
+		return nil, err
+	}
+	if dt.HTML, err = htmltemplate.New(DigestHTMLFile).Funcs(htmlFuncs).Parse(sources[DigestHTMLFile]); err != nil {
+		return nil, err
+	}
+
+	return &dt, nil
+}
+
+// DigestOption configures digest usecase
+type DigestOption struct {
+	// Schedule is rsvp.DigestDaily or rsvp.DigestWeekly
+	Schedule string
+	// Weekday is the day of a weekly digest
+	Weekday time.Weekday
+	// Hour and Minute are the time of the digest in Location
+	Hour     int
+	Minute   int
+	Location *time.Location
+	// Templates render the digest, which Sender delivers
+	Templates *DigestTemplates
+	Sender    rsvp.DigestSender
+	// ReportSender formats and delivers the digest itself, used instead of Templates and Sender when set
+	ReportSender rsvp.DigestReportSender
+}
+
+type digestUsecase struct {
+	*AccessProvider
+	opt DigestOption
+}
+
+func NewDigestUsecase(pvd *AccessProvider, opt DigestOption) rsvp.DigestUsecase {
+	return &digestUsecase{pvd, opt}
+}
+
+// GetDigestReport returns the new responses, new and edited messages, attendance changes
+// and removed RSVPs from from until to, along with the current totals. Changes are taken
+// from the outbox when they are written, whether or not they have been dispatched.
+func (du *digestUsecase) GetDigestReport(ctx context.Context, from time.Time, to time.Time) (*rsvp.DigestReport, error) {
+	newRsvps, err := du.RsvpRepo.GetRsvpsCreatedBetween(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := du.OutboxRepo.GetOutboxEvents(ctx, []string{rsvp.EventRsvpUpdated, rsvp.EventRsvpDeleted}, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	totals, err := du.RsvpRepo.CountRsvpsByAttendance(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &rsvp.DigestReport{From: from, To: to, NewRsvps: newRsvps, Totals: *totals}
+	for _, rp := range newRsvps {
+		report.Messages = addMessage(report.Messages, rp)
+	}
+	for _, e := range events {
+		if e.Type == rsvp.EventRsvpDeleted {
+			report.Cancelled = append(report.Cancelled, e.Rsvp)
+			continue
+		}
+		if e.Previous == nil {
+			continue
+		}
+		if e.Previous.Attend != e.Rsvp.Attend {
+			report.Changes = append(report.Changes, rsvp.AttendanceChange{Rsvp: e.Rsvp, Previous: e.Previous.Attend})
+		}
+		if e.Previous.Message != e.Rsvp.Message {
+			report.Messages = addMessage(report.Messages, e.Rsvp)
+		}
+	}
+
+	return report, nil
+}
+
+// addMessage adds rp to messages unless its message is empty, in place of an earlier message of the same RSVP
+func addMessage(messages []rsvp.Rsvp, rp rsvp.Rsvp) []rsvp.Rsvp {
+	for i := range messages {
+		if messages[i].ID == rp.ID {
+			messages = append(messages[:i], messages[i+1:]...)
+			break
+		}
+	}
+
+	if strings.TrimSpace(rp.Message) != "" {
+		messages = append(messages, rp)
+	}
+	return messages
+}
+
+// RunDigestScheduler sends a digest on every scheduled time until ctx is done. A digest covers
+// the time since the last one sent, so a failed digest is caught up by the next one.
+func (du *digestUsecase) RunDigestScheduler(ctx context.Context) {
+	go func() {
+		for {
+			slot := du.lastSlot(time.Now())
+			du.send(ctx, slot)
+
+			timer := time.NewTimer(time.Until(du.step(slot, 1)))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	}()
+}
+
+// lastSlot returns the last scheduled time at or before now
+func (du *digestUsecase) lastSlot(now time.Time) time.Time {
+	t := now.In(du.opt.Location)
+	slot := time.Date(t.Year(), t.Month(), t.Day(), du.opt.Hour, du.opt.Minute, 0, 0, du.opt.Location)
+	if du.opt.Schedule == rsvp.DigestWeekly {
+		slot = slot.AddDate(0, 0, -((int(t.Weekday()) - int(du.opt.Weekday) + 7) % 7))
+	}
+
+	for slot.After(t) {
+		slot = du.step(slot, -1)
+	}
+	return slot
+}
+
+// step returns the scheduled time n schedules after slot
+func (du *digestUsecase) step(slot time.Time, n int) time.Time {
+	if du.opt.Schedule == rsvp.DigestWeekly {
+		return slot.AddDate(0, 0, 7*n)
+	}
+	return slot.AddDate(0, 0, n)
+}
+
+// send delivers the digest of slot unless it has been, by this or another process
+func (du *digestUsecase) send(ctx context.Context, slot time.Time) {
+	d := rsvp.Digest{
+		ID:        slot.UTC().Format(time.RFC3339),
+		Schedule:  du.opt.Schedule,
+		From:      du.step(slot, -1),
+		To:        slot,
+		Status:    rsvp.DigestPending,
+		CreatedAt: time.Now(),
+	}
+
+	last, err := du.DigestRepo.GetLastSentDigest(ctx)
+	if err != nil && err != response.NotFoundError {
+		log.Printf("digest %s: failed: %v", d.ID, err)
+		return
+	}
+	if last != nil {
+		d.From = last.To
+	}
+
+	claimed, err := du.DigestRepo.ClaimDigest(ctx, d)
+	if err != nil {
+		log.Printf("digest %s: claim failed: %v", d.ID, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	if err = du.deliver(ctx, d); err != nil {
+		log.Printf("digest %s: failed: %v", d.ID, err)
+		d.Status = rsvp.DigestFailed
+		d.Error = err.Error()
+	} else {
+		now := time.Now()
+		d.Status = rsvp.DigestSent
+		d.SentAt = &now
+	}
+
+	if err = du.DigestRepo.UpdateDigest(ctx, d); err != nil {
+		log.Printf("digest %s: update failed: %v", d.ID, err)
+	}
+}
+
+func (du *digestUsecase) deliver(ctx context.Context, d rsvp.Digest) error {
+	report, err := du.GetDigestReport(ctx, d.From.In(du.opt.Location), d.To.In(du.opt.Location))
+	if err != nil {
+		return err
+	}
+
+	if du.opt.ReportSender != nil {
+		return du.opt.ReportSender.SendDigestReport(ctx, du.opt.Schedule, report)
+	}
+
+	subject, text, html, err := du.render(report)
+	if err != nil {
+		return err
+	}
+
+	return du.opt.Sender.SendDigest(ctx, subject, text, html)
+}
+
+// render executes the digest templates with report
+func (du *digestUsecase) render(report *rsvp.DigestReport) (string, string, string, error) {
+	data := struct {
+		*rsvp.DigestReport
+		Schedule string
+	}{report, du.opt.Schedule}
+
+	format := formatDigestTime(du.opt.Location)
+	subject, err := du.opt.Templates.Subject.Clone()
+	if err != nil {
+		return "", "", "", err
+	}
+	text, err := du.opt.Templates.Text.Clone()
+	if err != nil {
+		return "", "", "", err
+	}
+	html, err := du.opt.Templates.HTML.Clone()
+	if err != nil {
+		return "", "", "", err
+	}
+
+	var subjectBuf, textBuf, htmlBuf bytes.Buffer
+	if err = subject.Funcs(texttemplate.FuncMap{"time": format}).Execute(&subjectBuf, data); err != nil {
+		return "", "", "", err
+	}
+	if err = text.Funcs(texttemplate.FuncMap{"time": format}).Execute(&textBuf, data); err != nil {
+		return "", "", "", err
+	}
+	if err = html.Funcs(htmltemplate.FuncMap{"time": format}).Execute(&htmlBuf, data); err != nil {
+		return "", "", "", err
+	}
+
+	// a subject is a single line
+	return strings.Join(strings.Fields(subjectBuf.String()), " "), textBuf.String(), htmlBuf.String(), nil
+}
+
+// formatDigestTime returns a function formatting a time in location
+func formatDigestTime(location *time.Location) func(time.Time) string {
+	return func(t time.Time) string {
+		return t.In(location).Format("Mon, 02 Jan 2006 15:04")
+	}
+}
@@ -0,0 +1,253 @@
+package usecase_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/enumeration"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/faris-arifiansyah/fws-rsvp/usecase"
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDigestRepo keeps digests in memory
+type fakeDigestRepo struct {
+	sync.Mutex
+	digests []rsvp.Digest
+}
+
+func (fr *fakeDigestRepo) ClaimDigest(ctx context.Context, d rsvp.Digest) (bool, error) {
+	fr.Lock()
+	defer fr.Unlock()
+
+	for _, existing := range fr.digests {
+		if existing.ID == d.ID {
+			return false, nil
+		}
+	}
+	fr.digests = append(fr.digests, d)
+	return true, nil
+}
+
+func (fr *fakeDigestRepo) UpdateDigest(ctx context.Context, d rsvp.Digest) error {
+	fr.Lock()
+	defer fr.Unlock()
+
+	for i := range fr.digests {
+		if fr.digests[i].ID == d.ID {
+			fr.digests[i] = d
+		}
+	}
+	return nil
+}
+
+func (fr *fakeDigestRepo) GetLastSentDigest(ctx context.Context) (*rsvp.Digest, error) {
+	fr.Lock()
+	defer fr.Unlock()
+
+	var last *rsvp.Digest
+	for _, d := range fr.digests {
+		if d.Status == rsvp.DigestSent && (last == nil || d.To.After(last.To)) {
+			d := d
+			last = &d
+		}
+	}
+	if last == nil {
+		return nil, response.NotFoundError
+	}
+	return last, nil
+}
+
+// fakeDigestSender records the digests it sends
+type fakeDigestSender struct {
+	subjects chan string
+	texts    chan string
+}
+
+func (fs *fakeDigestSender) SendDigest(ctx context.Context, subject string, text string, html string) error {
+	fs.subjects <- subject
+	fs.texts <- text
+	return nil
+}
+
+func TestDigestReport(t *testing.T) {
+	assert := assert.New(t)
+
+	from := time.Date(2019, 8, 16, 8, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 1)
+	during := from.Add(time.Hour)
+
+	budi := rsvp.Rsvp{ID: bson.NewObjectId(), Name: "Budi", Address: "Jakarta", Attend: enumeration.AttendanceTypeYes, Message: "Selamat!", CreatedAt: during}
+	siti := rsvp.Rsvp{ID: bson.NewObjectId(), Name: "Siti", Address: "Bandung", Attend: enumeration.AttendanceTypeNo, CreatedAt: during}
+	rudi := rsvp.Rsvp{ID: bson.NewObjectId(), Name: "Rudi", Address: "Bogor", Attend: enumeration.AttendanceTypeMaybe, CreatedAt: from.AddDate(0, 0, -3)}
+	dewi := rsvp.Rsvp{ID: bson.NewObjectId(), Name: "Dewi", Address: "Depok", Attend: enumeration.AttendanceTypeYes, CreatedAt: from.AddDate(0, 0, -3)}
+	eka := rsvp.Rsvp{ID: bson.NewObjectId(), Name: "Eka", Address: "Bekasi", Attend: enumeration.AttendanceTypeYes, CreatedAt: from.AddDate(0, 0, -3)}
+	previous := rudi
+	previous.Attend = enumeration.AttendanceTypeYes
+	notAttending := siti
+	notAttending.Attend = enumeration.AttendanceTypeYes
+	editedBudi := budi
+	editedBudi.Message = "Selamat menempuh hidup baru!"
+	editedEka := eka
+	editedEka.Message = "Bahagia selalu"
+
+	outbox := &fakeOutboxRepo{entries: []rsvp.OutboxEntry{
+		{Event: rsvp.Event{Type: rsvp.EventRsvpUpdated, Rsvp: rudi, Previous: &previous}, CreatedAt: during},
+		// restored as it was
+		{Event: rsvp.Event{Type: rsvp.EventRsvpUpdated, Rsvp: siti, Previous: &siti}, CreatedAt: during},
+		{Event: rsvp.Event{Type: rsvp.EventRsvpDeleted, Rsvp: dewi}, CreatedAt: during},
+		{Event: rsvp.Event{Type: rsvp.EventRsvpDeleted, Rsvp: dewi}, CreatedAt: to},
+		{Event: rsvp.Event{Type: rsvp.EventRsvpUpdated, Rsvp: editedEka, Previous: &eka}, CreatedAt: during},
+		{Event: rsvp.Event{Type: rsvp.EventRsvpUpdated, Rsvp: editedBudi, Previous: &budi}, CreatedAt: during},
+		// not dispatched yet, which does not keep it from the digest
+		{Event: rsvp.Event{Type: rsvp.EventRsvpUpdated, Rsvp: notAttending, Previous: &siti}, Status: rsvp.OutboxPending, CreatedAt: during},
+		// never saved
+		{Event: rsvp.Event{Type: rsvp.EventRsvpDeleted, Rsvp: eka}, Status: rsvp.OutboxDiscarded, CreatedAt: during},
+	}}
+	for i := range outbox.entries {
+		if outbox.entries[i].Status == "" {
+			outbox.entries[i].Status = rsvp.OutboxDone
+		}
+	}
+
+	uc := usecase.NewDigestUsecase(&usecase.AccessProvider{
+		RsvpRepo:   &fakeRsvpRepo{data: []rsvp.Rsvp{budi, siti, rudi}},
+		OutboxRepo: outbox,
+	}, usecase.DigestOption{})
+
+	report, err := uc.GetDigestReport(context.Background(), from, to)
+	assert.NoError(err)
+	assert.Equal([]rsvp.Rsvp{budi, siti}, report.NewRsvps)
+	// an edited message replaces the one the RSVP was created with
+	assert.Equal([]rsvp.Rsvp{editedEka, editedBudi}, report.Messages)
+	assert.Equal([]rsvp.AttendanceChange{
+		{Rsvp: rudi, Previous: enumeration.AttendanceTypeYes},
+		{Rsvp: notAttending, Previous: enumeration.AttendanceTypeNo},
+	}, report.Changes)
+	assert.Equal([]rsvp.Rsvp{dewi}, report.Cancelled)
+	assert.Equal(int64(3), report.Totals.Total)
+	assert.False(report.IsEmpty())
+}
+
+func TestLoadDigestTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "digest")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, usecase.DigestSubjectFile), []byte("Kabar RSVP {{time .To}}"), 0644))
+
+	templates, err := usecase.LoadDigestTemplates(dir)
+	assert.NoError(t, err)
+	// the other templates keep their default
+	assert.NotNil(t, templates.Text.Lookup(usecase.DigestTextFile))
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, usecase.DigestHTMLFile), []byte("{{if}}"), 0644))
+	_, err = usecase.LoadDigestTemplates(dir)
+	assert.Error(t, err)
+}
+
+func TestDigestScheduler(t *testing.T) {
+	assert := assert.New(t)
+
+	location := time.FixedZone("WIB", 7*60*60)
+	templates, err := usecase.LoadDigestTemplates("")
+	assert.NoError(err)
+
+	now := time.Now()
+	digests := &fakeDigestRepo{}
+	sender := &fakeDigestSender{subjects: make(chan string, 2), texts: make(chan string, 2)}
+	pvd := &usecase.AccessProvider{
+		RsvpRepo: &fakeRsvpRepo{data: []rsvp.Rsvp{
+			{Name: "Budi", Address: "Jakarta", Attend: enumeration.AttendanceTypeYes, CreatedAt: now.Add(-2 * time.Minute)},
+		}},
+		OutboxRepo: &fakeOutboxRepo{},
+		DigestRepo: digests,
+	}
+	opt := usecase.DigestOption{
+		Schedule:  rsvp.DigestWeekly,
+		Weekday:   now.In(location).Weekday(),
+		Hour:      now.In(location).Hour(),
+		Minute:    now.In(location).Minute(),
+		Location:  location,
+		Templates: templates,
+		Sender:    sender,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the digest of this minute is due, another process must not send it again
+	usecase.NewDigestUsecase(pvd, opt).RunDigestScheduler(ctx)
+	usecase.NewDigestUsecase(pvd, opt).RunDigestScheduler(ctx)
+
+	select {
+	case subject := <-sender.subjects:
+		assert.Equal("RSVP weekly digest: 1 new, 0 attending", subject)
+		assert.Contains(<-sender.texts, "- Budi, Jakarta: Yes")
+	case <-time.After(5 * time.Second):
+		t.Fatal("digest was not sent")
+	}
+
+	select {
+	case subject := <-sender.subjects:
+		t.Fatalf("digest %q was sent twice", subject)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	digests.Lock()
+	defer digests.Unlock()
+	assert.Len(digests.digests, 1)
+	assert.Equal(rsvp.DigestSent, digests.digests[0].Status)
+	assert.Equal(7*24*time.Hour, digests.digests[0].To.Sub(digests.digests[0].From))
+}
+
+// fakeDigestReportSender records the digest reports it sends
+type fakeDigestReportSender struct {
+	reports chan *rsvp.DigestReport
+}
+
+func (fs *fakeDigestReportSender) SendDigestReport(ctx context.Context, schedule string, report *rsvp.DigestReport) error {
+	fs.reports <- report
+	return nil
+}
+
+func TestDigestReportSender(t *testing.T) {
+	assert := assert.New(t)
+
+	location := time.FixedZone("WIB", 7*60*60)
+	now := time.Now()
+	sender := &fakeDigestReportSender{reports: make(chan *rsvp.DigestReport, 1)}
+	pvd := &usecase.AccessProvider{
+		RsvpRepo:   &fakeRsvpRepo{},
+		OutboxRepo: &fakeOutboxRepo{},
+		DigestRepo: &fakeDigestRepo{},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the templates are not needed by a report sender
+	usecase.NewDigestUsecase(pvd, usecase.DigestOption{
+		Schedule:     rsvp.DigestDaily,
+		Hour:         now.In(location).Hour(),
+		Minute:       now.In(location).Minute(),
+		Location:     location,
+		ReportSender: sender,
+	}).RunDigestScheduler(ctx)
+
+	select {
+	case report := <-sender.reports:
+		assert.Equal(location, report.To.Location())
+		assert.Equal(location, report.From.Location())
+		assert.True(report.IsEmpty())
+	case <-time.After(5 * time.Second):
+		t.Fatal("digest was not sent")
+	}
+}
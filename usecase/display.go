@@ -0,0 +1,99 @@
+package usecase
+
+import (
+	"context"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+)
+
+type displayUsecase struct {
+	*AccessProvider
+	stream rsvp.StreamUsecase
+}
+
+// streamStart is the ID to resume after when no event is buffered, it matches no event
+const streamStart = "start"
+
+// NewDisplayUsecase is a function to create DisplayUsecase following approvals on stream
+func NewDisplayUsecase(pvd *AccessProvider, stream rsvp.StreamUsecase) rsvp.DisplayUsecase {
+	return &displayUsecase{pvd, stream}
+}
+
+func (du *displayUsecase) GetWishes(ctx context.Context, f rsvp.DisplayFilter) ([]rsvp.Wish, error) {
+	rps, err := du.RsvpRepo.GetApprovedRsvps(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	wishes := make([]rsvp.Wish, 0, len(rps))
+	for _, rp := range rps {
+		if f.Allows(rp) {
+			wishes = append(wishes, rsvp.NewWish(rp))
+		}
+	}
+
+	return wishes, nil
+}
+
+// GetWishesToStream reads the latest buffered event before the wishes, so resuming after it
+// replays every approval made since, at worst one the wishes already have. When nothing is
+// buffered it returns streamStart, which no event has, so the whole buffer is replayed.
+func (du *displayUsecase) GetWishesToStream(ctx context.Context, f rsvp.DisplayFilter) ([]rsvp.Wish, string, error) {
+	lastID, err := du.EventStreamRepo.LastEventID(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if lastID == "" {
+		lastID = streamStart
+	}
+
+	wishes, err := du.GetWishes(ctx, f)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return wishes, lastID, nil
+}
+
+func (du *displayUsecase) StreamWishes(ctx context.Context, f rsvp.DisplayFilter, lastID string) (<-chan rsvp.WishEvent, error) {
+	events, err := du.stream.StreamEvents(ctx, lastID)
+	if err != nil {
+		return nil, err
+	}
+
+	wishes := make(chan rsvp.WishEvent)
+	go func() {
+		defer close(wishes)
+
+		for e := range events {
+			we, ok := wishEvent(e, f)
+			if !ok {
+				continue
+			}
+
+			select {
+			case wishes <- we:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return wishes, nil
+}
+
+// wishEvent tells what e changes on a display showing the wishes that pass f,
+// reporting false when it changes nothing
+func wishEvent(e rsvp.Event, f rsvp.DisplayFilter) (rsvp.WishEvent, bool) {
+	shown := f.Allows(e.Rsvp)
+	wasShown := e.Previous != nil && f.Allows(*e.Previous)
+
+	switch {
+	case shown && !(wasShown && e.Previous.Message == e.Rsvp.Message):
+		return rsvp.WishEvent{ID: e.ID, Type: rsvp.WishShown, Wish: rsvp.NewWish(e.Rsvp)}, true
+	case !shown && wasShown:
+		return rsvp.WishEvent{ID: e.ID, Type: rsvp.WishWithdrawn, Wish: rsvp.NewWish(*e.Previous)}, true
+	}
+
+	return rsvp.WishEvent{}, false
+}
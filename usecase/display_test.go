@@ -0,0 +1,195 @@
+package usecase_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/faris-arifiansyah/fws-rsvp/usecase"
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+)
+
+func (fr *fakeRsvpRepo) SetRsvpApproval(ctx context.Context, id string, approvedAt *time.Time) (rsvp.Rsvp, error) {
+	for i := range fr.data {
+		if fr.data[i].ID.Hex() == id {
+			fr.data[i].ApprovedAt = approvedAt
+			return fr.data[i], nil
+		}
+	}
+	return rsvp.Rsvp{}, response.NotFoundError
+}
+
+func (fr *fakeRsvpRepo) GetApprovedRsvps(ctx context.Context) ([]rsvp.Rsvp, error) {
+	var rps []rsvp.Rsvp
+	for _, rp := range fr.data {
+		if rp.ApprovedAt != nil {
+			rps = append(rps, rp)
+		}
+	}
+	return rps, nil
+}
+
+func TestSetMessageApproval(t *testing.T) {
+	assert := assert.New(t)
+
+	approved := time.Now().Add(-time.Hour)
+	budi := rsvp.Rsvp{ID: bson.NewObjectId(), Name: "Budi", Message: "Selamat menempuh hidup baru!"}
+	siti := rsvp.Rsvp{ID: bson.NewObjectId(), Name: "Siti", Message: "  "}
+	rudi := rsvp.Rsvp{ID: bson.NewObjectId(), Name: "Rudi", Message: "Bahagia selalu", ApprovedAt: &approved}
+	uc := usecase.NewRsvpUsecase(&usecase.AccessProvider{RsvpRepo: &fakeRsvpRepo{data: []rsvp.Rsvp{budi, siti, rudi}}})
+	ctx := context.Background()
+
+	rp, err := uc.SetMessageApproval(ctx, budi.ID.Hex(), true)
+	assert.NoError(err)
+	assert.NotNil(rp.ApprovedAt)
+
+	_, err = uc.SetMessageApproval(ctx, siti.ID.Hex(), true)
+	assert.Equal("message", err.(response.CustomError).Field)
+
+	// approving again keeps the approval time
+	rp, err = uc.SetMessageApproval(ctx, rudi.ID.Hex(), true)
+	assert.NoError(err)
+	assert.Equal(approved, *rp.ApprovedAt)
+
+	rp, err = uc.SetMessageApproval(ctx, rudi.ID.Hex(), false)
+	assert.NoError(err)
+	assert.Nil(rp.ApprovedAt)
+
+	_, err = uc.SetMessageApproval(ctx, bson.NewObjectId().Hex(), true)
+	assert.Equal(response.NotFoundError, err)
+
+	// guests cannot approve their own message
+	rp, err = uc.CreateRsvp(ctx, rsvp.Rsvp{Name: "Dewi", Message: "Semoga langgeng", ApprovedAt: &approved})
+	assert.NoError(err)
+	assert.Nil(rp.ApprovedAt)
+}
+
+func TestGetWishes(t *testing.T) {
+	approved := time.Now()
+	rps := []rsvp.Rsvp{
+		{ID: bson.NewObjectId(), Name: "Budi", Address: "Jakarta", Message: " Selamat! ", ApprovedAt: &approved},
+		{ID: bson.NewObjectId(), Name: "Siti", Message: "Semoga menjadi keluarga yang sakinah", ApprovedAt: &approved},
+		{ID: bson.NewObjectId(), Name: "Rudi", Message: "Bahagia selalu"},
+		// counted in characters rather than bytes
+		{ID: bson.NewObjectId(), Name: "Dewi", Message: strings.Repeat("❤", 5), ApprovedAt: &approved},
+	}
+
+	tests := []struct {
+		name     string
+		filter   rsvp.DisplayFilter
+		expected []string
+	}{
+		{"no bounds", rsvp.DisplayFilter{}, []string{"Budi", "Siti", "Dewi"}},
+		{"short", rsvp.DisplayFilter{MaxLength: 8}, []string{"Budi", "Dewi"}},
+		{"long", rsvp.DisplayFilter{MinLength: 9}, []string{"Siti"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			uc := usecase.NewDisplayUsecase(&usecase.AccessProvider{RsvpRepo: &fakeRsvpRepo{data: rps}}, nil)
+
+			wishes, err := uc.GetWishes(context.Background(), test.filter)
+			assert.NoError(t, err)
+
+			var names []string
+			for _, w := range wishes {
+				names = append(names, w.Name)
+			}
+			assert.Equal(t, test.expected, names)
+		})
+	}
+}
+
+func TestStreamWishes(t *testing.T) {
+	assert := assert.New(t)
+
+	stream := usecase.NewStreamUsecase(&usecase.AccessProvider{EventStreamRepo: &fakeEventStream{}})
+	uc := usecase.NewDisplayUsecase(&usecase.AccessProvider{}, stream)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	wishes, err := uc.StreamWishes(ctx, rsvp.DisplayFilter{MaxLength: 20}, "")
+	assert.NoError(err)
+
+	approved := time.Now()
+	budi := rsvp.Rsvp{ID: bson.NewObjectId(), Name: "Budi", Address: "Jakarta", Message: "Selamat!"}
+	approvedBudi := budi
+	approvedBudi.ApprovedAt = &approved
+	long := rsvp.Rsvp{ID: bson.NewObjectId(), Name: "Siti", Message: "Semoga menjadi keluarga yang sakinah"}
+	approvedLong := long
+	approvedLong.ApprovedAt = &approved
+
+	events := []rsvp.Event{
+		{ID: "1", Type: rsvp.EventRsvpCreated, Rsvp: budi},
+		{ID: "2", Type: rsvp.EventRsvpUpdated, Rsvp: approvedBudi, Previous: &budi},
+		{ID: "3", Type: rsvp.EventRsvpUpdated, Rsvp: approvedLong, Previous: &long},
+		{ID: "4", Type: rsvp.EventRsvpUpdated, Rsvp: budi, Previous: &approvedBudi},
+	}
+	for _, e := range events {
+		assert.NoError(stream.Publish(ctx, e))
+	}
+
+	var received []rsvp.WishEvent
+	for len(received) < 2 {
+		select {
+		case we := <-wishes:
+			received = append(received, we)
+		case <-time.After(time.Second):
+			t.Fatalf("received %v, want 2 wish events", received)
+		}
+	}
+
+	assert.Equal(rsvp.WishEvent{ID: "2", Type: rsvp.WishShown, Wish: rsvp.NewWish(approvedBudi)}, received[0])
+	assert.Equal("4", received[1].ID)
+	assert.Equal(rsvp.WishWithdrawn, received[1].Type)
+	assert.Equal(budi.ID.Hex(), received[1].Wish.ID)
+}
+
+func TestGetWishesToStream(t *testing.T) {
+	approved := time.Now()
+	budi := rsvp.Rsvp{ID: bson.NewObjectId(), Name: "Budi", Message: "Selamat!", ApprovedAt: &approved}
+	siti := rsvp.Rsvp{ID: bson.NewObjectId(), Name: "Siti", Message: "Bahagia selalu"}
+	approvedSiti := siti
+	approvedSiti.ApprovedAt = &approved
+
+	tests := []struct {
+		name     string
+		buffered []rsvp.Event
+	}{
+		{"buffered events", []rsvp.Event{{ID: "1", Type: rsvp.EventRsvpCreated, Rsvp: budi}}},
+		{"empty buffer", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			events := &fakeEventStream{buffer: test.buffered}
+			pvd := &usecase.AccessProvider{RsvpRepo: &fakeRsvpRepo{data: []rsvp.Rsvp{budi, siti}}, EventStreamRepo: events}
+			stream := usecase.NewStreamUsecase(pvd)
+			uc := usecase.NewDisplayUsecase(pvd, stream)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			wishes, lastID, err := uc.GetWishesToStream(ctx, rsvp.DisplayFilter{})
+			assert.NoError(err)
+			assert.Equal([]rsvp.Wish{rsvp.NewWish(budi)}, wishes)
+
+			// approved after the page was rendered, before its stream connected
+			assert.NoError(stream.Publish(ctx, rsvp.Event{ID: "2", Type: rsvp.EventRsvpUpdated, Rsvp: approvedSiti, Previous: &siti}))
+
+			resumed, err := uc.StreamWishes(ctx, rsvp.DisplayFilter{}, lastID)
+			assert.NoError(err)
+			select {
+			case we := <-resumed:
+				assert.Equal(rsvp.WishEvent{ID: "2", Type: rsvp.WishShown, Wish: rsvp.NewWish(approvedSiti)}, we)
+			case <-time.After(time.Second):
+				t.Fatal("the approval made in between was not resumed")
+			}
+		})
+	}
+}
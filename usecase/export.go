@@ -0,0 +1,243 @@
+package usecase
+
+import (
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+)
+
+const (
+	langEN = "en"
+	langID = "id"
+)
+
+// utf8BOM lets spreadsheet applications detect UTF-8 in a CSV file
+const utf8BOM = "\ufeff"
+
+// exportColumn is a column an export may contain, its value is a string, an int or a time.Time
+type exportColumn struct {
+	labels map[string]string
+	width  float64
+	value  func(number int, rp *rsvp.Rsvp, l *exportLayout) interface{}
+}
+
+var exportColumns = map[string]exportColumn{
+	"id": {
+		labels: map[string]string{langEN: "ID", langID: "ID"},
+		width:  26,
+		value:  func(_ int, rp *rsvp.Rsvp, _ *exportLayout) interface{} { return rp.ID.Hex() },
+	},
+	"number": {
+		labels: map[string]string{langEN: "Number", langID: "Nomor"},
+		width:  10,
+		value:  func(number int, _ *rsvp.Rsvp, _ *exportLayout) interface{} { return number },
+	},
+	"name": {
+		labels: map[string]string{langEN: "Name", langID: "Nama"},
+		width:  30,
+		value:  func(_ int, rp *rsvp.Rsvp, _ *exportLayout) interface{} { return rp.Name },
+	},
+	"address": {
+		labels: map[string]string{langEN: "Address", langID: "Alamat"},
+		width:  40,
+		value:  func(_ int, rp *rsvp.Rsvp, _ *exportLayout) interface{} { return rp.Address },
+	},
+	"attend": {
+		labels: map[string]string{langEN: "Attend", langID: "Kehadiran"},
+		width:  12,
+		value:  func(_ int, rp *rsvp.Rsvp, l *exportLayout) interface{} { return rp.Attend.Label(l.language) },
+	},
+	"message": {
+		labels: map[string]string{langEN: "Message", langID: "Ucapan"},
+		width:  60,
+		value:  func(_ int, rp *rsvp.Rsvp, _ *exportLayout) interface{} { return rp.Message },
+	},
+	"email": {
+		labels: map[string]string{langEN: "Email", langID: "Email"},
+		width:  30,
+		value:  func(_ int, rp *rsvp.Rsvp, _ *exportLayout) interface{} { return rp.Email },
+	},
+	"created_at": {
+		labels: map[string]string{langEN: "Created Date", langID: "Tanggal Dibuat"},
+		width:  20,
+		value:  func(_ int, rp *rsvp.Rsvp, l *exportLayout) interface{} { return l.time(rp.CreatedAt) },
+	},
+}
+
+var defaultExportColumns = []string{"number", "name", "address", "attend", "message", "created_at"}
+
+// messageExportColumns are the columns of the sheet of messages in a workbook
+var messageExportColumns = []string{"name", "message", "created_at"}
+
+// exportLabels are the labels of a workbook besides its column headers
+var exportLabels = map[string]map[string]string{
+	langEN: {
+		"rsvps":      "RSVPs",
+		"messages":   "Messages",
+		"summary":    "Summary",
+		"attendance": "Attendance",
+		"count":      "Count",
+		"total":      "Total",
+	},
+	langID: {
+		"rsvps":      "RSVP",
+		"messages":   "Ucapan",
+		"summary":    "Ringkasan",
+		"attendance": "Kehadiran",
+		"count":      "Jumlah",
+		"total":      "Total",
+	},
+}
+
+// dateFormats are the named date formats an export accepts besides a Go time layout
+var dateFormats = map[string]string{
+	"":         "2006-01-02 15-04-05",
+	"datetime": "2006-01-02 15:04:05",
+	"date":     "2006-01-02",
+	"rfc3339":  time.RFC3339,
+	"id":       "02/01/2006 15:04",
+}
+
+var delimiters = map[string]rune{
+	"":          ',',
+	"comma":     ',',
+	"semicolon": ';',
+	"tab":       '\t',
+}
+
+// exportLayout is a validated rsvp.ExportOption
+type exportLayout struct {
+	columns    []exportColumn
+	language   string
+	location   *time.Location
+	dateFormat string
+	delimiter  rune
+	bom        bool
+}
+
+// newExportLayout validates opt, an invalid option is reported as
+// response.BadRequestError with the name of its query parameter
+func newExportLayout(opt rsvp.ExportOption) (*exportLayout, error) {
+	l := &exportLayout{
+		language: opt.Language,
+		bom:      opt.BOM,
+	}
+
+	if l.language == "" {
+		l.language = langEN
+	}
+	if l.language != langEN && l.language != langID {
+		return nil, badRequest("lang")
+	}
+
+	keys := opt.Columns
+	if len(keys) == 0 {
+		keys = defaultExportColumns
+	}
+	for _, key := range keys {
+		col, ok := exportColumns[key]
+		if !ok {
+			return nil, badRequest("columns")
+		}
+		l.columns = append(l.columns, col)
+	}
+
+	if opt.TimeZone != "" {
+		location, err := time.LoadLocation(opt.TimeZone)
+		if err != nil {
+			return nil, badRequest("tz")
+		}
+		l.location = location
+	}
+
+	l.dateFormat = opt.DateFormat
+	if layout, ok := dateFormats[opt.DateFormat]; ok {
+		l.dateFormat = layout
+	}
+
+	delimiter, ok := delimiters[opt.Delimiter]
+	if !ok {
+		r, size := utf8.DecodeRuneInString(opt.Delimiter)
+		if size != len(opt.Delimiter) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+			return nil, badRequest("delimiter")
+		}
+		delimiter = r
+	}
+	l.delimiter = delimiter
+
+	return l, nil
+}
+
+func (l *exportLayout) label(key string) string {
+	return exportLabels[l.language][key]
+}
+
+// with returns a copy of l laid out with columns
+func (l *exportLayout) with(columns []string) *exportLayout {
+	c := *l
+	c.columns = nil
+	for _, key := range columns {
+		c.columns = append(c.columns, exportColumns[key])
+	}
+	return &c
+}
+
+func (l *exportLayout) header() []string {
+	record := make([]string, len(l.columns))
+	for i, col := range l.columns {
+		record[i] = col.labels[l.language]
+	}
+	return record
+}
+
+func (l *exportLayout) widths() []float64 {
+	widths := make([]float64, len(l.columns))
+	for i, col := range l.columns {
+		widths[i] = col.width
+	}
+	return widths
+}
+
+// values returns the typed values of the columns of rp
+func (l *exportLayout) values(number int, rp *rsvp.Rsvp) []interface{} {
+	values := make([]interface{}, len(l.columns))
+	for i, col := range l.columns {
+		values[i] = col.value(number, rp, l)
+	}
+	return values
+}
+
+// record returns the values of the columns of rp formatted as text
+func (l *exportLayout) record(number int, rp *rsvp.Rsvp) []string {
+	record := make([]string, len(l.columns))
+	for i, v := range l.values(number, rp) {
+		switch v := v.(type) {
+		case int:
+			record[i] = strconv.Itoa(v)
+		case time.Time:
+			record[i] = v.Format(l.dateFormat)
+		case string:
+			record[i] = v
+		}
+	}
+	return record
+}
+
+// time returns t in the time zone of the export.
+// Without a time zone dates keep the zone they are stored in.
+func (l *exportLayout) time(t time.Time) time.Time {
+	if l.location != nil {
+		return t.In(l.location)
+	}
+	return t
+}
+
+// badRequest returns response.BadRequestError for field
+func badRequest(field string) error {
+	err := response.BadRequestError
+	err.Field = field
+	return err
+}
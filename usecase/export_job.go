@@ -0,0 +1,378 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/constants"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+)
+
+// exportProgressRows is the number of rows rendered between progress updates
+const exportProgressRows = 500
+
+// ExportJobOption configures export job usecase
+type ExportJobOption struct {
+	// TTL is how long a finished export can be downloaded
+	TTL time.Duration
+	// PollInterval is how often idle workers look for pending jobs and remove expired files
+	PollInterval time.Duration
+	// Lease is how long a running job is kept from other workers after it starts and after
+	// every progress update, longer than rendering exportProgressRows rows takes
+	Lease time.Duration
+}
+
+type exportJobUsecase struct {
+	*AccessProvider
+	opt  ExportJobOption
+	wake chan struct{}
+}
+
+func NewExportJobUsecase(pvd *AccessProvider, opt ExportJobOption) rsvp.ExportJobUsecase {
+	return &exportJobUsecase{pvd, opt, make(chan struct{}, 1)}
+}
+
+func (eu *exportJobUsecase) CreateExportJob(ctx context.Context, req rsvp.ExportJobRequest, createdBy string) (rsvp.ExportJob, error) {
+	switch req.Format {
+	case rsvp.ExportFormatCsv, rsvp.ExportFormatXlsx, rsvp.ExportFormatNdjson:
+	default:
+		return rsvp.ExportJob{}, badRequest("format")
+	}
+
+	job := rsvp.ExportJob{
+		Format: req.Format,
+		Parameter: rsvp.Parameter{
+			Sort:   req.Sort,
+			Limit:  constants.NoLimit,
+			Offset: req.Offset,
+		},
+		Option: rsvp.ExportOption{
+			Columns:    req.Columns,
+			Language:   req.Language,
+			TimeZone:   req.TimeZone,
+			DateFormat: req.DateFormat,
+			Delimiter:  req.Delimiter,
+			BOM:        req.BOM,
+		},
+		Status:    rsvp.ExportJobPending,
+		CreatedBy: createdBy,
+	}
+	if req.Limit != nil {
+		job.Parameter.Limit = *req.Limit
+	}
+
+	if _, err := newExportLayout(job.Option); err != nil {
+		return rsvp.ExportJob{}, err
+	}
+
+	job, err := eu.ExportJobRepo.CreateExportJob(ctx, job)
+	if err != nil {
+		return rsvp.ExportJob{}, err
+	}
+
+	// a worker waiting for jobs starts right away
+	select {
+	case eu.wake <- struct{}{}:
+	default:
+	}
+
+	return job, nil
+}
+
+func (eu *exportJobUsecase) GetExportJob(ctx context.Context, id string) (*rsvp.ExportJob, error) {
+	job, err := eu.ExportJobRepo.GetExportJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case job.Status == rsvp.ExportJobDone || job.Status == rsvp.ExportJobExpired:
+		job.Progress = 100
+	case job.Total > 0:
+		job.Progress = int(job.Rows * 100 / job.Total)
+		if job.Progress > 99 {
+			job.Progress = 99
+		}
+	}
+
+	return job, nil
+}
+
+// OpenExportFile returns the file rendered by the job, response.ExportNotReadyError
+// until it is done and response.ExportExpiredError once it has expired
+func (eu *exportJobUsecase) OpenExportFile(ctx context.Context, id string) (*rsvp.ExportJob, rsvp.StoredFile, error) {
+	job, err := eu.ExportJobRepo.GetExportJob(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if job.Status == rsvp.ExportJobExpired || (job.ExpiresAt != nil && !job.ExpiresAt.After(time.Now())) {
+		return nil, nil, response.ExportExpiredError
+	}
+	if job.Status != rsvp.ExportJobDone {
+		return nil, nil, response.ExportNotReadyError
+	}
+
+	f, err := eu.FileStore.Open(ctx, storedExportName(job))
+	if err == response.NotFoundError {
+		return nil, nil, response.ExportExpiredError
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return job, f, nil
+}
+
+// RunExportWorkers starts workers goroutines rendering pending jobs, and one removing
+// expired files, all stopping when ctx is done. Jobs pending on restart are picked up again,
+// and jobs left running by a stopped process are rendered again once their lease is over.
+func (eu *exportJobUsecase) RunExportWorkers(ctx context.Context, workers int) {
+	for i := 0; i < workers; i++ {
+		go eu.work(ctx)
+	}
+
+	go func() {
+		ticker := time.NewTicker(eu.opt.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			eu.expire(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (eu *exportJobUsecase) work(ctx context.Context) {
+	ticker := time.NewTicker(eu.opt.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil && eu.runNext(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-eu.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// runNext renders the oldest pending job, reporting whether there was one
+func (eu *exportJobUsecase) runNext(ctx context.Context) (ran bool) {
+	now := time.Now()
+	job, err := eu.ExportJobRepo.ClaimExportJob(ctx, now, now.Add(eu.opt.Lease))
+	if err == response.NotFoundError {
+		return false
+	}
+	if err != nil {
+		log.Printf("export job: claim failed: %v", err)
+		return false
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			eu.finish(ctx, job, 0, fmt.Errorf("panic: %v", r))
+			ran = true
+		}
+	}()
+
+	size, err := eu.render(ctx, job)
+	// a job interrupted by shutdown is rendered again once its lease is over, and one claimed by
+	// another worker meanwhile is left to it, both into a file of their own
+	if ctx.Err() != nil || err == errExportClaimLost {
+		if err == errExportClaimLost {
+			log.Printf("export job %s: claimed by another worker", job.ID.Hex())
+		}
+		if rerr := eu.FileStore.Remove(context.Background(), storedExportName(job)); rerr != nil {
+			log.Printf("export job %s: remove failed: %v", job.ID.Hex(), rerr)
+		}
+		return true
+	}
+	eu.finish(ctx, job, size, err)
+
+	return true
+}
+
+// errExportClaimLost stops a worker rendering a job another worker claimed after its lease ran out
+var errExportClaimLost = errors.New("export job claimed by another worker")
+
+func (eu *exportJobUsecase) render(ctx context.Context, job *rsvp.ExportJob) (int64, error) {
+	summary, err := eu.RsvpRepo.CountRsvpsByAttendance(ctx)
+	if err != nil {
+		return 0, err
+	}
+	job.Total = exportRows(summary.Total, &job.Parameter)
+	if job.Format == rsvp.ExportFormatXlsx {
+		// the workbook reads the RSVPs twice, for the sheet of RSVPs and of messages
+		job.Total *= 2
+	}
+	err = eu.ExportJobRepo.UpdateClaimedExportJob(ctx, *job)
+	if err == response.NotFoundError {
+		return 0, errExportClaimLost
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	lost := false
+	repo := &countingRsvpRepo{RsvpRepo: eu.RsvpRepo, count: func() bool {
+		job.Rows++
+		if job.Rows%exportProgressRows == 0 {
+			err := eu.ExportJobRepo.UpdateExportJobRows(ctx, job.ID, job.Claim, job.Rows, time.Now().Add(eu.opt.Lease))
+			if err == response.NotFoundError {
+				lost = true
+				return false
+			}
+			if err != nil {
+				log.Printf("export job %s: progress update failed: %v", job.ID.Hex(), err)
+			}
+		}
+		return true
+	}}
+	uc := NewRsvpUsecase(&AccessProvider{RsvpRepo: repo})
+
+	f, err := eu.FileStore.Create(ctx, storedExportName(job))
+	if err != nil {
+		return 0, err
+	}
+	w := &countingWriter{w: f}
+
+	switch job.Format {
+	case rsvp.ExportFormatCsv:
+		err = uc.WriteRsvpsCsv(ctx, &job.Parameter, job.Option, w)
+	case rsvp.ExportFormatXlsx:
+		err = uc.WriteRsvpsXlsx(ctx, &job.Parameter, job.Option, w)
+	case rsvp.ExportFormatNdjson:
+		err = uc.WriteRsvpsNdjson(ctx, &job.Parameter, w)
+	}
+
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if lost {
+		return w.n, errExportClaimLost
+	}
+
+	return w.n, err
+}
+
+// finish records the outcome of a rendered job, removing the file of a failed one
+func (eu *exportJobUsecase) finish(ctx context.Context, job *rsvp.ExportJob, size int64, err error) {
+	now := time.Now()
+	job.FinishedAt = &now
+	job.LeaseUntil = nil
+
+	if err != nil {
+		log.Printf("export job %s: failed: %v", job.ID.Hex(), err)
+		job.Status = rsvp.ExportJobFailed
+		job.Error = err.Error()
+		if rerr := eu.FileStore.Remove(ctx, storedExportName(job)); rerr != nil {
+			log.Printf("export job %s: remove failed: %v", job.ID.Hex(), rerr)
+		}
+	} else {
+		expiresAt := now.Add(eu.opt.TTL)
+		job.Status = rsvp.ExportJobDone
+		job.Rows = job.Total
+		job.Size = size
+		job.FileName = fmt.Sprintf("rsvp-%s.%s", now.Format("2006-01-02_15-04-05"), job.Format)
+		job.ExpiresAt = &expiresAt
+	}
+
+	err = eu.ExportJobRepo.UpdateClaimedExportJob(ctx, *job)
+	if err == response.NotFoundError {
+		log.Printf("export job %s: claimed by another worker", job.ID.Hex())
+		if rerr := eu.FileStore.Remove(ctx, storedExportName(job)); rerr != nil {
+			log.Printf("export job %s: remove failed: %v", job.ID.Hex(), rerr)
+		}
+		return
+	}
+	if err != nil {
+		log.Printf("export job %s: update failed: %v", job.ID.Hex(), err)
+	}
+}
+
+// expire removes the files of the jobs past their expiry
+func (eu *exportJobUsecase) expire(ctx context.Context) {
+	jobs, err := eu.ExportJobRepo.GetExpiredExportJobs(ctx, time.Now())
+	if err != nil {
+		log.Printf("export job: expiry failed: %v", err)
+		return
+	}
+
+	for _, job := range jobs {
+		if err = eu.FileStore.Remove(ctx, storedExportName(job)); err != nil {
+			log.Printf("export job %s: remove failed: %v", job.ID.Hex(), err)
+			continue
+		}
+
+		job.Status = rsvp.ExportJobExpired
+		if err = eu.ExportJobRepo.UpdateExportJob(ctx, *job); err != nil {
+			log.Printf("export job %s: update failed: %v", job.ID.Hex(), err)
+		}
+	}
+}
+
+// exportRows returns the number of rows p selects out of total
+func exportRows(total int64, p *rsvp.Parameter) int64 {
+	total -= int64(p.Offset)
+	if p.Limit != constants.NoLimit && int64(p.Limit) < total {
+		total = int64(p.Limit)
+	}
+	if total < 0 {
+		return 0
+	}
+	return total
+}
+
+// storedExportName names the file of job after its claim, so a worker whose lease ran out
+// does not write into the file of the worker that took the job over
+func storedExportName(job *rsvp.ExportJob) string {
+	if job.Claim == "" {
+		return job.ID.Hex() + "." + job.Format
+	}
+	return job.ID.Hex() + "-" + job.Claim + "." + job.Format
+}
+
+// countingRsvpRepo calls count for every RSVP its iterators return, ending them when it returns false
+type countingRsvpRepo struct {
+	rsvp.RsvpRepo
+	count func() bool
+}
+
+func (cr *countingRsvpRepo) IterateRsvps(ctx context.Context, p *rsvp.Parameter) rsvp.RsvpIterator {
+	return &countingRsvpIterator{cr.RsvpRepo.IterateRsvps(ctx, p), cr.count}
+}
+
+type countingRsvpIterator struct {
+	rsvp.RsvpIterator
+	count func() bool
+}
+
+func (it *countingRsvpIterator) Next(rp *rsvp.Rsvp) bool {
+	return it.RsvpIterator.Next(rp) && it.count()
+}
+
+// countingWriter counts the bytes written to w
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(b []byte) (int, error) {
+	n, err := cw.w.Write(b)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,316 @@
+package usecase_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/constants"
+	"github.com/faris-arifiansyah/fws-rsvp/enumeration"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/faris-arifiansyah/fws-rsvp/usecase"
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeExportJobRepo keeps export jobs in memory
+type fakeExportJobRepo struct {
+	sync.Mutex
+	jobs []rsvp.ExportJob
+}
+
+func (fr *fakeExportJobRepo) CreateExportJob(ctx context.Context, job rsvp.ExportJob) (rsvp.ExportJob, error) {
+	fr.Lock()
+	defer fr.Unlock()
+
+	job.ID = bson.NewObjectId()
+	job.CreatedAt = time.Now()
+	fr.jobs = append(fr.jobs, job)
+	return job, nil
+}
+
+func (fr *fakeExportJobRepo) GetExportJob(ctx context.Context, id string) (*rsvp.ExportJob, error) {
+	fr.Lock()
+	defer fr.Unlock()
+
+	for _, job := range fr.jobs {
+		if job.ID.Hex() == id {
+			return &job, nil
+		}
+	}
+	return nil, response.NotFoundError
+}
+
+func (fr *fakeExportJobRepo) ClaimExportJob(ctx context.Context, now time.Time, lease time.Time) (*rsvp.ExportJob, error) {
+	fr.Lock()
+	defer fr.Unlock()
+
+	for i, job := range fr.jobs {
+		stale := job.Status == rsvp.ExportJobRunning && (job.LeaseUntil == nil || !job.LeaseUntil.After(now))
+		if job.Status == rsvp.ExportJobPending || stale {
+			fr.jobs[i].Status, fr.jobs[i].Rows, fr.jobs[i].LeaseUntil = rsvp.ExportJobRunning, 0, &lease
+			fr.jobs[i].Claim = bson.NewObjectId().Hex()
+			job := fr.jobs[i]
+			return &job, nil
+		}
+	}
+	return nil, response.NotFoundError
+}
+
+func (fr *fakeExportJobRepo) UpdateExportJob(ctx context.Context, job rsvp.ExportJob) error {
+	fr.Lock()
+	defer fr.Unlock()
+
+	for i := range fr.jobs {
+		if fr.jobs[i].ID == job.ID {
+			fr.jobs[i] = job
+			return nil
+		}
+	}
+	return response.NotFoundError
+}
+
+func (fr *fakeExportJobRepo) UpdateClaimedExportJob(ctx context.Context, job rsvp.ExportJob) error {
+	fr.Lock()
+	defer fr.Unlock()
+
+	for i := range fr.jobs {
+		if fr.jobs[i].ID == job.ID && fr.jobs[i].Claim == job.Claim {
+			fr.jobs[i] = job
+			return nil
+		}
+	}
+	return response.NotFoundError
+}
+
+func (fr *fakeExportJobRepo) UpdateExportJobRows(ctx context.Context, id bson.ObjectId, claim string, rows int64, lease time.Time) error {
+	fr.Lock()
+	defer fr.Unlock()
+
+	for i := range fr.jobs {
+		if fr.jobs[i].ID == id && fr.jobs[i].Claim == claim {
+			fr.jobs[i].Rows, fr.jobs[i].LeaseUntil = rows, &lease
+			return nil
+		}
+	}
+	return response.NotFoundError
+}
+
+// takeOver claims the job with id for another worker
+func (fr *fakeExportJobRepo) takeOver(id bson.ObjectId) {
+	fr.Lock()
+	defer fr.Unlock()
+
+	for i := range fr.jobs {
+		if fr.jobs[i].ID == id {
+			fr.jobs[i].Claim = "other"
+		}
+	}
+}
+
+func (fr *fakeExportJobRepo) GetExpiredExportJobs(ctx context.Context, now time.Time) ([]*rsvp.ExportJob, error) {
+	return nil, nil
+}
+
+// fakeFileStore keeps files in memory
+type fakeFileStore struct {
+	sync.Mutex
+	files map[string]*bytes.Buffer
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+type nopReadSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopReadSeekCloser) Close() error { return nil }
+
+func (fs *fakeFileStore) Create(ctx context.Context, name string) (io.WriteCloser, error) {
+	fs.Lock()
+	defer fs.Unlock()
+
+	fs.files[name] = &bytes.Buffer{}
+	return nopWriteCloser{fs.files[name]}, nil
+}
+
+func (fs *fakeFileStore) Open(ctx context.Context, name string) (rsvp.StoredFile, error) {
+	fs.Lock()
+	defer fs.Unlock()
+
+	b, ok := fs.files[name]
+	if !ok {
+		return nil, response.NotFoundError
+	}
+	return nopReadSeekCloser{bytes.NewReader(b.Bytes())}, nil
+}
+
+func (fs *fakeFileStore) Remove(ctx context.Context, name string) error {
+	fs.Lock()
+	defer fs.Unlock()
+
+	delete(fs.files, name)
+	return nil
+}
+
+func (fr *fakeRsvpRepo) CountRsvpsByAttendance(ctx context.Context) (*rsvp.AttendanceSummary, error) {
+	return &rsvp.AttendanceSummary{Total: int64(len(fr.data))}, nil
+}
+
+func TestExportJob(t *testing.T) {
+	assert := assert.New(t)
+
+	createdAt := time.Date(2019, 8, 17, 10, 30, 0, 0, time.UTC)
+	uc := usecase.NewExportJobUsecase(&usecase.AccessProvider{
+		RsvpRepo: &fakeRsvpRepo{data: []rsvp.Rsvp{
+			{Name: "Budi", Address: "Jakarta", Attend: enumeration.AttendanceTypeYes, CreatedAt: createdAt},
+			{Name: "Siti", Address: "Bandung", Attend: enumeration.AttendanceTypeNo, CreatedAt: createdAt},
+		}},
+		ExportJobRepo: &fakeExportJobRepo{},
+		FileStore:     &fakeFileStore{files: map[string]*bytes.Buffer{}},
+	}, usecase.ExportJobOption{TTL: time.Hour, PollInterval: time.Second})
+
+	_, err := uc.CreateExportJob(context.Background(), rsvp.ExportJobRequest{Format: "pdf"}, "admin:budi")
+	assert.Equal("format", err.(response.CustomError).Field)
+
+	job, err := uc.CreateExportJob(context.Background(), rsvp.ExportJobRequest{Format: rsvp.ExportFormatCsv, Columns: []string{"name", "attend"}}, "admin:budi")
+	assert.NoError(err)
+	assert.Equal(rsvp.ExportJobPending, job.Status)
+
+	_, _, err = uc.OpenExportFile(context.Background(), job.ID.Hex())
+	assert.Equal(response.ExportNotReadyError, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	uc.RunExportWorkers(ctx, 2)
+
+	var done *rsvp.ExportJob
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		done, err = uc.GetExportJob(context.Background(), job.ID.Hex())
+		assert.NoError(err)
+		if done.Status == rsvp.ExportJobDone {
+			break
+		}
+	}
+
+	assert.Equal(rsvp.ExportJobDone, done.Status)
+	assert.Equal(100, done.Progress)
+	assert.Equal(int64(2), done.Rows)
+	assert.NotNil(done.ExpiresAt)
+
+	_, f, err := uc.OpenExportFile(context.Background(), job.ID.Hex())
+	assert.NoError(err)
+	b, err := ioutil.ReadAll(f)
+	assert.NoError(err)
+	assert.Equal("Name,Attend\nBudi,Yes\nSiti,No\n", string(b))
+	assert.Equal(int64(len(b)), done.Size)
+}
+
+func TestTakeOverExportJob(t *testing.T) {
+	assert := assert.New(t)
+
+	expired, held := time.Now().Add(-time.Second), time.Now().Add(time.Hour)
+	parameter := rsvp.Parameter{Limit: constants.NoLimit}
+	staleID, busyID := bson.NewObjectId(), bson.NewObjectId()
+	jobs := &fakeExportJobRepo{jobs: []rsvp.ExportJob{
+		// left by a stopped instance halfway
+		{ID: staleID, Format: rsvp.ExportFormatCsv, Parameter: parameter, Status: rsvp.ExportJobRunning, Rows: 1, LeaseUntil: &expired},
+		// rendered by an instance still running
+		{ID: busyID, Format: rsvp.ExportFormatCsv, Parameter: parameter, Status: rsvp.ExportJobRunning, LeaseUntil: &held},
+	}}
+	uc := usecase.NewExportJobUsecase(&usecase.AccessProvider{
+		RsvpRepo:      &fakeRsvpRepo{data: []rsvp.Rsvp{{Name: "Budi"}, {Name: "Siti"}}},
+		ExportJobRepo: jobs,
+		FileStore:     &fakeFileStore{files: map[string]*bytes.Buffer{}},
+	}, usecase.ExportJobOption{TTL: time.Hour, PollInterval: 10 * time.Millisecond, Lease: time.Minute})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	uc.RunExportWorkers(ctx, 1)
+
+	var stale *rsvp.ExportJob
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if stale, _ = uc.GetExportJob(ctx, staleID.Hex()); stale.Status == rsvp.ExportJobDone {
+			break
+		}
+	}
+
+	assert.Equal(rsvp.ExportJobDone, stale.Status)
+	assert.Equal(int64(2), stale.Rows)
+	assert.Nil(stale.LeaseUntil)
+
+	busy, err := uc.GetExportJob(ctx, busyID.Hex())
+	assert.NoError(err)
+	assert.Equal(rsvp.ExportJobRunning, busy.Status)
+}
+
+// stolenExportJobRepo lets another worker take a job over before its first progress update
+type stolenExportJobRepo struct {
+	*fakeExportJobRepo
+	stolen chan struct{}
+}
+
+func (sr stolenExportJobRepo) UpdateExportJobRows(ctx context.Context, id bson.ObjectId, claim string, rows int64, lease time.Time) error {
+	sr.takeOver(id)
+	defer close(sr.stolen)
+	return sr.fakeExportJobRepo.UpdateExportJobRows(ctx, id, claim, rows, lease)
+}
+
+func TestExportJobClaimLost(t *testing.T) {
+	assert := assert.New(t)
+
+	rps := make([]rsvp.Rsvp, 600)
+	for i := range rps {
+		rps[i] = rsvp.Rsvp{Name: "Budi"}
+	}
+	id := bson.NewObjectId()
+	jobs := stolenExportJobRepo{
+		fakeExportJobRepo: &fakeExportJobRepo{jobs: []rsvp.ExportJob{
+			{ID: id, Format: rsvp.ExportFormatCsv, Parameter: rsvp.Parameter{Limit: constants.NoLimit}, Status: rsvp.ExportJobPending},
+		}},
+		stolen: make(chan struct{}),
+	}
+	files := &fakeFileStore{files: map[string]*bytes.Buffer{}}
+	uc := usecase.NewExportJobUsecase(&usecase.AccessProvider{
+		RsvpRepo:      &fakeRsvpRepo{data: rps},
+		ExportJobRepo: jobs,
+		FileStore:     files,
+	}, usecase.ExportJobOption{TTL: time.Hour, PollInterval: time.Hour, Lease: time.Minute})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	uc.RunExportWorkers(ctx, 1)
+
+	select {
+	case <-jobs.stolen:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the job was not rendered")
+	}
+
+	// the worker stops, leaving the job to the other worker, and removes its own file
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		files.Lock()
+		n := len(files.files)
+		files.Unlock()
+		if n == 0 {
+			break
+		}
+	}
+	job, err := uc.GetExportJob(ctx, id.Hex())
+	assert.NoError(err)
+	assert.Equal(rsvp.ExportJobRunning, job.Status)
+	assert.Equal("other", job.Claim)
+	assert.Equal(int64(0), job.Rows)
+	files.Lock()
+	defer files.Unlock()
+	assert.Empty(files.files)
+}
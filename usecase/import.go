@@ -0,0 +1,239 @@
+package usecase
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"strings"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/enumeration"
+	"github.com/faris-arifiansyah/fws-rsvp/request/validator"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+)
+
+// importBatchSize is the number of RSVPs inserted at once
+const importBatchSize = 500
+
+// importColumns are the export columns a CSV import reads, others are ignored
+var importColumns = map[string]struct{}{
+	"name":       {},
+	"address":    {},
+	"attend":     {},
+	"message":    {},
+	"email":      {},
+	"created_at": {},
+}
+
+// ImportRsvpsCsv creates RSVPs from the rows of a CSV file with a header row, such as one written by
+// WriteRsvpsCsv. The name, address and attend columns are required. Rows that are blank or repeat the name and address of an existing RSVP are skipped,
+// invalid rows are reported in the result and do not stop the import.
+func (ru *rsvpUsecase) ImportRsvpsCsv(ctx context.Context, r io.Reader, opt rsvp.ImportOption) (*rsvp.ImportResult, error) {
+	layout, err := newExportLayout(rsvp.ExportOption{TimeZone: opt.TimeZone, DateFormat: opt.DateFormat, Delimiter: opt.Delimiter})
+	if err != nil {
+		return nil, err
+	}
+	location := layout.location
+	if location == nil {
+		location = time.Local
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comma = layout.delimiter
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, badRequest("body")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := mapImportHeader(header, opt.Mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &rsvp.ImportResult{DryRun: opt.DryRun}
+	seen := map[string]struct{}{}
+	var batch []rsvp.Rsvp
+
+	// the header is row 1
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if pe, ok := err.(*csv.ParseError); ok {
+			result.Fail(row, "", pe.Err.Error())
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if isBlank(record) {
+			result.Skipped++
+			continue
+		}
+
+		item, field, message := parseImportRecord(record, columns, layout.dateFormat, location)
+		if field != "" {
+			result.Fail(row, field, message)
+			continue
+		}
+
+		key := strings.ToLower(item.Name + "\x00" + item.Address)
+		if _, ok := seen[key]; ok {
+			result.Skipped++
+			continue
+		}
+		seen[key] = struct{}{}
+
+		exists, err := ru.RsvpRepo.ExistsRsvp(ctx, item.Name, item.Address)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			result.Skipped++
+			continue
+		}
+
+		result.Created++
+		if opt.DryRun {
+			continue
+		}
+
+		batch = append(batch, item)
+		if len(batch) == importBatchSize {
+			if err = ru.RsvpRepo.CreateRsvps(ctx, batch); err != nil {
+				return nil, err
+			}
+			batch = batch[:0]
+		}
+	}
+
+	if len(batch) > 0 {
+		if err = ru.RsvpRepo.CreateRsvps(ctx, batch); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// mapImportHeader returns the column key of every header, empty for ignored headers.
+// A header is mapped by mapping, or else when it is a column key or label in any language.
+func mapImportHeader(header []string, mapping map[string]string) ([]string, error) {
+	columns := make([]string, len(header))
+	found := map[string]bool{}
+
+	for i, h := range header {
+		h = strings.TrimSpace(strings.TrimPrefix(h, utf8BOM))
+
+		key, ok := lookupFold(mapping, h)
+		if ok {
+			if _, readable := importColumns[key]; !readable {
+				return nil, badRequest("map")
+			}
+		} else {
+			key = importColumnOf(h)
+		}
+
+		if key == "" || found[key] {
+			continue
+		}
+		found[key] = true
+		columns[i] = key
+	}
+
+	if !found["name"] || !found["address"] || !found["attend"] {
+		return nil, badRequest("header")
+	}
+
+	return columns, nil
+}
+
+// importColumnOf returns the key of the import column named or labeled h
+func importColumnOf(h string) string {
+	for key := range importColumns {
+		if strings.EqualFold(key, h) {
+			return key
+		}
+		for _, label := range exportColumns[key].labels {
+			if strings.EqualFold(label, h) {
+				return key
+			}
+		}
+	}
+	return ""
+}
+
+// parseImportRecord returns the RSVP in record, or the field that is invalid and why
+func parseImportRecord(record []string, columns []string, dateFormat string, location *time.Location) (rsvp.Rsvp, string, string) {
+	var item rsvp.Rsvp
+
+	for i, value := range record {
+		if i >= len(columns) {
+			break
+		}
+
+		value = strings.TrimSpace(value)
+		switch columns[i] {
+		case "name":
+			item.Name = value
+		case "address":
+			item.Address = value
+		case "message":
+			item.Message = value
+		case "email":
+			if !validEmail(value) {
+				return item, "email", "invalid email address"
+			}
+			item.Email = value
+		case "attend":
+			at, err := enumeration.ParseAttendanceType(value)
+			if err != nil {
+				return item, "attend", err.Error()
+			}
+			item.Attend = at
+		case "created_at":
+			if value == "" {
+				continue
+			}
+			createdAt, err := time.ParseInLocation(dateFormat, value, location)
+			if err != nil {
+				return item, "created_at", err.Error()
+			}
+			item.CreatedAt = createdAt
+		}
+	}
+
+	if errs := validator.Validate(item); len(errs) > 0 {
+		ce := errs[0].(response.CustomError)
+		return item, ce.Field, ce.Message
+	}
+
+	return item, "", ""
+}
+
+func lookupFold(m map[string]string, key string) (string, bool) {
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func isBlank(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,189 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+)
+
+// OutboxOption configures outbox usecase
+type OutboxOption struct {
+	// Handlers are told about every event by name, such as "email" or "webhook".
+	// A handler is told again when the process stops before the entry is done,
+	// so it must tolerate an event it has seen.
+	Handlers map[string]rsvp.Publisher
+	// MaxAttempts is the number of attempts before an entry fails
+	MaxAttempts int
+	// Backoff is the delay before the first retry, doubled on every further retry up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Lease is how long a claimed entry is hidden from other workers, longer than any handler takes
+	Lease time.Duration
+	// Grace is how long an entry waits for its change to become visible before it is discarded
+	Grace time.Duration
+	// PollInterval is how often workers look for due entries
+	PollInterval time.Duration
+}
+
+type outboxUsecase struct {
+	*AccessProvider
+	opt   OutboxOption
+	names []string
+}
+
+func NewOutboxUsecase(pvd *AccessProvider, opt OutboxOption) rsvp.OutboxUsecase {
+	names := make([]string, 0, len(opt.Handlers))
+	for name := range opt.Handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return &outboxUsecase{pvd, opt, names}
+}
+
+// RunOutboxDispatchers starts workers goroutines delivering due entries, stopping when ctx is done.
+// Entries left by a stopped process are delivered again once their lease is over.
+func (ou *outboxUsecase) RunOutboxDispatchers(ctx context.Context, workers int) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			ticker := time.NewTicker(ou.opt.PollInterval)
+			defer ticker.Stop()
+
+			for {
+				for ctx.Err() == nil && ou.dispatchNext(ctx) {
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+}
+
+// dispatchNext delivers the entry due the longest, reporting whether there was one
+func (ou *outboxUsecase) dispatchNext(ctx context.Context) (dispatched bool) {
+	now := time.Now()
+	e, err := ou.OutboxRepo.ClaimOutboxEntry(ctx, now, now.Add(ou.opt.Lease))
+	if err == response.NotFoundError {
+		return false
+	}
+	if err != nil {
+		log.Printf("outbox: claim failed: %v", err)
+		return false
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			ou.retry(e, fmt.Errorf("panic: %v", r))
+			ou.update(ctx, e)
+			dispatched = true
+		}
+	}()
+
+	ou.dispatch(ctx, e)
+	ou.update(ctx, e)
+
+	return true
+}
+
+// dispatch tells the handlers that have not handled e yet about its event
+func (ou *outboxUsecase) dispatch(ctx context.Context, e *rsvp.OutboxEntry) {
+	saved, err := ou.saved(ctx, e)
+	if err != nil {
+		ou.retry(e, err)
+		return
+	}
+	if !saved {
+		if time.Since(e.CreatedAt) < ou.opt.Grace {
+			next := time.Now().Add(ou.opt.PollInterval)
+			e.NextAttemptAt = &next
+		} else {
+			e.Status = rsvp.OutboxDiscarded
+			e.NextAttemptAt = nil
+		}
+		return
+	}
+
+	var errs []string
+	for _, name := range ou.names {
+		if handled(e, name) {
+			continue
+		}
+
+		if err = ou.opt.Handlers[name].Publish(ctx, e.Event); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		e.Handled = append(e.Handled, name)
+	}
+
+	if len(errs) > 0 {
+		ou.retry(e, fmt.Errorf("%s", strings.Join(errs, "; ")))
+		return
+	}
+
+	now := time.Now()
+	e.Status = rsvp.OutboxDone
+	e.Error = ""
+	e.NextAttemptAt = nil
+	e.DoneAt = &now
+}
+
+// saved reports whether the change e tells about is visible, as its entry is written ahead of it.
+// An update is visible once the RSVP has its revision or a later one, which also covers an update
+// overwritten by a later one before it was dispatched.
+func (ou *outboxUsecase) saved(ctx context.Context, e *rsvp.OutboxEntry) (bool, error) {
+	rp, err := ou.RsvpRepo.GetRsvp(ctx, e.RsvpID.Hex())
+	if err != nil && err != response.NotFoundError {
+		return false, err
+	}
+
+	exists := err == nil
+	switch e.Event.Type {
+	case rsvp.EventRsvpDeleted:
+		return !exists, nil
+	case rsvp.EventRsvpUpdated:
+		return exists && rp.Revision >= e.Event.Rsvp.Revision, nil
+	}
+	return exists, nil
+}
+
+// retry records a failed attempt of e and schedules the next one, or fails e after the last one
+func (ou *outboxUsecase) retry(e *rsvp.OutboxEntry, err error) {
+	e.Attempts++
+	e.Error = err.Error()
+	e.NextAttemptAt = nil
+
+	if e.Attempts >= ou.opt.MaxAttempts {
+		log.Printf("outbox entry %s: failed: %v", e.ID.Hex(), err)
+		e.Status = rsvp.OutboxFailed
+		return
+	}
+
+	next := time.Now().Add(backoff(ou.opt.Backoff, ou.opt.MaxBackoff, e.Attempts))
+	e.NextAttemptAt = &next
+}
+
+func (ou *outboxUsecase) update(ctx context.Context, e *rsvp.OutboxEntry) {
+	if err := ou.OutboxRepo.UpdateOutboxEntry(ctx, *e); err != nil {
+		log.Printf("outbox entry %s: update failed: %v", e.ID.Hex(), err)
+	}
+}
+
+func handled(e *rsvp.OutboxEntry, name string) bool {
+	for _, h := range e.Handled {
+		if h == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/enumeration"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/faris-arifiansyah/fws-rsvp/usecase"
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeOutboxRepo keeps outbox entries in memory
+type fakeOutboxRepo struct {
+	sync.Mutex
+	entries []rsvp.OutboxEntry
+}
+
+func (fr *fakeOutboxRepo) ClaimOutboxEntry(ctx context.Context, now time.Time, lease time.Time) (*rsvp.OutboxEntry, error) {
+	fr.Lock()
+	defer fr.Unlock()
+
+	for i, e := range fr.entries {
+		if e.Status == rsvp.OutboxPending && !e.NextAttemptAt.After(now) {
+			fr.entries[i].NextAttemptAt = &lease
+			return &e, nil
+		}
+	}
+	return nil, response.NotFoundError
+}
+
+func (fr *fakeOutboxRepo) UpdateOutboxEntry(ctx context.Context, e rsvp.OutboxEntry) error {
+	fr.Lock()
+	defer fr.Unlock()
+
+	for i := range fr.entries {
+		if fr.entries[i].ID == e.ID {
+			fr.entries[i] = e
+		}
+	}
+	return nil
+}
+
+func (fr *fakeOutboxRepo) GetOutboxEvents(ctx context.Context, eventTypes []string, from time.Time, to time.Time) ([]rsvp.Event, error) {
+	fr.Lock()
+	defer fr.Unlock()
+
+	var events []rsvp.Event
+	for _, e := range fr.entries {
+		for _, t := range eventTypes {
+			if e.Event.Type == t && !e.CreatedAt.Before(from) && e.CreatedAt.Before(to) && e.Status != rsvp.OutboxDiscarded {
+				events = append(events, e.Event)
+			}
+		}
+	}
+	return events, nil
+}
+
+func (fr *fakeOutboxRepo) entry(i int) rsvp.OutboxEntry {
+	fr.Lock()
+	defer fr.Unlock()
+
+	return fr.entries[i]
+}
+
+// fakePublisher records the events it is told about, failing the first fails times
+type fakePublisher struct {
+	sync.Mutex
+	fails  int
+	events []string
+}
+
+func (fp *fakePublisher) Publish(ctx context.Context, e rsvp.Event) error {
+	fp.Lock()
+	defer fp.Unlock()
+
+	if fp.fails > 0 {
+		fp.fails--
+		return errors.New("unavailable")
+	}
+	fp.events = append(fp.events, e.ID)
+	return nil
+}
+
+func TestOutboxDispatch(t *testing.T) {
+	assert := assert.New(t)
+
+	saved := rsvp.Rsvp{ID: bson.NewObjectId(), Name: "Budi", Revision: 2}
+	deleted := rsvp.Rsvp{ID: bson.NewObjectId(), Name: "Siti"}
+	unsaved := rsvp.Rsvp{ID: bson.NewObjectId(), Name: "Rudi"}
+	updated := saved
+	updated.Message = "Selamat!"
+	overwritten := saved
+	overwritten.Revision = 1
+	overwritten.Message = "Selamat menempuh hidup baru!"
+	failed := saved
+	failed.Revision = 3
+	failed.Attend = enumeration.AttendanceTypeNo
+
+	now := time.Now()
+	longAgo := now.Add(-time.Hour)
+	outbox := &fakeOutboxRepo{entries: []rsvp.OutboxEntry{
+		{ID: bson.NewObjectId(), Event: rsvp.Event{ID: "created", Type: rsvp.EventRsvpCreated, Rsvp: saved}, RsvpID: saved.ID, CreatedAt: now},
+		{ID: bson.NewObjectId(), Event: rsvp.Event{ID: "deleted", Type: rsvp.EventRsvpDeleted, Rsvp: deleted}, RsvpID: deleted.ID, CreatedAt: now},
+		// the process stopped before the RSVP was saved
+		{ID: bson.NewObjectId(), Event: rsvp.Event{ID: "unsaved", Type: rsvp.EventRsvpCreated, Rsvp: unsaved}, RsvpID: unsaved.ID, CreatedAt: longAgo},
+		{ID: bson.NewObjectId(), Event: rsvp.Event{ID: "updated", Type: rsvp.EventRsvpUpdated, Rsvp: updated, Previous: &saved}, RsvpID: saved.ID, CreatedAt: now},
+		// the update was never applied, though the RSVP exists
+		{ID: bson.NewObjectId(), Event: rsvp.Event{ID: "failed", Type: rsvp.EventRsvpUpdated, Rsvp: failed, Previous: &saved}, RsvpID: saved.ID, CreatedAt: longAgo},
+		// the update was applied, then overwritten by a later one before it was dispatched
+		{ID: bson.NewObjectId(), Event: rsvp.Event{ID: "overwritten", Type: rsvp.EventRsvpUpdated, Rsvp: overwritten, Previous: &saved}, RsvpID: saved.ID, CreatedAt: now},
+	}}
+	for i := range outbox.entries {
+		outbox.entries[i].Status = rsvp.OutboxPending
+		outbox.entries[i].NextAttemptAt = &now
+	}
+
+	email := &fakePublisher{fails: 1}
+	webhook := &fakePublisher{}
+	uc := usecase.NewOutboxUsecase(&usecase.AccessProvider{
+		RsvpRepo:   &fakeRsvpRepo{data: []rsvp.Rsvp{saved}},
+		OutboxRepo: outbox,
+	}, usecase.OutboxOption{
+		Handlers:     map[string]rsvp.Publisher{"email": email, "webhook": webhook},
+		MaxAttempts:  3,
+		Backoff:      time.Millisecond,
+		MaxBackoff:   time.Millisecond,
+		Lease:        time.Minute,
+		Grace:        time.Minute,
+		PollInterval: 10 * time.Millisecond,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	uc.RunOutboxDispatchers(ctx, 1)
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if outbox.entry(0).Status != rsvp.OutboxPending && outbox.entry(1).Status != rsvp.OutboxPending && outbox.entry(3).Status != rsvp.OutboxPending && outbox.entry(5).Status != rsvp.OutboxPending {
+			break
+		}
+	}
+	cancel()
+
+	created := outbox.entry(0)
+	assert.Equal(rsvp.OutboxDone, created.Status)
+	assert.Equal(1, created.Attempts)
+	assert.ElementsMatch([]string{"email", "webhook"}, created.Handled)
+	assert.Equal(rsvp.OutboxDone, outbox.entry(1).Status)
+	assert.Equal(rsvp.OutboxDiscarded, outbox.entry(2).Status)
+	assert.Equal(rsvp.OutboxDone, outbox.entry(3).Status)
+	assert.Equal(rsvp.OutboxDiscarded, outbox.entry(4).Status)
+	assert.Equal(rsvp.OutboxDone, outbox.entry(5).Status)
+
+	// the webhook is not told again when only the email failed
+	assert.Equal([]string{"created", "deleted", "updated", "overwritten"}, webhook.events)
+	// a retried event may arrive after later ones
+	assert.ElementsMatch([]string{"created", "deleted", "updated", "overwritten"}, email.events)
+}
@@ -0,0 +1,396 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/constants"
+	"github.com/faris-arifiansyah/fws-rsvp/request/validator"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+)
+
+// DefaultReminderMessage is sent by a campaign without a message, executed with the invitee
+const DefaultReminderMessage = `Hi {{.Name}}, we have not received your RSVP yet. We would love to know whether you can come!`
+
+// reminderSkipped is the outcome of an invitee who was not reminded
+const reminderSkipped = "skipped"
+
+// errCampaignStopped stops the run of a campaign that is no longer running, as it was cancelled
+var errCampaignStopped = errors.New("campaign stopped")
+
+// phonePattern matches a phone number without separators
+var phonePattern = regexp.MustCompile(`^\+?[0-9]{8,15}$`)
+
+// phoneSeparators are left out of a phone number
+var phoneSeparators = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+
+// ReminderOption configures reminder usecase
+type ReminderOption struct {
+	// Senders deliver reminders by channel, a campaign can only use these channels
+	Senders map[string]rsvp.ReminderSender
+	// Message is the message of a campaign without one, DefaultReminderMessage when empty
+	Message string
+	// Interval is the pause after every reminder sent, so a channel is not flooded
+	Interval time.Duration
+	// Cooldown is how long after a reminder an invitee is not reminded again
+	Cooldown time.Duration
+	// MaxPerInvitee is the number of reminders an invitee gets at most, zero for no limit
+	MaxPerInvitee int
+	// PollInterval is how often an idle worker looks for pending campaigns
+	PollInterval time.Duration
+	// Lease is how long a running campaign is kept from other workers after each reminder,
+	// longer than Interval and a reminder take
+	Lease time.Duration
+}
+
+type reminderUsecase struct {
+	*AccessProvider
+	opt  ReminderOption
+	wake chan struct{}
+}
+
+func NewReminderUsecase(pvd *AccessProvider, opt ReminderOption) rsvp.ReminderUsecase {
+	if opt.Message == "" {
+		opt.Message = DefaultReminderMessage
+	}
+
+	return &reminderUsecase{pvd, opt, make(chan struct{}, 1)}
+}
+
+// CreateInvitees saves invitees, whose email and phone are optional. Invitees who have
+// already sent an RSVP are saved as responded.
+func (ru *reminderUsecase) CreateInvitees(ctx context.Context, invitees []rsvp.Invitee) ([]rsvp.Invitee, error) {
+	if len(invitees) == 0 {
+		return nil, badRequest("body")
+	}
+
+	now := time.Now()
+	for i := range invitees {
+		inv := &invitees[i]
+		inv.Name = strings.TrimSpace(inv.Name)
+		inv.Address = strings.TrimSpace(inv.Address)
+		inv.Email = strings.TrimSpace(inv.Email)
+		inv.Phone = phoneSeparators.Replace(inv.Phone)
+		inv.RespondedAt, inv.Reminders, inv.LastRemindedAt = nil, 0, nil
+
+		if errs := validator.Validate(*inv); len(errs) > 0 {
+			return nil, errs[0]
+		}
+		if !validEmail(inv.Email) {
+			return nil, badRequest("email")
+		}
+		if inv.Phone != "" && !phonePattern.MatchString(inv.Phone) {
+			return nil, badRequest("phone")
+		}
+
+		responded, err := ru.responded(ctx, *inv)
+		if err != nil {
+			return nil, err
+		}
+		if responded {
+			inv.RespondedAt = &now
+		}
+	}
+
+	if err := ru.InviteeRepo.CreateInvitees(ctx, invitees); err != nil {
+		return nil, err
+	}
+
+	return invitees, nil
+}
+
+func (ru *reminderUsecase) GetInvitees(ctx context.Context, p *rsvp.Parameter, pending bool) (*rsvp.InviteeResult, error) {
+	return ru.InviteeRepo.GetInvitees(ctx, p, pending)
+}
+
+func (ru *reminderUsecase) DeleteInvitee(ctx context.Context, id string) error {
+	return ru.InviteeRepo.DeleteInvitee(ctx, id)
+}
+
+func (ru *reminderUsecase) GetReminders(ctx context.Context, inviteeID string) ([]*rsvp.Reminder, error) {
+	if _, err := ru.InviteeRepo.GetInvitee(ctx, inviteeID); err != nil {
+		return nil, err
+	}
+
+	return ru.ReminderRepo.GetReminders(ctx, inviteeID)
+}
+
+// Publish marks the invitees of the RSVP of a rsvp.created or rsvp.updated event as responded
+func (ru *reminderUsecase) Publish(ctx context.Context, e rsvp.Event) error {
+	if e.Type != rsvp.EventRsvpCreated && e.Type != rsvp.EventRsvpUpdated {
+		return nil
+	}
+
+	return ru.InviteeRepo.MarkInviteesResponded(ctx, e.Rsvp.Name, e.Rsvp.Address, e.Rsvp.Email, e.CreatedAt)
+}
+
+func (ru *reminderUsecase) CreateReminderCampaign(ctx context.Context, req rsvp.ReminderCampaignRequest, createdBy string) (rsvp.ReminderCampaign, error) {
+	if _, ok := ru.opt.Senders[req.Channel]; !ok {
+		return rsvp.ReminderCampaign{}, badRequest("channel")
+	}
+
+	if strings.TrimSpace(req.Message) == "" {
+		req.Message = ru.opt.Message
+	}
+	if _, err := template.New("message").Parse(req.Message); err != nil {
+		return rsvp.ReminderCampaign{}, badRequest("message")
+	}
+
+	c, err := ru.ReminderRepo.CreateReminderCampaign(ctx, rsvp.ReminderCampaign{
+		Channel:   req.Channel,
+		Message:   req.Message,
+		Status:    rsvp.CampaignPending,
+		CreatedBy: createdBy,
+	})
+	if err != nil {
+		return rsvp.ReminderCampaign{}, err
+	}
+
+	// a worker waiting for campaigns starts right away
+	select {
+	case ru.wake <- struct{}{}:
+	default:
+	}
+
+	return c, nil
+}
+
+func (ru *reminderUsecase) GetReminderCampaign(ctx context.Context, id string) (*rsvp.ReminderCampaign, error) {
+	return ru.ReminderRepo.GetReminderCampaign(ctx, id)
+}
+
+func (ru *reminderUsecase) GetReminderCampaigns(ctx context.Context) ([]*rsvp.ReminderCampaign, error) {
+	return ru.ReminderRepo.GetReminderCampaigns(ctx)
+}
+
+// CancelReminderCampaign stops a pending or running campaign, a running one after its current reminder
+func (ru *reminderUsecase) CancelReminderCampaign(ctx context.Context, id string) (*rsvp.ReminderCampaign, error) {
+	return ru.ReminderRepo.CancelReminderCampaign(ctx, id, time.Now())
+}
+
+// RunReminderWorker starts a goroutine sending the reminders of pending campaigns until ctx is done.
+// Campaigns run one at a time, so reminders never go out faster than Interval. A campaign left
+// running by a stopped process is taken over once its lease is over.
+func (ru *reminderUsecase) RunReminderWorker(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(ru.opt.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			for ctx.Err() == nil && ru.runNext(ctx) {
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ru.wake:
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// runNext runs the oldest pending campaign, reporting whether there was one
+func (ru *reminderUsecase) runNext(ctx context.Context) (ran bool) {
+	now := time.Now()
+	c, err := ru.ReminderRepo.ClaimReminderCampaign(ctx, now, now.Add(ru.opt.Lease))
+	if err == response.NotFoundError {
+		return false
+	}
+	if err != nil {
+		log.Printf("reminder campaign: claim failed: %v", err)
+		return false
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			ru.finish(ctx, c, fmt.Errorf("panic: %v", r))
+			ran = true
+		}
+	}()
+
+	err = ru.run(ctx, c)
+	// a campaign interrupted by shutdown is taken over once its lease is over
+	if err != errCampaignStopped && ctx.Err() == nil {
+		ru.finish(ctx, c, err)
+	}
+
+	return true
+}
+
+// run reminds every invitee who has not responded, stopping with errCampaignStopped when the
+// campaign is cancelled. The counts start over when a campaign is taken over.
+func (ru *reminderUsecase) run(ctx context.Context, c *rsvp.ReminderCampaign) error {
+	message, err := template.New("message").Parse(c.Message)
+	if err != nil {
+		return err
+	}
+
+	invitees, err := ru.InviteeRepo.GetInvitees(ctx, &rsvp.Parameter{Limit: constants.NoLimit}, true)
+	if err != nil {
+		return err
+	}
+	if err = ru.ReminderRepo.StartReminderCampaign(ctx, c.ID, len(invitees.Data), time.Now().Add(ru.opt.Lease)); err != nil {
+		return ru.stopped(err)
+	}
+
+	for _, inv := range invitees.Data {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var p rsvp.ReminderProgress
+		outcome := ru.remind(ctx, c, *inv, message)
+		switch outcome {
+		case rsvp.ReminderSent:
+			p.Sent++
+		case rsvp.ReminderFailed:
+			p.Failed++
+		default:
+			p.Skipped++
+		}
+
+		if err = ru.ReminderRepo.AddReminderProgress(ctx, c.ID, p, time.Now().Add(ru.opt.Lease)); err != nil {
+			return ru.stopped(err)
+		}
+		if outcome == reminderSkipped {
+			continue
+		}
+
+		timer := time.NewTimer(ru.opt.Interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	return nil
+}
+
+// stopped turns the response.NotFoundError of a campaign that is no longer running into errCampaignStopped
+func (ru *reminderUsecase) stopped(err error) error {
+	if err == response.NotFoundError {
+		return errCampaignStopped
+	}
+	return err
+}
+
+// remind sends the campaign message to inv unless they have responded, have no contact on the
+// channel, were reminded too often or by this campaign before it was taken over, and returns the outcome
+func (ru *reminderUsecase) remind(ctx context.Context, c *rsvp.ReminderCampaign, inv rsvp.Invitee, message *template.Template) string {
+	to := inv.Email
+	if c.Channel == rsvp.ReminderSMS {
+		to = inv.Phone
+	}
+	if to == "" {
+		return reminderSkipped
+	}
+
+	if ru.opt.MaxPerInvitee > 0 && inv.Reminders >= ru.opt.MaxPerInvitee {
+		return reminderSkipped
+	}
+	if inv.LastRemindedAt != nil && time.Since(*inv.LastRemindedAt) < ru.opt.Cooldown {
+		return reminderSkipped
+	}
+
+	reminded, err := ru.remindedBy(ctx, c, inv)
+	if err != nil {
+		log.Printf("reminder campaign %s: invitee %s: %v", c.ID.Hex(), inv.ID.Hex(), err)
+		return reminderSkipped
+	}
+	if reminded {
+		return reminderSkipped
+	}
+
+	// the invitee may have responded since the outbox last told about an RSVP
+	responded, err := ru.responded(ctx, inv)
+	if err != nil {
+		log.Printf("reminder campaign %s: invitee %s: %v", c.ID.Hex(), inv.ID.Hex(), err)
+		return reminderSkipped
+	}
+	if responded {
+		if err = ru.InviteeRepo.MarkInviteesResponded(ctx, inv.Name, inv.Address, inv.Email, time.Now()); err != nil {
+			log.Printf("reminder campaign %s: invitee %s: %v", c.ID.Hex(), inv.ID.Hex(), err)
+		}
+		return reminderSkipped
+	}
+
+	r := rsvp.Reminder{
+		CampaignID: c.ID,
+		InviteeID:  inv.ID,
+		Channel:    c.Channel,
+		To:         to,
+		Status:     rsvp.ReminderSent,
+	}
+
+	var text bytes.Buffer
+	err = message.Execute(&text, inv)
+	if err == nil {
+		err = ru.opt.Senders[c.Channel].SendReminder(ctx, inv, text.String())
+	}
+	r.SentAt = time.Now()
+	if err != nil {
+		r.Status = rsvp.ReminderFailed
+		r.Error = err.Error()
+	}
+
+	if err = ru.ReminderRepo.CreateReminder(ctx, r); err != nil {
+		log.Printf("reminder campaign %s: invitee %s: %v", c.ID.Hex(), inv.ID.Hex(), err)
+	}
+	if r.Status == rsvp.ReminderSent {
+		if err = ru.InviteeRepo.RecordInviteeReminder(ctx, inv.ID, r.SentAt); err != nil {
+			log.Printf("reminder campaign %s: invitee %s: %v", c.ID.Hex(), inv.ID.Hex(), err)
+		}
+	}
+
+	return r.Status
+}
+
+// remindedBy reports whether c has reminded inv already
+func (ru *reminderUsecase) remindedBy(ctx context.Context, c *rsvp.ReminderCampaign, inv rsvp.Invitee) (bool, error) {
+	reminders, err := ru.ReminderRepo.GetReminders(ctx, inv.ID.Hex())
+	if err != nil {
+		return false, err
+	}
+
+	for _, r := range reminders {
+		if r.CampaignID == c.ID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// responded reports whether inv has sent an RSVP, by name and address or by email
+func (ru *reminderUsecase) responded(ctx context.Context, inv rsvp.Invitee) (bool, error) {
+	exists, err := ru.RsvpRepo.ExistsRsvp(ctx, inv.Name, inv.Address)
+	if err != nil || exists || inv.Email == "" {
+		return exists, err
+	}
+
+	return ru.RsvpRepo.ExistsRsvpWithEmail(ctx, inv.Email)
+}
+
+// finish records the outcome of a campaign that ran to its end or failed, unless it was cancelled meanwhile
+func (ru *reminderUsecase) finish(ctx context.Context, c *rsvp.ReminderCampaign, err error) {
+	status, errMsg := rsvp.CampaignDone, ""
+	if err != nil {
+		log.Printf("reminder campaign %s: failed: %v", c.ID.Hex(), err)
+		status, errMsg = rsvp.CampaignFailed, err.Error()
+	}
+
+	err = ru.ReminderRepo.FinishReminderCampaign(ctx, c.ID, status, errMsg, time.Now())
+	if err != nil && err != response.NotFoundError {
+		log.Printf("reminder campaign %s: update failed: %v", c.ID.Hex(), err)
+	}
+}
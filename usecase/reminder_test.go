@@ -0,0 +1,459 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/constants"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/faris-arifiansyah/fws-rsvp/usecase"
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeInviteeRepo keeps invitees in memory
+type fakeInviteeRepo struct {
+	sync.Mutex
+	invitees []rsvp.Invitee
+}
+
+func (fr *fakeInviteeRepo) CreateInvitees(ctx context.Context, invitees []rsvp.Invitee) error {
+	fr.Lock()
+	defer fr.Unlock()
+
+	for i := range invitees {
+		invitees[i].ID = bson.NewObjectId()
+		fr.invitees = append(fr.invitees, invitees[i])
+	}
+	return nil
+}
+
+func (fr *fakeInviteeRepo) GetInvitee(ctx context.Context, id string) (*rsvp.Invitee, error) {
+	fr.Lock()
+	defer fr.Unlock()
+
+	for _, inv := range fr.invitees {
+		if inv.ID.Hex() == id {
+			return &inv, nil
+		}
+	}
+	return nil, response.NotFoundError
+}
+
+func (fr *fakeInviteeRepo) GetInvitees(ctx context.Context, p *rsvp.Parameter, pending bool) (*rsvp.InviteeResult, error) {
+	fr.Lock()
+	defer fr.Unlock()
+
+	result := new(rsvp.InviteeResult)
+	for _, inv := range fr.invitees {
+		if !pending || inv.RespondedAt == nil {
+			inv := inv
+			result.Data = append(result.Data, &inv)
+			result.Total++
+		}
+	}
+	return result, nil
+}
+
+func (fr *fakeInviteeRepo) DeleteInvitee(ctx context.Context, id string) error {
+	return nil
+}
+
+func (fr *fakeInviteeRepo) MarkInviteesResponded(ctx context.Context, name string, address string, email string, at time.Time) error {
+	fr.Lock()
+	defer fr.Unlock()
+
+	for i, inv := range fr.invitees {
+		if inv.RespondedAt == nil && ((inv.Name == name && inv.Address == address) || (email != "" && inv.Email == email)) {
+			fr.invitees[i].RespondedAt = &at
+		}
+	}
+	return nil
+}
+
+func (fr *fakeInviteeRepo) RecordInviteeReminder(ctx context.Context, id bson.ObjectId, at time.Time) error {
+	fr.Lock()
+	defer fr.Unlock()
+
+	for i := range fr.invitees {
+		if fr.invitees[i].ID == id {
+			fr.invitees[i].Reminders++
+			fr.invitees[i].LastRemindedAt = &at
+		}
+	}
+	return nil
+}
+
+// responded returns the names of the invitees who have responded
+func (fr *fakeInviteeRepo) responded() []string {
+	fr.Lock()
+	defer fr.Unlock()
+
+	var names []string
+	for _, inv := range fr.invitees {
+		if inv.RespondedAt != nil {
+			names = append(names, inv.Name)
+		}
+	}
+	return names
+}
+
+// fakeReminderRepo keeps campaigns and reminders in memory
+type fakeReminderRepo struct {
+	sync.Mutex
+	campaigns []rsvp.ReminderCampaign
+	reminders []rsvp.Reminder
+}
+
+func (fr *fakeReminderRepo) CreateReminderCampaign(ctx context.Context, c rsvp.ReminderCampaign) (rsvp.ReminderCampaign, error) {
+	fr.Lock()
+	defer fr.Unlock()
+
+	c.ID = bson.NewObjectId()
+	fr.campaigns = append(fr.campaigns, c)
+	return c, nil
+}
+
+func (fr *fakeReminderRepo) GetReminderCampaign(ctx context.Context, id string) (*rsvp.ReminderCampaign, error) {
+	fr.Lock()
+	defer fr.Unlock()
+
+	for _, c := range fr.campaigns {
+		if c.ID.Hex() == id {
+			return &c, nil
+		}
+	}
+	return nil, response.NotFoundError
+}
+
+func (fr *fakeReminderRepo) GetReminderCampaigns(ctx context.Context) ([]*rsvp.ReminderCampaign, error) {
+	return nil, nil
+}
+
+func (fr *fakeReminderRepo) ClaimReminderCampaign(ctx context.Context, now time.Time, lease time.Time) (*rsvp.ReminderCampaign, error) {
+	fr.Lock()
+	defer fr.Unlock()
+
+	for i, c := range fr.campaigns {
+		if c.Status == rsvp.CampaignPending || (c.Status == rsvp.CampaignRunning && (c.LeaseUntil == nil || !c.LeaseUntil.After(now))) {
+			fr.campaigns[i].Status = rsvp.CampaignRunning
+			fr.campaigns[i].LeaseUntil = &lease
+			return &fr.campaigns[i], nil
+		}
+	}
+	return nil, response.NotFoundError
+}
+
+func (fr *fakeReminderRepo) StartReminderCampaign(ctx context.Context, id bson.ObjectId, total int, lease time.Time) error {
+	return fr.updateRunning(id, func(c *rsvp.ReminderCampaign) {
+		c.Total, c.Sent, c.Skipped, c.Failed, c.LeaseUntil = total, 0, 0, 0, &lease
+	})
+}
+
+func (fr *fakeReminderRepo) AddReminderProgress(ctx context.Context, id bson.ObjectId, p rsvp.ReminderProgress, lease time.Time) error {
+	return fr.updateRunning(id, func(c *rsvp.ReminderCampaign) {
+		c.Sent += p.Sent
+		c.Skipped += p.Skipped
+		c.Failed += p.Failed
+		c.LeaseUntil = &lease
+	})
+}
+
+func (fr *fakeReminderRepo) FinishReminderCampaign(ctx context.Context, id bson.ObjectId, status string, errMsg string, at time.Time) error {
+	return fr.updateRunning(id, func(c *rsvp.ReminderCampaign) {
+		c.Status, c.Error, c.FinishedAt, c.LeaseUntil = status, errMsg, &at, nil
+	})
+}
+
+func (fr *fakeReminderRepo) updateRunning(id bson.ObjectId, update func(c *rsvp.ReminderCampaign)) error {
+	fr.Lock()
+	defer fr.Unlock()
+
+	for i := range fr.campaigns {
+		if fr.campaigns[i].ID == id && fr.campaigns[i].Status == rsvp.CampaignRunning {
+			update(&fr.campaigns[i])
+			return nil
+		}
+	}
+	return response.NotFoundError
+}
+
+func (fr *fakeReminderRepo) CancelReminderCampaign(ctx context.Context, id string, at time.Time) (*rsvp.ReminderCampaign, error) {
+	fr.Lock()
+	defer fr.Unlock()
+
+	for i, c := range fr.campaigns {
+		if c.ID.Hex() != id {
+			continue
+		}
+		if c.Status != rsvp.CampaignPending && c.Status != rsvp.CampaignRunning {
+			return nil, response.CampaignFinishedError
+		}
+		fr.campaigns[i].Status, fr.campaigns[i].FinishedAt, fr.campaigns[i].LeaseUntil = rsvp.CampaignCancelled, &at, nil
+		c = fr.campaigns[i]
+		return &c, nil
+	}
+	return nil, response.NotFoundError
+}
+
+func (fr *fakeReminderRepo) CreateReminder(ctx context.Context, r rsvp.Reminder) error {
+	fr.Lock()
+	defer fr.Unlock()
+
+	r.ID = bson.NewObjectId()
+	fr.reminders = append(fr.reminders, r)
+	return nil
+}
+
+func (fr *fakeReminderRepo) GetReminders(ctx context.Context, inviteeID string) ([]*rsvp.Reminder, error) {
+	fr.Lock()
+	defer fr.Unlock()
+
+	var reminders []*rsvp.Reminder
+	for _, r := range fr.reminders {
+		if r.InviteeID.Hex() == inviteeID {
+			r := r
+			reminders = append(reminders, &r)
+		}
+	}
+	return reminders, nil
+}
+
+// fakeReminderSender records the reminders it sends, failing for the invitees named in fails.
+// It calls sent, when set, after every reminder.
+type fakeReminderSender struct {
+	sync.Mutex
+	fails map[string]bool
+	texts []string
+	sent  func()
+}
+
+func (fs *fakeReminderSender) SendReminder(ctx context.Context, inv rsvp.Invitee, text string) error {
+	fs.Lock()
+	defer fs.Unlock()
+
+	if fs.sent != nil {
+		defer fs.sent()
+	}
+	if fs.fails[inv.Name] {
+		return errors.New("mailbox unavailable")
+	}
+	fs.texts = append(fs.texts, text)
+	return nil
+}
+
+// campaign returns the campaign at i
+func (fr *fakeReminderRepo) campaign(i int) rsvp.ReminderCampaign {
+	fr.Lock()
+	defer fr.Unlock()
+
+	return fr.campaigns[i]
+}
+
+func TestCreateInvitees(t *testing.T) {
+	tests := []struct {
+		name    string
+		invitee rsvp.Invitee
+		field   string
+	}{
+		{"no name", rsvp.Invitee{Address: "Jakarta"}, "name"},
+		{"invalid email", rsvp.Invitee{Name: "Budi", Email: "budi@"}, "email"},
+		{"invalid phone", rsvp.Invitee{Name: "Budi", Phone: "0812-ABC"}, "phone"},
+		{"valid", rsvp.Invitee{Name: "Budi", Address: "Jakarta", Phone: "+62 812-3456-7890"}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			uc := usecase.NewReminderUsecase(&usecase.AccessProvider{RsvpRepo: &fakeRsvpRepo{}, InviteeRepo: &fakeInviteeRepo{}}, usecase.ReminderOption{})
+
+			invitees, err := uc.CreateInvitees(context.Background(), []rsvp.Invitee{test.invitee})
+			if test.field != "" {
+				assert.Equal(t, test.field, err.(response.CustomError).Field)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, "+6281234567890", invitees[0].Phone)
+		})
+	}
+}
+
+func TestReminderCampaign(t *testing.T) {
+	assert := assert.New(t)
+
+	lastWeek := time.Now().AddDate(0, 0, -7)
+	yesterday := time.Now().AddDate(0, 0, -1)
+	rsvps := &fakeRsvpRepo{data: []rsvp.Rsvp{{Name: "Siti", Address: "Bandung", Email: "siti@example.com"}}}
+	invitees := &fakeInviteeRepo{}
+	reminders := &fakeReminderRepo{}
+	sender := &fakeReminderSender{fails: map[string]bool{"Dewi": true}}
+
+	uc := usecase.NewReminderUsecase(&usecase.AccessProvider{RsvpRepo: rsvps, InviteeRepo: invitees, ReminderRepo: reminders}, usecase.ReminderOption{
+		Senders:       map[string]rsvp.ReminderSender{rsvp.ReminderEmail: sender},
+		Cooldown:      48 * time.Hour,
+		MaxPerInvitee: 3,
+		PollInterval:  10 * time.Millisecond,
+	})
+	ctx := context.Background()
+
+	created, err := uc.CreateInvitees(ctx, []rsvp.Invitee{
+		{Name: "Budi", Address: "Jakarta", Email: "budi@example.com"},
+		// responded before being invited, by email
+		{Name: "Siti", Address: "Bogor", Email: "siti@example.com"},
+		{Name: "Rudi", Address: "Depok", Phone: "081234567890"},
+		{Name: "Dewi", Address: "Bekasi", Email: "dewi@example.com"},
+		{Name: "Andi", Address: "Tangerang", Email: "andi@example.com"},
+		{Name: "Wati", Address: "Cirebon", Email: "wati@example.com"},
+		{Name: "Joko", Address: "Solo", Email: "joko@example.com"},
+	})
+	assert.NoError(err)
+	assert.NotNil(created[1].RespondedAt)
+
+	// Andi was reminded yesterday and Wati three times already
+	invitees.invitees[4].LastRemindedAt = &yesterday
+	invitees.invitees[5].Reminders, invitees.invitees[5].LastRemindedAt = 3, &lastWeek
+
+	// Joko responds before the campaign runs, which the outbox tells
+	assert.NoError(uc.Publish(ctx, rsvp.Event{Type: rsvp.EventRsvpCreated, Rsvp: rsvp.Rsvp{Name: "Joko", Address: "Solo"}, CreatedAt: time.Now()}))
+	assert.ElementsMatch([]string{"Siti", "Joko"}, invitees.responded())
+
+	_, err = uc.CreateReminderCampaign(ctx, rsvp.ReminderCampaignRequest{Channel: rsvp.ReminderSMS}, "admin:budi")
+	assert.Equal("channel", err.(response.CustomError).Field)
+	_, err = uc.CreateReminderCampaign(ctx, rsvp.ReminderCampaignRequest{Channel: rsvp.ReminderEmail, Message: "{{.Name"}, "admin:budi")
+	assert.Equal("message", err.(response.CustomError).Field)
+
+	c, err := uc.CreateReminderCampaign(ctx, rsvp.ReminderCampaignRequest{Channel: rsvp.ReminderEmail}, "admin:budi")
+	assert.NoError(err)
+	assert.Equal(usecase.DefaultReminderMessage, c.Message)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	uc.RunReminderWorker(ctx)
+
+	var campaign *rsvp.ReminderCampaign
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if campaign, err = uc.GetReminderCampaign(ctx, c.ID.Hex()); campaign.Status == rsvp.CampaignDone {
+			break
+		}
+	}
+
+	assert.NoError(err)
+	assert.Equal(rsvp.CampaignDone, campaign.Status)
+	assert.Equal(5, campaign.Total)
+	// Rudi has no email, Andi and Wati were reminded enough
+	assert.Equal(1, campaign.Sent)
+	assert.Equal(1, campaign.Failed)
+	assert.Equal(3, campaign.Skipped)
+	assert.Equal([]string{"Hi Budi, we have not received your RSVP yet. We would love to know whether you can come!"}, sender.texts)
+
+	history, err := uc.GetReminders(ctx, created[0].ID.Hex())
+	assert.NoError(err)
+	assert.Len(history, 1)
+	assert.Equal(rsvp.ReminderSent, history[0].Status)
+	assert.Equal("budi@example.com", history[0].To)
+
+	history, err = uc.GetReminders(ctx, created[3].ID.Hex())
+	assert.NoError(err)
+	assert.Equal(rsvp.ReminderFailed, history[0].Status)
+	assert.Equal("mailbox unavailable", history[0].Error)
+
+	pending, err := uc.GetInvitees(ctx, &rsvp.Parameter{Limit: constants.NoLimit}, true)
+	assert.NoError(err)
+	assert.Equal(1, pending.Data[0].Reminders)
+
+	_, err = uc.CancelReminderCampaign(ctx, c.ID.Hex())
+	assert.Equal(response.CampaignFinishedError, err)
+}
+
+func TestCancelRunningReminderCampaign(t *testing.T) {
+	assert := assert.New(t)
+
+	invitees := &fakeInviteeRepo{}
+	reminders := &fakeReminderRepo{}
+	sender := &fakeReminderSender{}
+	uc := usecase.NewReminderUsecase(&usecase.AccessProvider{RsvpRepo: &fakeRsvpRepo{}, InviteeRepo: invitees, ReminderRepo: reminders}, usecase.ReminderOption{
+		Senders:      map[string]rsvp.ReminderSender{rsvp.ReminderEmail: sender},
+		PollInterval: 10 * time.Millisecond,
+		Lease:        time.Minute,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := uc.CreateInvitees(ctx, []rsvp.Invitee{
+		{Name: "Budi", Email: "budi@example.com"},
+		{Name: "Siti", Email: "siti@example.com"},
+		{Name: "Rudi", Email: "rudi@example.com"},
+	})
+	assert.NoError(err)
+	c, err := uc.CreateReminderCampaign(ctx, rsvp.ReminderCampaignRequest{Channel: rsvp.ReminderEmail}, "admin:budi")
+	assert.NoError(err)
+
+	// the campaign is cancelled while the first reminder is sent
+	sender.sent = func() {
+		sender.sent = nil
+		_, err := uc.CancelReminderCampaign(ctx, c.ID.Hex())
+		assert.NoError(err)
+	}
+	uc.RunReminderWorker(ctx)
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if reminders.campaign(0).Sent+reminders.campaign(0).Failed+reminders.campaign(0).Skipped > 0 {
+			break
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	campaign := reminders.campaign(0)
+	assert.Equal(rsvp.CampaignCancelled, campaign.Status)
+	// the reminder under way is not counted once the campaign is cancelled
+	assert.Equal(0, campaign.Sent)
+	assert.Len(sender.texts, 1)
+}
+
+func TestTakeOverReminderCampaign(t *testing.T) {
+	assert := assert.New(t)
+
+	invitees := &fakeInviteeRepo{}
+	reminders := &fakeReminderRepo{}
+	sender := &fakeReminderSender{}
+	uc := usecase.NewReminderUsecase(&usecase.AccessProvider{RsvpRepo: &fakeRsvpRepo{}, InviteeRepo: invitees, ReminderRepo: reminders}, usecase.ReminderOption{
+		Senders:      map[string]rsvp.ReminderSender{rsvp.ReminderEmail: sender},
+		PollInterval: 10 * time.Millisecond,
+		Lease:        time.Minute,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	created, err := uc.CreateInvitees(ctx, []rsvp.Invitee{
+		{Name: "Budi", Email: "budi@example.com"},
+		{Name: "Siti", Email: "siti@example.com"},
+	})
+	assert.NoError(err)
+
+	// a stopped instance reminded Budi, and one still running holds the other campaign
+	expired, held := time.Now().Add(-time.Second), time.Now().Add(time.Hour)
+	stale := rsvp.ReminderCampaign{ID: bson.NewObjectId(), Channel: rsvp.ReminderEmail, Message: "Hi {{.Name}}", Status: rsvp.CampaignRunning, Total: 2, Sent: 1, LeaseUntil: &expired}
+	busy := rsvp.ReminderCampaign{ID: bson.NewObjectId(), Channel: rsvp.ReminderEmail, Message: "Hello {{.Name}}", Status: rsvp.CampaignRunning, LeaseUntil: &held}
+	reminders.campaigns = []rsvp.ReminderCampaign{stale, busy}
+	reminders.reminders = []rsvp.Reminder{{CampaignID: stale.ID, InviteeID: created[0].ID, Status: rsvp.ReminderSent}}
+
+	uc.RunReminderWorker(ctx)
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if reminders.campaign(0).Status == rsvp.CampaignDone {
+			break
+		}
+	}
+
+	campaign := reminders.campaign(0)
+	assert.Equal(rsvp.CampaignDone, campaign.Status)
+	assert.Equal(2, campaign.Total)
+	assert.Equal(1, campaign.Sent)
+	assert.Equal(1, campaign.Skipped)
+	assert.Equal([]string{"Hi Siti"}, sender.texts)
+	assert.Equal(rsvp.CampaignRunning, reminders.campaign(1).Status)
+}
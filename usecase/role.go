@@ -0,0 +1,132 @@
+package usecase
+
+import (
+	"context"
+	"regexp"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,31}$`)
+
+type roleUsecase struct {
+	*AccessProvider
+}
+
+func NewRoleUsecase(pvd *AccessProvider) rsvp.RoleUsecase {
+	return &roleUsecase{pvd}
+}
+
+func (ru *roleUsecase) GetRole(ctx context.Context, name string) (*rsvp.Role, error) {
+	return getRole(ctx, ru.RoleRepo, name)
+}
+
+func (ru *roleUsecase) GetRoles(ctx context.Context) ([]*rsvp.Role, error) {
+	custom, err := ru.RoleRepo.GetRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make([]*rsvp.Role, 0, len(rsvp.BuiltInRoles)+len(custom))
+	for i := range rsvp.BuiltInRoles {
+		role := rsvp.BuiltInRoles[i]
+		roles = append(roles, &role)
+	}
+
+	return append(roles, custom...), nil
+}
+
+// SaveRole creates or replaces a custom role
+func (ru *roleUsecase) SaveRole(ctx context.Context, role rsvp.Role) (*rsvp.Role, error) {
+	if !roleNamePattern.MatchString(role.Name) || isBuiltInRole(role.Name) {
+		err := response.BadRequestError
+		err.Field = "name"
+		return nil, err
+	}
+
+	for _, p := range role.Permissions {
+		if !isGrantable(p) {
+			err := response.BadRequestError
+			err.Field = "permissions"
+			return nil, err
+		}
+	}
+
+	role.BuiltIn = false
+	if err := ru.RoleRepo.SaveRole(ctx, role); err != nil {
+		return nil, err
+	}
+
+	return &role, nil
+}
+
+func (ru *roleUsecase) DeleteRole(ctx context.Context, name string) error {
+	if isBuiltInRole(name) {
+		err := response.BadRequestError
+		err.Field = "name"
+		return err
+	}
+
+	count, err := ru.AdminUserRepo.CountAdminUsersWithRole(ctx, name)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return response.RoleInUseError
+	}
+
+	return ru.RoleRepo.DeleteRole(ctx, name)
+}
+
+// Authorize returns response.UserUnauthorizedError unless the role of admin grants p.
+// Admins created before roles existed have no role and keep full access as owner.
+func (ru *roleUsecase) Authorize(ctx context.Context, admin *rsvp.AdminUser, p rsvp.Permission) error {
+	name := admin.Role
+	if name == "" {
+		name = rsvp.RoleOwner
+	}
+
+	role, err := getRole(ctx, ru.RoleRepo, name)
+	if err == response.NotFoundError {
+		return response.UserUnauthorizedError
+	}
+	if err != nil {
+		return err
+	}
+
+	if !role.Allows(p) {
+		return response.UserUnauthorizedError
+	}
+
+	return nil
+}
+
+func getRole(ctx context.Context, repo rsvp.RoleRepo, name string) (*rsvp.Role, error) {
+	for i := range rsvp.BuiltInRoles {
+		if rsvp.BuiltInRoles[i].Name == name {
+			role := rsvp.BuiltInRoles[i]
+			return &role, nil
+		}
+	}
+
+	return repo.GetRole(ctx, name)
+}
+
+func isBuiltInRole(name string) bool {
+	for _, role := range rsvp.BuiltInRoles {
+		if role.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isGrantable(p rsvp.Permission) bool {
+	for _, granted := range rsvp.Permissions {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
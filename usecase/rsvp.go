@@ -1,20 +1,36 @@
 package usecase
 
 import (
-	"bytes"
 	"context"
 	"encoding/csv"
-	"fmt"
-	"strconv"
+	"io"
+	"net/mail"
 	"strings"
 	"time"
 
 	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/enumeration"
+	"github.com/faris-arifiansyah/fws-rsvp/xlsx"
 )
 
+const csvFlushRows = 100
+
 // AccessProvider are collections of provider that used by usecase
 type AccessProvider struct {
-	RsvpRepo rsvp.RsvpRepo
+	RsvpRepo        rsvp.RsvpRepo
+	AdminUserRepo   rsvp.AdminUserRepo
+	SessionRepo     rsvp.SessionRepo
+	RoleRepo        rsvp.RoleRepo
+	APIKeyRepo      rsvp.APIKeyRepo
+	AuditRepo       rsvp.AuditRepo
+	ExportJobRepo   rsvp.ExportJobRepo
+	FileStore       rsvp.FileStore
+	WebhookRepo     rsvp.WebhookRepo
+	OutboxRepo      rsvp.OutboxRepo
+	DigestRepo      rsvp.DigestRepo
+	InviteeRepo     rsvp.InviteeRepo
+	ReminderRepo    rsvp.ReminderRepo
+	EventStreamRepo rsvp.EventStreamRepo
 }
 
 type rsvpUsecase struct {
@@ -25,54 +41,222 @@ func NewRsvpUsecase(pvd *AccessProvider) rsvp.Usecase {
 	return &rsvpUsecase{pvd}
 }
 
+// CreateRsvp saves rp, whose email is optional and must be a bare address when given.
+// Its message is never approved, only an admin approves messages.
 func (ru *rsvpUsecase) CreateRsvp(ctx context.Context, rp rsvp.Rsvp) (rsvp.Rsvp, error) {
+	rp.ApprovedAt = nil
+	rp.Email = strings.TrimSpace(rp.Email)
+	if !validEmail(rp.Email) {
+		return rp, badRequest("email")
+	}
+
 	return ru.RsvpRepo.CreateRsvp(ctx, rp)
 }
 
+// validEmail reports whether email is empty or a bare address, without a display name
+func validEmail(email string) bool {
+	if email == "" {
+		return true
+	}
+
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
 func (ru *rsvpUsecase) GetRsvps(ctx context.Context, p *rsvp.Parameter) (*rsvp.RsvpResult, error) {
 	p.Sort = ru.GetValidSortField(p.Sort)
 
 	return ru.RsvpRepo.GetRsvps(ctx, p)
 }
 
-func (ru *rsvpUsecase) WriteRsvpsCsv(ctx context.Context, p *rsvp.Parameter) (*rsvp.File, error) {
-	rsvpResult, err := ru.GetRsvps(ctx, p)
+func (ru *rsvpUsecase) DeleteRsvp(ctx context.Context, id string) error {
+	_, err := ru.RsvpRepo.DeleteRsvp(ctx, id)
+	return err
+}
+
+// SetMessageApproval leaves an RSVP already approved or withdrawn as it is,
+// and an RSVP without a message cannot be approved
+func (ru *rsvpUsecase) SetMessageApproval(ctx context.Context, id string, approved bool) (rsvp.Rsvp, error) {
+	rp, err := ru.RsvpRepo.GetRsvp(ctx, id)
+	if err != nil {
+		return rsvp.Rsvp{}, err
+	}
+	if approved && strings.TrimSpace(rp.Message) == "" {
+		return *rp, badRequest("message")
+	}
+	if approved == (rp.ApprovedAt != nil) {
+		return *rp, nil
+	}
+
+	var approvedAt *time.Time
+	if approved {
+		now := time.Now()
+		approvedAt = &now
+	}
+
+	return ru.RsvpRepo.SetRsvpApproval(ctx, id, approvedAt)
+}
+
+func (ru *rsvpUsecase) GetAttendanceSummary(ctx context.Context) (*rsvp.AttendanceSummary, error) {
+	return ru.RsvpRepo.CountRsvpsByAttendance(ctx)
+}
+
+// WriteRsvpsCsv streams RSVPs as CSV rows laid out by opt to w, flushing every
+// csvFlushRows rows so a large export starts downloading immediately.
+// Nothing is written when opt is invalid.
+func (ru *rsvpUsecase) WriteRsvpsCsv(ctx context.Context, p *rsvp.Parameter, opt rsvp.ExportOption, w io.Writer) error {
+	layout, err := newExportLayout(opt)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	file := new(rsvp.File)
-	buffer := &bytes.Buffer{}
-	writer := csv.NewWriter(buffer)
+	p.Sort = ru.GetValidSortField(p.Sort)
+
+	iter := ru.RsvpRepo.IterateRsvps(ctx, p)
+	defer iter.Close()
+
+	if layout.bom {
+		if _, err = io.WriteString(w, utf8BOM); err != nil {
+			return err
+		}
+	}
 
-	records := [][]string{}
+	writer := csv.NewWriter(w)
+	writer.Comma = layout.delimiter
+	flusher, canFlush := w.(interface{ Flush() })
 
 	//Set Header
-	records = append(records, []string{"Number", "Name", "Address", "Attend", "Message", "Created Date"})
+	if err = writer.Write(layout.header()); err != nil {
+		return err
+	}
 
-	for i, item := range rsvpResult.Data {
-		var record = []string{}
-		record = append(record, strconv.Itoa(i+1))
-		record = append(record, item.Name)
-		record = append(record, item.Address)
-		record = append(record, item.Attend.String())
-		record = append(record, item.Message)
-		record = append(record, item.CreatedAt.Format("2006-01-02 15-04-05"))
+	var item rsvp.Rsvp
+	for i := 1; iter.Next(&item); i++ {
+		if err = writer.Write(layout.record(i, &item)); err != nil {
+			return err
+		}
 
-		records = append(records, record)
+		if i%csvFlushRows == 0 {
+			writer.Flush()
+			if canFlush {
+				flusher.Flush()
+			}
+		}
+
+		item = rsvp.Rsvp{}
 	}
 
-	if err := writer.WriteAll(records); err != nil {
-		return nil, err
+	if err = iter.Err(); err != nil {
+		return err
 	}
 
-	timestamp := time.Now().Format("2006-01-02_15-04-05")
-	filename := fmt.Sprintf("rsvp-%s.csv", timestamp)
+	writer.Flush()
+	return writer.Error()
+}
 
-	file.Content = buffer.Bytes()
-	file.Name = filename
+// WriteRsvpsXlsx streams RSVPs laid out by opt as a workbook to w. Besides the sheet
+// of RSVPs it has a sheet of the messages and a summary of attendance totals.
+// Nothing is written when opt is invalid.
+func (ru *rsvpUsecase) WriteRsvpsXlsx(ctx context.Context, p *rsvp.Parameter, opt rsvp.ExportOption, w io.Writer) error {
+	layout, err := newExportLayout(opt)
+	if err != nil {
+		return err
+	}
+
+	p.Sort = ru.GetValidSortField(p.Sort)
+
+	xw := xlsx.NewWriter(w)
+	flusher, canFlush := w.(interface{ Flush() })
+	sheetOption := func(l *exportLayout) xlsx.SheetOption {
+		return xlsx.SheetOption{Widths: l.widths(), FreezeHeader: true, AutoFilter: true}
+	}
+
+	var summary rsvp.AttendanceSummary
+	err = ru.writeSheet(ctx, p, xw, layout.label("rsvps"), sheetOption(layout), layout.header(), func(sh *xlsx.Sheet, i int, item *rsvp.Rsvp) error {
+		summary.Total++
+		switch item.Attend {
+		case enumeration.AttendanceTypeYes:
+			summary.Yes++
+		case enumeration.AttendanceTypeNo:
+			summary.No++
+		case enumeration.AttendanceTypeMaybe:
+			summary.Maybe++
+		}
+
+		if err := sh.WriteRow(layout.values(i, item)...); err != nil {
+			return err
+		}
+		if i%csvFlushRows == 0 {
+			if err := sh.Flush(); err != nil {
+				return err
+			}
+			if canFlush {
+				flusher.Flush()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	messages := layout.with(messageExportColumns)
+	number := 0
+	err = ru.writeSheet(ctx, p, xw, layout.label("messages"), sheetOption(messages), messages.header(), func(sh *xlsx.Sheet, i int, item *rsvp.Rsvp) error {
+		if strings.TrimSpace(item.Message) == "" {
+			return nil
+		}
+		number++
+		return sh.WriteRow(messages.values(number, item)...)
+	})
+	if err != nil {
+		return err
+	}
+
+	sh, err := xw.NewSheet(layout.label("summary"), xlsx.SheetOption{Widths: []float64{20, 12}, FreezeHeader: true})
+	if err != nil {
+		return err
+	}
+	rows := [][]interface{}{
+		{enumeration.AttendanceTypeYes.Label(layout.language), summary.Yes},
+		{enumeration.AttendanceTypeNo.Label(layout.language), summary.No},
+		{enumeration.AttendanceTypeMaybe.Label(layout.language), summary.Maybe},
+		{layout.label("total"), summary.Total},
+	}
+	if err = sh.WriteHeader(layout.label("attendance"), layout.label("count")); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err = sh.WriteRow(row...); err != nil {
+			return err
+		}
+	}
+
+	return xw.Close()
+}
+
+// writeSheet starts a sheet with header and passes every RSVP matching p to write
+func (ru *rsvpUsecase) writeSheet(ctx context.Context, p *rsvp.Parameter, xw *xlsx.Writer, name string, opt xlsx.SheetOption, header []string, write func(sh *xlsx.Sheet, i int, item *rsvp.Rsvp) error) error {
+	sh, err := xw.NewSheet(name, opt)
+	if err != nil {
+		return err
+	}
+	if err = sh.WriteHeader(header...); err != nil {
+		return err
+	}
+
+	iter := ru.RsvpRepo.IterateRsvps(ctx, p)
+	defer iter.Close()
+
+	var item rsvp.Rsvp
+	for i := 1; iter.Next(&item); i++ {
+		if err = write(sh, i, &item); err != nil {
+			return err
+		}
+		item = rsvp.Rsvp{}
+	}
 
-	return file, nil
+	return iter.Err()
 }
 
 func (ru *rsvpUsecase) GetValidSortField(sf string) string {
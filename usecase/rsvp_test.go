@@ -0,0 +1,404 @@
+package usecase_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/enumeration"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/faris-arifiansyah/fws-rsvp/usecase"
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRsvpRepo serves RSVPs from memory
+type fakeRsvpRepo struct {
+	rsvp.RsvpRepo
+	data    []rsvp.Rsvp
+	batches int
+}
+
+type fakeRsvpIterator struct {
+	data []rsvp.Rsvp
+}
+
+func (it *fakeRsvpIterator) Next(rp *rsvp.Rsvp) bool {
+	if len(it.data) == 0 {
+		return false
+	}
+	*rp, it.data = it.data[0], it.data[1:]
+	return true
+}
+
+func (it *fakeRsvpIterator) Err() error   { return nil }
+func (it *fakeRsvpIterator) Close() error { return nil }
+
+func (fr *fakeRsvpRepo) IterateRsvps(ctx context.Context, p *rsvp.Parameter) rsvp.RsvpIterator {
+	return &fakeRsvpIterator{fr.data}
+}
+
+func (fr *fakeRsvpRepo) UpsertRsvp(ctx context.Context, rp rsvp.Rsvp) (bool, error) {
+	for i := range fr.data {
+		if fr.data[i].ID == rp.ID {
+			fr.data[i] = rp
+			return false, nil
+		}
+	}
+
+	fr.data = append(fr.data, rp)
+	return true, nil
+}
+
+func (fr *fakeRsvpRepo) CreateRsvps(ctx context.Context, rps []rsvp.Rsvp) error {
+	fr.batches++
+	fr.data = append(fr.data, rps...)
+	return nil
+}
+
+func (fr *fakeRsvpRepo) ExistsRsvp(ctx context.Context, name string, address string) (bool, error) {
+	for _, rp := range fr.data {
+		if rp.Name == name && rp.Address == address {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (fr *fakeRsvpRepo) ExistsRsvpWithEmail(ctx context.Context, email string) (bool, error) {
+	for _, rp := range fr.data {
+		if rp.Email == email {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (fr *fakeRsvpRepo) GetRsvp(ctx context.Context, id string) (*rsvp.Rsvp, error) {
+	for _, rp := range fr.data {
+		if rp.ID.Hex() == id {
+			return &rp, nil
+		}
+	}
+	return nil, response.NotFoundError
+}
+
+func (fr *fakeRsvpRepo) GetRsvpsCreatedBetween(ctx context.Context, from time.Time, to time.Time) ([]rsvp.Rsvp, error) {
+	var rps []rsvp.Rsvp
+	for _, rp := range fr.data {
+		if !rp.CreatedAt.Before(from) && rp.CreatedAt.Before(to) {
+			rps = append(rps, rp)
+		}
+	}
+	return rps, nil
+}
+
+func (fr *fakeRsvpRepo) CreateRsvp(ctx context.Context, rp rsvp.Rsvp) (rsvp.Rsvp, error) {
+	rp.ID = bson.NewObjectId()
+	fr.data = append(fr.data, rp)
+	return rp, nil
+}
+
+func TestCreateRsvpEmail(t *testing.T) {
+	tests := []struct {
+		email    string
+		expected string
+		field    string
+	}{
+		{"", "", ""},
+		{" budi@example.com ", "budi@example.com", ""},
+		{"budi", "", "email"},
+		{"Budi <budi@example.com>", "", "email"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.email, func(t *testing.T) {
+			uc := usecase.NewRsvpUsecase(&usecase.AccessProvider{RsvpRepo: &fakeRsvpRepo{}})
+
+			rp, err := uc.CreateRsvp(context.Background(), rsvp.Rsvp{Name: "Budi", Address: "Jakarta", Email: test.email})
+			if test.field != "" {
+				assert.Equal(t, test.field, err.(response.CustomError).Field)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, rp.Email)
+		})
+	}
+}
+
+func TestWriteRsvpsCsv(t *testing.T) {
+	assert := assert.New(t)
+
+	createdAt := time.Date(2019, 8, 17, 10, 30, 0, 0, time.UTC)
+	uc := usecase.NewRsvpUsecase(&usecase.AccessProvider{
+		RsvpRepo: &fakeRsvpRepo{data: []rsvp.Rsvp{
+			{Name: "Budi", Address: "Jakarta", Attend: enumeration.AttendanceTypeYes, Message: "Selamat, ya!", CreatedAt: createdAt},
+			{Name: "Siti", Address: "Bandung", Attend: enumeration.AttendanceTypeMaybe, CreatedAt: createdAt},
+		}},
+	})
+
+	buffer := &bytes.Buffer{}
+	err := uc.WriteRsvpsCsv(context.Background(), &rsvp.Parameter{Limit: -1}, rsvp.ExportOption{}, buffer)
+
+	assert.NoError(err)
+	assert.Equal("Number,Name,Address,Attend,Message,Created Date\n"+
+		"1,Budi,Jakarta,Yes,\"Selamat, ya!\",2019-08-17 10-30-00\n"+
+		"2,Siti,Bandung,Maybe,,2019-08-17 10-30-00\n", buffer.String())
+}
+
+func TestWriteRsvpsCsvWithOption(t *testing.T) {
+	assert := assert.New(t)
+
+	createdAt := time.Date(2019, 8, 17, 10, 30, 0, 0, time.UTC)
+	repo := &fakeRsvpRepo{data: []rsvp.Rsvp{
+		{Name: "Budi", Address: "Jakarta", Attend: enumeration.AttendanceTypeYes, Message: "Selamat; ya!", CreatedAt: createdAt},
+		{Name: "Siti", Address: "Bandung", Attend: enumeration.AttendanceTypeNo, CreatedAt: createdAt},
+	}}
+
+	testCases := []struct {
+		opt           rsvp.ExportOption
+		expectedCsv   string
+		expectedField string
+	}{
+		{
+			opt: rsvp.ExportOption{
+				Columns:    []string{"name", "attend", "created_at"},
+				Language:   "id",
+				TimeZone:   "Asia/Jakarta",
+				DateFormat: "id",
+				Delimiter:  "semicolon",
+				BOM:        true,
+			},
+			expectedCsv: "\ufeffNama;Kehadiran;Tanggal Dibuat\n" +
+				"Budi;Ya;17/08/2019 17:30\n" +
+				"Siti;Tidak;17/08/2019 17:30\n",
+		},
+		{
+			opt: rsvp.ExportOption{
+				Columns:    []string{"message", "number"},
+				DateFormat: "2006",
+				Delimiter:  ";",
+			},
+			expectedCsv: "Message;Number\n" +
+				"\"Selamat; ya!\";1\n" +
+				";2\n",
+		},
+		{
+			opt:           rsvp.ExportOption{Columns: []string{"name", "phone"}},
+			expectedField: "columns",
+		},
+		{
+			opt:           rsvp.ExportOption{Language: "fr"},
+			expectedField: "lang",
+		},
+		{
+			opt:           rsvp.ExportOption{TimeZone: "Mars/Olympus"},
+			expectedField: "tz",
+		},
+		{
+			opt:           rsvp.ExportOption{Delimiter: "::"},
+			expectedField: "delimiter",
+		},
+	}
+
+	for _, tc := range testCases {
+		uc := usecase.NewRsvpUsecase(&usecase.AccessProvider{RsvpRepo: repo})
+
+		buffer := &bytes.Buffer{}
+		err := uc.WriteRsvpsCsv(context.Background(), &rsvp.Parameter{Limit: -1}, tc.opt, buffer)
+
+		if tc.expectedField != "" {
+			assert.Equal(tc.expectedField, err.(response.CustomError).Field)
+			assert.Empty(buffer.String())
+			continue
+		}
+
+		assert.NoError(err)
+		assert.Equal(tc.expectedCsv, buffer.String())
+	}
+}
+
+func TestWriteRsvpsXlsx(t *testing.T) {
+	assert := assert.New(t)
+
+	createdAt := time.Date(2019, 8, 17, 10, 30, 0, 0, time.UTC)
+	uc := usecase.NewRsvpUsecase(&usecase.AccessProvider{
+		RsvpRepo: &fakeRsvpRepo{data: []rsvp.Rsvp{
+			{Name: "Budi", Address: "0812345", Attend: enumeration.AttendanceTypeYes, Message: "Selamat!", CreatedAt: createdAt},
+			{Name: "Siti", Address: "Bandung", Attend: enumeration.AttendanceTypeNo, CreatedAt: createdAt},
+		}},
+	})
+
+	buffer := &bytes.Buffer{}
+	err := uc.WriteRsvpsXlsx(context.Background(), &rsvp.Parameter{Limit: -1}, rsvp.ExportOption{Language: "id", TimeZone: "Asia/Jakarta"}, buffer)
+	assert.NoError(err)
+
+	zr, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	assert.NoError(err)
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		assert.NoError(err)
+		b, err := ioutil.ReadAll(rc)
+		assert.NoError(err)
+		rc.Close()
+		files[f.Name] = string(b)
+	}
+
+	testCases := []struct {
+		name     string
+		expected []string
+	}{
+		{
+			name: "xl/workbook.xml",
+			expected: []string{
+				`<sheet name="RSVP" sheetId="1" r:id="rId1"/>`,
+				`<sheet name="Ucapan" sheetId="2" r:id="rId2"/>`,
+				`<sheet name="Ringkasan" sheetId="3" r:id="rId3"/>`,
+			},
+		},
+		{
+			name: "xl/worksheets/sheet1.xml",
+			expected: []string{
+				`<t xml:space="preserve">Kehadiran</t>`,
+				`<c r="A2" s="0"><v>1</v></c>`,
+				`<c r="C2" s="0" t="inlineStr"><is><t xml:space="preserve">0812345</t></is></c>`,
+				`<t xml:space="preserve">Ya</t>`,
+				`<c r="F2" s="2"><v>43694.729166666664</v></c>`,
+				`<autoFilter ref="A1:F3"/>`,
+			},
+		},
+		{
+			name: "xl/worksheets/sheet2.xml",
+			expected: []string{
+				`<t xml:space="preserve">Selamat!</t>`,
+				`<autoFilter ref="A1:C2"/>`,
+			},
+		},
+		{
+			name: "xl/worksheets/sheet3.xml",
+			expected: []string{
+				`<c r="B2" s="0"><v>1</v></c>`,
+				`<c r="B3" s="0"><v>1</v></c>`,
+				`<c r="B4" s="0"><v>0</v></c>`,
+				`<c r="B5" s="0"><v>2</v></c>`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		for _, expected := range tc.expected {
+			assert.Contains(files[tc.name], expected, tc.name)
+		}
+	}
+	assert.NotContains(files["xl/worksheets/sheet2.xml"], "Siti")
+}
+
+func TestRsvpsNdjsonRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	createdAt := time.Date(2019, 8, 17, 10, 30, 0, 0, time.UTC)
+	source := &fakeRsvpRepo{data: []rsvp.Rsvp{
+		{ID: bson.ObjectIdHex("5d5799d1e1382315c4a0a001"), Name: "Budi", Address: "Jakarta", Attend: enumeration.AttendanceTypeYes, Message: "Selamat!", CreatedAt: createdAt},
+		{ID: bson.ObjectIdHex("5d5799d1e1382315c4a0a002"), Name: "Siti", Address: "Bandung", Attend: enumeration.AttendanceTypeMaybe, CreatedAt: createdAt},
+	}}
+
+	buffer := &bytes.Buffer{}
+	err := usecase.NewRsvpUsecase(&usecase.AccessProvider{RsvpRepo: source}).WriteRsvpsNdjson(context.Background(), &rsvp.Parameter{Limit: -1}, buffer)
+	assert.NoError(err)
+	assert.Equal(`{"id":"5d5799d1e1382315c4a0a001","name":"Budi","address":"Jakarta","attend":1,"message":"Selamat!","created_at":"2019-08-17T10:30:00Z"}`+"\n"+
+		`{"id":"5d5799d1e1382315c4a0a002","name":"Siti","address":"Bandung","attend":2,"message":"","created_at":"2019-08-17T10:30:00Z"}`+"\n", buffer.String())
+
+	backup := buffer.String() + "\n" +
+		`{"name":"Tanpa ID","address":"Bogor","attend":1,"created_at":"2019-08-17T10:30:00Z"}` + "\n" +
+		`{"id":"5d5799d1e1382315c4a0a003","name":"Rudi","address":"Depok","attend":7,"created_at":"2019-08-17T10:30:00Z"}` + "\n" +
+		`{"id":` + "\n"
+
+	target := &fakeRsvpRepo{data: []rsvp.Rsvp{source.data[0]}}
+	uc := usecase.NewRsvpUsecase(&usecase.AccessProvider{RsvpRepo: target})
+
+	result, err := uc.ImportRsvpsNdjson(context.Background(), strings.NewReader(backup))
+	assert.NoError(err)
+	assert.Equal(&rsvp.ImportResult{
+		Created: 1,
+		Updated: 1,
+		Skipped: 1,
+		Failed:  3,
+		Errors: []rsvp.ImportError{
+			{Row: 4, Field: "id", Message: response.BadRequestError.Message},
+			{Row: 5, Field: "attend", Message: response.BadRequestError.Message},
+			{Row: 6, Message: "invalid JSON"},
+		},
+	}, result)
+	assert.Equal(source.data, target.data)
+
+	// restoring the same backup again changes nothing
+	result, err = uc.ImportRsvpsNdjson(context.Background(), strings.NewReader(buffer.String()))
+	assert.NoError(err)
+	assert.Equal(2, result.Updated)
+	assert.Len(target.data, 2)
+}
+
+func TestImportRsvpsCsv(t *testing.T) {
+	assert := assert.New(t)
+
+	file := "\ufeffNo;Nama Tamu;Alamat;Kehadiran;Ucapan;Tanggal Dibuat\n" +
+		"1;Budi;Jakarta;Ya;Selamat!;17/08/2019 17:30\n" +
+		"2;Siti;Bandung;mungkin;;\n" +
+		";;;;;\n" +
+		"3;budi;jakarta;Yes;Dobel;\n" +
+		"4;Rudi;Depok;Tidak;;\n" +
+		"5;;Bogor;Yes;;\n" +
+		"6;Dewi;Bekasi;hadir;;\n" +
+		"7;Tono;Bogor;No;;kemarin\n"
+
+	testCases := []struct {
+		dryRun          bool
+		expectedBatches int
+		expectedData    int
+	}{
+		{dryRun: true, expectedBatches: 0, expectedData: 1},
+		{dryRun: false, expectedBatches: 1, expectedData: 3},
+	}
+
+	for _, tc := range testCases {
+		repo := &fakeRsvpRepo{data: []rsvp.Rsvp{{Name: "Rudi", Address: "Depok"}}}
+		uc := usecase.NewRsvpUsecase(&usecase.AccessProvider{RsvpRepo: repo})
+
+		result, err := uc.ImportRsvpsCsv(context.Background(), strings.NewReader(file), rsvp.ImportOption{
+			Mapping:    map[string]string{"nama tamu": "name"},
+			TimeZone:   "Asia/Jakarta",
+			DateFormat: "id",
+			Delimiter:  ";",
+			DryRun:     tc.dryRun,
+		})
+
+		assert.NoError(err)
+		assert.Equal(tc.dryRun, result.DryRun)
+		assert.Equal(2, result.Created)
+		assert.Equal(3, result.Skipped)
+		assert.Equal(3, result.Failed)
+		assert.Equal([]int{7, 8, 9}, []int{result.Errors[0].Row, result.Errors[1].Row, result.Errors[2].Row})
+		assert.Equal([]string{"name", "attend", "created_at"}, []string{result.Errors[0].Field, result.Errors[1].Field, result.Errors[2].Field})
+		assert.Equal(tc.expectedBatches, repo.batches)
+		assert.Len(repo.data, tc.expectedData)
+
+		if !tc.dryRun {
+			assert.Equal("Budi", repo.data[1].Name)
+			assert.Equal(enumeration.AttendanceTypeYes, repo.data[1].Attend)
+			assert.True(time.Date(2019, 8, 17, 10, 30, 0, 0, time.UTC).Equal(repo.data[1].CreatedAt))
+			assert.Equal(enumeration.AttendanceTypeMaybe, repo.data[2].Attend)
+		}
+	}
+
+	uc := usecase.NewRsvpUsecase(&usecase.AccessProvider{RsvpRepo: &fakeRsvpRepo{}})
+	_, err := uc.ImportRsvpsCsv(context.Background(), strings.NewReader("Name,Message\nBudi,Hai\n"), rsvp.ImportOption{})
+	assert.Equal("header", err.(response.CustomError).Field)
+}
@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+)
+
+func (au *adminUsecase) Login(ctx context.Context, cred rsvp.AdminCredential) (rsvp.Session, error) {
+	user, err := au.Authenticate(ctx, cred)
+	if err != nil {
+		return rsvp.Session{}, err
+	}
+
+	return au.createSession(ctx, user.Username)
+}
+
+func (au *adminUsecase) Logout(ctx context.Context, token string) error {
+	err := au.SessionRepo.DeleteSession(ctx, token)
+	if err == response.NotFoundError { //Already expired or revoked
+		return nil
+	}
+
+	return err
+}
+
+// RefreshSession issues a new session for the owner of token and revokes token
+func (au *adminUsecase) RefreshSession(ctx context.Context, token string) (rsvp.Session, error) {
+	user, err := au.AuthenticateToken(ctx, token)
+	if err != nil {
+		return rsvp.Session{}, err
+	}
+
+	s, err := au.createSession(ctx, user.Username)
+	if err != nil {
+		return rsvp.Session{}, err
+	}
+
+	return s, au.SessionRepo.DeleteSession(ctx, token)
+}
+
+// AuthenticateToken returns the enabled admin signed in with token. The store expires
+// sessions on its own, an expired one it still returns is refused all the same.
+func (au *adminUsecase) AuthenticateToken(ctx context.Context, token string) (*rsvp.AdminUser, error) {
+	s, err := au.SessionRepo.GetSession(ctx, token)
+	if err == response.NotFoundError {
+		return nil, response.UserUnauthorizedError
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !s.ExpiresAt.After(time.Now()) {
+		return nil, response.UserUnauthorizedError
+	}
+
+	user, err := au.AdminUserRepo.GetAdminUser(ctx, s.Username)
+	if err == response.NotFoundError {
+		return nil, response.UserUnauthorizedError
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Disabled {
+		return nil, response.UserUnauthorizedError
+	}
+
+	return user, nil
+}
+
+func (au *adminUsecase) createSession(ctx context.Context, username string) (rsvp.Session, error) {
+	token, err := newToken()
+	if err != nil {
+		return rsvp.Session{}, err
+	}
+
+	s := rsvp.Session{
+		Token:     token,
+		Username:  username,
+		ExpiresAt: time.Now().Add(au.opt.SessionTTL),
+	}
+
+	return s, au.SessionRepo.CreateSession(ctx, s)
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
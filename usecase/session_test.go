@@ -0,0 +1,169 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/faris-arifiansyah/fws-rsvp/usecase"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLogin(t *testing.T) {
+	assert := assert.New(t)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("budi-password"), bcrypt.MinCost)
+	sessions := newFakeSessionRepo()
+	uc := usecase.NewAdminUsecase(&usecase.AccessProvider{
+		AdminUserRepo: newFakeAdminUserRepo(rsvp.AdminUser{Username: "budi", PasswordHash: string(hash)}),
+		SessionRepo:   sessions,
+	}, usecase.AdminOption{SessionTTL: time.Hour})
+	ctx := context.Background()
+
+	_, err := uc.Login(ctx, rsvp.AdminCredential{Username: "budi", Password: "wrong-password"})
+	assert.Equal(response.UserUnauthorizedError, err)
+	assert.Empty(sessions.data)
+
+	s, err := uc.Login(ctx, rsvp.AdminCredential{Username: "budi", Password: "budi-password"})
+	assert.NoError(err)
+	assert.Len(s.Token, 43)
+	assert.Equal("budi", s.Username)
+	assert.WithinDuration(time.Now().Add(time.Hour), s.ExpiresAt, time.Minute)
+	assert.Equal(s, sessions.data[s.Token])
+
+	other, err := uc.Login(ctx, rsvp.AdminCredential{Username: "budi", Password: "budi-password"})
+	assert.NoError(err)
+	assert.NotEqual(s.Token, other.Token)
+
+	user, err := uc.AuthenticateToken(ctx, s.Token)
+	assert.NoError(err)
+	assert.Equal("budi", user.Username)
+}
+
+func TestAuthenticateToken(t *testing.T) {
+	users := newFakeAdminUserRepo(
+		rsvp.AdminUser{Username: "budi"},
+		rsvp.AdminUser{Username: "siti", Disabled: true},
+	)
+	sessions := newFakeSessionRepo()
+	later := time.Now().Add(time.Hour)
+	sessions.data["valid"] = rsvp.Session{Token: "valid", Username: "budi", ExpiresAt: later}
+	sessions.data["expired"] = rsvp.Session{Token: "expired", Username: "budi", ExpiresAt: time.Now().Add(-time.Second)}
+	sessions.data["disabled"] = rsvp.Session{Token: "disabled", Username: "siti", ExpiresAt: later}
+	sessions.data["deleted"] = rsvp.Session{Token: "deleted", Username: "rudi", ExpiresAt: later}
+	uc := newAdminUsecase(users, sessions)
+
+	tests := []struct {
+		token    string
+		expected string
+	}{
+		{"valid", "budi"},
+		{"unknown", ""},
+		{"expired", ""},
+		{"disabled", ""},
+		{"deleted", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.token, func(t *testing.T) {
+			user, err := uc.AuthenticateToken(context.Background(), test.token)
+			if test.expected == "" {
+				assert.Equal(t, response.UserUnauthorizedError, err)
+				assert.Nil(t, user)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, user.Username)
+		})
+	}
+}
+
+func TestLogout(t *testing.T) {
+	assert := assert.New(t)
+
+	sessions := newFakeSessionRepo()
+	later := time.Now().Add(time.Hour)
+	sessions.data["token"] = rsvp.Session{Token: "token", Username: "budi", ExpiresAt: later}
+	sessions.data["other"] = rsvp.Session{Token: "other", Username: "budi", ExpiresAt: later}
+	uc := newAdminUsecase(newFakeAdminUserRepo(rsvp.AdminUser{Username: "budi"}), sessions)
+	ctx := context.Background()
+
+	assert.NoError(uc.Logout(ctx, "token"))
+	_, err := uc.AuthenticateToken(ctx, "token")
+	assert.Equal(response.UserUnauthorizedError, err)
+	// only the session logged out of ends
+	_, err = uc.AuthenticateToken(ctx, "other")
+	assert.NoError(err)
+
+	// logging out of an expired or revoked session succeeds
+	assert.NoError(uc.Logout(ctx, "token"))
+}
+
+func TestRefreshSession(t *testing.T) {
+	assert := assert.New(t)
+
+	sessions := newFakeSessionRepo()
+	sessions.data["token"] = rsvp.Session{Token: "token", Username: "budi", ExpiresAt: time.Now().Add(time.Minute)}
+	uc := usecase.NewAdminUsecase(&usecase.AccessProvider{
+		AdminUserRepo: newFakeAdminUserRepo(rsvp.AdminUser{Username: "budi"}),
+		SessionRepo:   sessions,
+	}, usecase.AdminOption{SessionTTL: time.Hour})
+	ctx := context.Background()
+
+	s, err := uc.RefreshSession(ctx, "token")
+	assert.NoError(err)
+	assert.WithinDuration(time.Now().Add(time.Hour), s.ExpiresAt, time.Minute)
+
+	_, err = uc.AuthenticateToken(ctx, "token")
+	assert.Equal(response.UserUnauthorizedError, err)
+	_, err = uc.AuthenticateToken(ctx, s.Token)
+	assert.NoError(err)
+
+	_, err = uc.RefreshSession(ctx, "token")
+	assert.Equal(response.UserUnauthorizedError, err)
+}
+
+func TestSessionsEndWithTheirAdmin(t *testing.T) {
+	owner := rsvp.AdminUser{Username: "owner", Role: rsvp.RoleOwner}
+	budi := rsvp.AdminUser{Username: "budi", Role: rsvp.RoleEditor}
+
+	tests := []struct {
+		name   string
+		change func(uc rsvp.AdminUsecase) error
+	}{
+		{"password change", func(uc rsvp.AdminUsecase) error {
+			return uc.ChangePassword(context.Background(), &owner, rsvp.PasswordChangeRequest{Username: "budi", Password: "a-new-password"})
+		}},
+		{"disable", func(uc rsvp.AdminUsecase) error {
+			return uc.SetAdminUserDisabled(context.Background(), &owner, "budi", true)
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sessions := newFakeSessionRepo()
+			later := time.Now().Add(time.Hour)
+			sessions.data["budi-1"] = rsvp.Session{Token: "budi-1", Username: "budi", ExpiresAt: later}
+			sessions.data["budi-2"] = rsvp.Session{Token: "budi-2", Username: "budi", ExpiresAt: later}
+			sessions.data["owner"] = rsvp.Session{Token: "owner", Username: "owner", ExpiresAt: later}
+			uc := newAdminUsecase(newFakeAdminUserRepo(owner, budi), sessions)
+
+			assert.NoError(t, test.change(uc))
+
+			// every session of budi ends, the other admins stay signed in
+			assert.Equal(t, []string{"owner"}, tokens(sessions))
+		})
+	}
+}
+
+func tokens(sessions *fakeSessionRepo) []string {
+	var tokens []string
+	for token := range sessions.data {
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
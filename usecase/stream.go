@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"context"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+)
+
+type streamUsecase struct {
+	*AccessProvider
+}
+
+func NewStreamUsecase(pvd *AccessProvider) rsvp.StreamUsecase {
+	return &streamUsecase{pvd}
+}
+
+// Publish streams rsvp.created and rsvp.updated events, ignoring the rest
+func (su *streamUsecase) Publish(ctx context.Context, e rsvp.Event) error {
+	if e.Type != rsvp.EventRsvpCreated && e.Type != rsvp.EventRsvpUpdated {
+		return nil
+	}
+
+	return su.EventStreamRepo.PublishEvent(ctx, e)
+}
+
+// StreamEvents subscribes before reading the replay buffer so no event published
+// in between is lost, and drops the live events that were already replayed
+func (su *streamUsecase) StreamEvents(ctx context.Context, lastID string) (<-chan rsvp.Event, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	live, err := su.EventStreamRepo.SubscribeEvents(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	var replay []rsvp.Event
+	if lastID != "" {
+		if replay, err = su.EventStreamRepo.ReplayEvents(ctx, lastID); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+
+	events := make(chan rsvp.Event)
+	go func() {
+		defer close(events)
+		defer cancel()
+
+		replayed := make(map[string]bool, len(replay))
+		for _, e := range replay {
+			replayed[e.ID] = true
+			select {
+			case events <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		for e := range live {
+			if replayed[e.ID] {
+				continue
+			}
+			select {
+			case events <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
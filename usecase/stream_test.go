@@ -0,0 +1,126 @@
+package usecase_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/usecase"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeEventStream buffers every event and sends it to the subscribers in memory
+type fakeEventStream struct {
+	sync.Mutex
+	buffer      []rsvp.Event
+	subscribers []chan rsvp.Event
+}
+
+func (fs *fakeEventStream) PublishEvent(ctx context.Context, e rsvp.Event) error {
+	fs.Lock()
+	defer fs.Unlock()
+
+	fs.buffer = append(fs.buffer, e)
+	for _, ch := range fs.subscribers {
+		ch <- e
+	}
+	return nil
+}
+
+func (fs *fakeEventStream) ReplayEvents(ctx context.Context, lastID string) ([]rsvp.Event, error) {
+	fs.Lock()
+	defer fs.Unlock()
+
+	for i, e := range fs.buffer {
+		if e.ID == lastID {
+			return append([]rsvp.Event(nil), fs.buffer[i+1:]...), nil
+		}
+	}
+	return append([]rsvp.Event(nil), fs.buffer...), nil
+}
+
+func (fs *fakeEventStream) LastEventID(ctx context.Context) (string, error) {
+	fs.Lock()
+	defer fs.Unlock()
+
+	if len(fs.buffer) == 0 {
+		return "", nil
+	}
+	return fs.buffer[len(fs.buffer)-1].ID, nil
+}
+
+func (fs *fakeEventStream) SubscribeEvents(ctx context.Context) (<-chan rsvp.Event, error) {
+	fs.Lock()
+	defer fs.Unlock()
+
+	ch := make(chan rsvp.Event, 10)
+	fs.subscribers = append(fs.subscribers, ch)
+	go func() {
+		<-ctx.Done()
+		fs.Lock()
+		defer fs.Unlock()
+		for i, sub := range fs.subscribers {
+			if sub == ch {
+				fs.subscribers = append(fs.subscribers[:i], fs.subscribers[i+1:]...)
+			}
+		}
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// receive returns the IDs of the next n events
+func receive(t *testing.T, events <-chan rsvp.Event, n int) []string {
+	var ids []string
+	for len(ids) < n {
+		select {
+		case e := <-events:
+			ids = append(ids, e.ID)
+		case <-time.After(time.Second):
+			t.Fatalf("received %v, want %d events", ids, n)
+		}
+	}
+	return ids
+}
+
+func TestStreamEvents(t *testing.T) {
+	assert := assert.New(t)
+
+	stream := &fakeEventStream{}
+	uc := usecase.NewStreamUsecase(&usecase.AccessProvider{EventStreamRepo: stream})
+	ctx := context.Background()
+
+	assert.NoError(uc.Publish(ctx, rsvp.Event{ID: "1", Type: rsvp.EventRsvpCreated}))
+	assert.NoError(uc.Publish(ctx, rsvp.Event{ID: "2", Type: rsvp.EventRsvpDeleted}))
+	assert.NoError(uc.Publish(ctx, rsvp.Event{ID: "3", Type: rsvp.EventRsvpUpdated}))
+	// deleted events are not streamed
+	assert.Len(stream.buffer, 2)
+
+	live, cancelLive := context.WithCancel(ctx)
+	fresh, err := uc.StreamEvents(live, "")
+	assert.NoError(err)
+
+	resumed, cancelResumed := context.WithCancel(ctx)
+	defer cancelResumed()
+	caughtUp, err := uc.StreamEvents(resumed, "1")
+	assert.NoError(err)
+
+	assert.NoError(uc.Publish(ctx, rsvp.Event{ID: "4", Type: rsvp.EventRsvpCreated}))
+
+	assert.Equal([]string{"4"}, receive(t, fresh, 1))
+	// the buffer already held 3 when resuming, so it is not sent twice
+	assert.Equal([]string{"3", "4"}, receive(t, caughtUp, 2))
+
+	cancelLive()
+	for range fresh {
+	}
+	stream.Lock()
+	assert.Len(stream.subscribers, 1)
+	stream.Unlock()
+
+	unknown, err := uc.StreamEvents(resumed, "gone")
+	assert.NoError(err)
+	assert.Equal([]string{"1", "3", "4"}, receive(t, unknown, 3))
+}
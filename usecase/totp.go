@@ -0,0 +1,161 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/faris-arifiansyah/fws-rsvp/totp"
+)
+
+const recoveryCodeCount = 10
+
+// EnrollTOTP generates a new TOTP secret for username.
+// It is not required at login until it is confirmed with ConfirmTOTP.
+func (au *adminUsecase) EnrollTOTP(ctx context.Context, username string) (rsvp.TOTPEnrollment, error) {
+	user, err := au.AdminUserRepo.GetAdminUser(ctx, normalizeUsername(username))
+	if err != nil {
+		return rsvp.TOTPEnrollment{}, err
+	}
+
+	if user.TOTPEnabled {
+		return rsvp.TOTPEnrollment{}, response.TOTPAlreadyEnabledError
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return rsvp.TOTPEnrollment{}, err
+	}
+
+	user.TOTPSecret = secret
+	if err = au.AdminUserRepo.UpdateAdminUser(ctx, *user); err != nil {
+		return rsvp.TOTPEnrollment{}, err
+	}
+
+	return rsvp.TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(au.opt.TOTPIssuer, user.Username, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables TOTP once code proves the authenticator app holds the enrolled secret.
+// It returns recovery codes, which are shown only this once.
+func (au *adminUsecase) ConfirmTOTP(ctx context.Context, username string, code string) ([]string, error) {
+	user, err := au.AdminUserRepo.GetAdminUser(ctx, normalizeUsername(username))
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TOTPEnabled {
+		return nil, response.TOTPAlreadyEnabledError
+	}
+
+	step, ok := totp.ValidateStep(user.TOTPSecret, code, time.Now())
+	if user.TOTPSecret == "" || !ok {
+		err := response.BadRequestError
+		err.Field = "otp"
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		if codes[i], err = newRecoveryCode(); err != nil {
+			return nil, err
+		}
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	user.TOTPEnabled = true
+	// the code confirming TOTP cannot log in
+	user.TOTPLastStep = step
+	user.RecoveryCodes = hashes
+	if err = au.AdminUserRepo.UpdateAdminUser(ctx, *user); err != nil {
+		return nil, err
+	}
+
+	return codes, au.SessionRepo.DeleteSessions(ctx, user.Username)
+}
+
+// DisableTOTP turns TOTP off after verifying a current TOTP or recovery code
+func (au *adminUsecase) DisableTOTP(ctx context.Context, username string, code string) error {
+	user, err := au.AdminUserRepo.GetAdminUser(ctx, normalizeUsername(username))
+	if err != nil {
+		return err
+	}
+
+	if !user.TOTPEnabled {
+		return nil
+	}
+
+	if err = au.verifySecondFactor(ctx, user, code); err != nil {
+		return err
+	}
+
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPLastStep = 0
+	user.RecoveryCodes = nil
+
+	return au.AdminUserRepo.UpdateAdminUser(ctx, *user)
+}
+
+// verifySecondFactor accepts a TOTP code of a later time step than the last one accepted, as
+// RFC 6238 section 5.2 recommends so a code cannot be replayed, or consumes one of the recovery codes of user
+func (au *adminUsecase) verifySecondFactor(ctx context.Context, user *rsvp.AdminUser, code string) error {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return response.OTPRequiredError
+	}
+
+	if step, ok := totp.ValidateStep(user.TOTPSecret, code, time.Now()); ok {
+		err := au.AdminUserRepo.AdvanceTOTPStep(ctx, user.Username, step)
+		if err == response.NotFoundError {
+			return response.UserUnauthorizedError
+		}
+		if err == nil {
+			user.TOTPLastStep = step
+		}
+		return err
+	}
+
+	hash := hashRecoveryCode(code)
+	for i, recoveryCode := range user.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(recoveryCode)) == 1 {
+			// the code is removed only if it is still there, so it cannot be used twice at once
+			err := au.AdminUserRepo.UseRecoveryCode(ctx, user.Username, recoveryCode)
+			if err == response.NotFoundError {
+				return response.UserUnauthorizedError
+			}
+			if err == nil {
+				user.RecoveryCodes = append(user.RecoveryCodes[:i], user.RecoveryCodes[i+1:]...)
+			}
+			return err
+		}
+	}
+
+	return response.UserUnauthorizedError
+}
+
+// newRecoveryCode returns a code formatted as xxxxx-xxxxx
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(code))))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,102 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/faris-arifiansyah/fws-rsvp/totp"
+	"github.com/faris-arifiansyah/fws-rsvp/usecase"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestTOTPCodeIsUsedOnce(t *testing.T) {
+	assert := assert.New(t)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("budi-password"), bcrypt.MinCost)
+	users := newFakeAdminUserRepo(rsvp.AdminUser{Username: "budi", PasswordHash: string(hash)})
+	uc := newAdminUsecase(users, newFakeSessionRepo())
+	ctx := context.Background()
+
+	enrollment, err := uc.EnrollTOTP(ctx, "budi")
+	assert.NoError(err)
+	code := func(steps int) string {
+		c, err := totp.Code(enrollment.Secret, time.Now().Add(time.Duration(steps*totp.Period)*time.Second))
+		assert.NoError(err)
+		return c
+	}
+	login := func(otp string) error {
+		_, err := uc.Authenticate(ctx, rsvp.AdminCredential{Username: "budi", Password: "budi-password", OTP: otp})
+		return err
+	}
+
+	current := code(0)
+	recoveryCodes, err := uc.ConfirmTOTP(ctx, "budi", current)
+	assert.NoError(err)
+
+	// the code confirming TOTP is spent
+	assert.Equal(response.UserUnauthorizedError, login(current))
+
+	next := code(1)
+	assert.NoError(login(next))
+	assert.Equal(response.UserUnauthorizedError, login(next))
+	// so is every code before it
+	assert.Equal(response.UserUnauthorizedError, login(code(-1)))
+
+	assert.NoError(login(recoveryCodes[0]))
+	assert.Equal(response.UserUnauthorizedError, login(recoveryCodes[0]))
+}
+
+// staleAdminUserRepo reads the admin users as they were when it was made,
+// as two logins racing each other both do
+type staleAdminUserRepo struct {
+	*fakeAdminUserRepo
+	snapshot map[string]rsvp.AdminUser
+}
+
+func (sr staleAdminUserRepo) GetAdminUser(ctx context.Context, username string) (*rsvp.AdminUser, error) {
+	u, ok := sr.snapshot[username]
+	if !ok {
+		return nil, response.NotFoundError
+	}
+	u.RecoveryCodes = append([]string{}, u.RecoveryCodes...)
+	return &u, nil
+}
+
+func TestRecoveryCodeIsUsedOnceAtOnce(t *testing.T) {
+	assert := assert.New(t)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("budi-password"), bcrypt.MinCost)
+	users := newFakeAdminUserRepo(rsvp.AdminUser{Username: "budi", PasswordHash: string(hash)})
+	uc := newAdminUsecase(users, newFakeSessionRepo())
+	ctx := context.Background()
+
+	enrollment, err := uc.EnrollTOTP(ctx, "budi")
+	assert.NoError(err)
+	current, err := totp.Code(enrollment.Secret, time.Now())
+	assert.NoError(err)
+	recoveryCodes, err := uc.ConfirmTOTP(ctx, "budi", current)
+	assert.NoError(err)
+
+	snapshot := map[string]rsvp.AdminUser{}
+	for username, u := range users.data {
+		snapshot[username] = u
+	}
+	stale := usecase.NewAdminUsecase(&usecase.AccessProvider{
+		AdminUserRepo: staleAdminUserRepo{users, snapshot},
+		SessionRepo:   newFakeSessionRepo(),
+		RoleRepo:      fakeRoleRepo{},
+	}, usecase.AdminOption{})
+	login := func(otp string) error {
+		_, err := stale.Authenticate(ctx, rsvp.AdminCredential{Username: "budi", Password: "budi-password", OTP: otp})
+		return err
+	}
+
+	assert.NoError(login(recoveryCodes[0]))
+	assert.Equal(response.UserUnauthorizedError, login(recoveryCodes[0]))
+
+	assert.Len(users.data["budi"].RecoveryCodes, len(recoveryCodes)-1)
+}
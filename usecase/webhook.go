@@ -0,0 +1,384 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+)
+
+// Headers of a webhook request
+const (
+	HeaderWebhookEvent     = "X-FWS-Event"
+	HeaderWebhookDelivery  = "X-FWS-Delivery"
+	HeaderWebhookTimestamp = "X-FWS-Timestamp"
+	HeaderWebhookSignature = "X-FWS-Signature"
+)
+
+// maxWebhookResponse is the most of a webhook response read before the connection is reused
+const maxWebhookResponse = 64 * 1024
+
+// WebhookOption configures webhook usecase
+type WebhookOption struct {
+	// MaxAttempts is the number of attempts before a delivery fails
+	MaxAttempts int
+	// Backoff is the delay before the first retry, doubled on every further retry up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Timeout is how long a webhook may take to respond
+	Timeout time.Duration
+	// PollInterval is how often idle workers look for due deliveries
+	PollInterval time.Duration
+	// AllowPrivateTargets lets webhooks reach loopback, link-local and private addresses
+	AllowPrivateTargets bool
+}
+
+// errPrivateTarget is returned when a webhook resolves to an address it may not reach
+var errPrivateTarget = errors.New("webhook target is a private address")
+
+type webhookUsecase struct {
+	*AccessProvider
+	opt    WebhookOption
+	client *http.Client
+	wake   chan struct{}
+}
+
+func NewWebhookUsecase(pvd *AccessProvider, opt WebhookOption) rsvp.WebhookUsecase {
+	return &webhookUsecase{
+		AccessProvider: pvd,
+		opt:            opt,
+		client:         newWebhookClient(opt),
+		wake:           make(chan struct{}, 1),
+	}
+}
+
+// newWebhookClient returns a client that does not follow redirects and, unless
+// opt.AllowPrivateTargets, refuses to connect to private addresses. The address is checked
+// when connecting, so a host name resolving to a private address is refused too.
+func newWebhookClient(opt WebhookOption) *http.Client {
+	dialer := &net.Dialer{Timeout: opt.Timeout}
+	if !opt.AllowPrivateTargets {
+		dialer.Control = func(network string, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivateIP(ip) {
+				return errPrivateTarget
+			}
+			return nil
+		}
+	}
+
+	return &http.Client{
+		Timeout: opt.Timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: opt.Timeout,
+			MaxIdleConnsPerHost: 2,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// privateNetworks are the private, shared and "this network" address ranges webhooks may not reach,
+// along with NAT64 addresses, which embed any IPv4 address
+var privateNetworks = func() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range []string{"0.0.0.0/8", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "fc00::/7", "64:ff9b::/96"} {
+		_, n, _ := net.ParseCIDR(cidr)
+		nets = append(nets, n)
+	}
+	return nets
+}()
+
+// isPrivateIP tells whether ip is a loopback, link-local, private, unspecified or multicast address
+func isPrivateIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast() {
+		return true
+	}
+	for _, n := range privateNetworks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (wu *webhookUsecase) CreateWebhook(ctx context.Context, req rsvp.WebhookRequest, createdBy string) (rsvp.Webhook, error) {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return rsvp.Webhook{}, badRequest("url")
+	}
+	if !wu.opt.AllowPrivateTargets {
+		host := strings.ToLower(u.Hostname())
+		if ip := net.ParseIP(host); host == "localhost" || strings.HasSuffix(host, ".localhost") || (ip != nil && isPrivateIP(ip)) {
+			return rsvp.Webhook{}, badRequest("url")
+		}
+	}
+
+	if len(req.Events) == 0 {
+		return rsvp.Webhook{}, badRequest("events")
+	}
+	for _, e := range req.Events {
+		if !isEventType(e) {
+			return rsvp.Webhook{}, badRequest("events")
+		}
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		b := make([]byte, 32)
+		if _, err = rand.Read(b); err != nil {
+			return rsvp.Webhook{}, err
+		}
+		secret = hex.EncodeToString(b)
+	}
+
+	return wu.WebhookRepo.CreateWebhook(ctx, rsvp.Webhook{
+		URL:       req.URL,
+		Secret:    secret,
+		Events:    req.Events,
+		CreatedBy: createdBy,
+	})
+}
+
+// GetWebhooks returns every webhook without its secret
+func (wu *webhookUsecase) GetWebhooks(ctx context.Context) ([]*rsvp.Webhook, error) {
+	webhooks, err := wu.WebhookRepo.GetWebhooks(ctx)
+	for _, wh := range webhooks {
+		wh.Secret = ""
+	}
+
+	return webhooks, err
+}
+
+func (wu *webhookUsecase) DeleteWebhook(ctx context.Context, id string) error {
+	return wu.WebhookRepo.DeleteWebhook(ctx, id)
+}
+
+func (wu *webhookUsecase) GetWebhookDeliveries(ctx context.Context, webhookID string, p *rsvp.Parameter) (*rsvp.WebhookDeliveryResult, error) {
+	if _, err := wu.WebhookRepo.GetWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	return wu.WebhookRepo.GetWebhookDeliveries(ctx, webhookID, p)
+}
+
+// Redeliver queues the payload of a delivery again as a new delivery with its own attempts.
+// The event ID is kept, so receivers can tell they have seen it.
+func (wu *webhookUsecase) Redeliver(ctx context.Context, webhookID string, deliveryID string) (rsvp.WebhookDelivery, error) {
+	d, err := wu.WebhookRepo.GetWebhookDelivery(ctx, webhookID, deliveryID)
+	if err != nil {
+		return rsvp.WebhookDelivery{}, err
+	}
+
+	now := time.Now()
+	redelivery, err := wu.WebhookRepo.CreateWebhookDelivery(ctx, rsvp.WebhookDelivery{
+		WebhookID:     d.WebhookID,
+		EventID:       d.EventID,
+		EventType:     d.EventType,
+		Payload:       d.Payload,
+		Status:        rsvp.DeliveryPending,
+		Attempts:      []rsvp.WebhookAttempt{},
+		NextAttemptAt: &now,
+		RedeliveryOf:  &d.ID,
+	})
+	if err != nil {
+		return rsvp.WebhookDelivery{}, err
+	}
+
+	wu.notify()
+	return redelivery, nil
+}
+
+// Publish queues a delivery of e to every webhook subscribed to its type
+func (wu *webhookUsecase) Publish(ctx context.Context, e rsvp.Event) error {
+	webhooks, err := wu.WebhookRepo.GetWebhooksForEvent(ctx, e.Type)
+	if err != nil || len(webhooks) == 0 {
+		return err
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	// the outbox publishes e again when a handler failed, so deliveries queued already are kept
+	now := time.Now()
+	for _, wh := range webhooks {
+		err = wu.WebhookRepo.UpsertWebhookDelivery(ctx, rsvp.WebhookDelivery{
+			WebhookID:     wh.ID,
+			EventID:       e.ID,
+			EventType:     e.Type,
+			Payload:       string(payload),
+			Status:        rsvp.DeliveryPending,
+			Attempts:      []rsvp.WebhookAttempt{},
+			NextAttemptAt: &now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	wu.notify()
+	return nil
+}
+
+// RunWebhookWorkers starts workers goroutines delivering due events, stopping when ctx is done.
+// Deliveries pending on restart are picked up again.
+func (wu *webhookUsecase) RunWebhookWorkers(ctx context.Context, workers int) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			ticker := time.NewTicker(wu.opt.PollInterval)
+			defer ticker.Stop()
+
+			for {
+				for ctx.Err() == nil && wu.deliverNext(ctx) {
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-wu.wake:
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+}
+
+// notify wakes a worker waiting for deliveries
+func (wu *webhookUsecase) notify() {
+	select {
+	case wu.wake <- struct{}{}:
+	default:
+	}
+}
+
+// deliverNext attempts the delivery due the longest, reporting whether there was one
+func (wu *webhookUsecase) deliverNext(ctx context.Context) bool {
+	now := time.Now()
+	// the lease outlives an attempt, so a delivery left by a crashed worker is attempted again
+	d, err := wu.WebhookRepo.ClaimWebhookDelivery(ctx, now, now.Add(2*wu.opt.Timeout))
+	if err == response.NotFoundError {
+		return false
+	}
+	if err != nil {
+		log.Printf("webhook: claim failed: %v", err)
+		return false
+	}
+
+	wu.attempt(ctx, d)
+
+	if err = wu.WebhookRepo.UpdateWebhookDelivery(ctx, *d); err != nil {
+		log.Printf("webhook delivery %s: update failed: %v", d.ID.Hex(), err)
+	}
+
+	return true
+}
+
+// attempt posts d to its webhook once and schedules the next attempt when it failed
+func (wu *webhookUsecase) attempt(ctx context.Context, d *rsvp.WebhookDelivery) {
+	a := rsvp.WebhookAttempt{At: time.Now()}
+
+	wh, err := wu.WebhookRepo.GetWebhook(ctx, d.WebhookID.Hex())
+	if err == nil {
+		a.ResponseCode, err = wu.post(ctx, wh, d)
+	}
+	a.Duration = time.Since(a.At)
+	if err == response.NotFoundError {
+		a.Error = "webhook has been deleted"
+	} else if err != nil {
+		a.Error = err.Error()
+	}
+
+	d.Attempts = append(d.Attempts, a)
+	d.NextAttemptAt = nil
+
+	switch {
+	case err == nil && a.ResponseCode >= 200 && a.ResponseCode < 300:
+		d.Status = rsvp.DeliverySucceeded
+	case err == response.NotFoundError || len(d.Attempts) >= wu.opt.MaxAttempts:
+		d.Status = rsvp.DeliveryFailed
+	default:
+		next := time.Now().Add(backoff(wu.opt.Backoff, wu.opt.MaxBackoff, len(d.Attempts)))
+		d.NextAttemptAt = &next
+	}
+}
+
+// backoff returns the delay after the given number of failed attempts,
+// starting at base and doubling up to max
+func backoff(base time.Duration, max time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// post sends the payload of d to wh, signed with its secret, and returns the response status
+func (wu *webhookUsecase) post(ctx context.Context, wh *rsvp.Webhook, d *rsvp.WebhookDelivery) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, wh.URL, bytes.NewReader([]byte(d.Payload)))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "fws-rsvp-webhook")
+	req.Header.Set(HeaderWebhookEvent, d.EventType)
+	req.Header.Set(HeaderWebhookDelivery, d.ID.Hex())
+	req.Header.Set(HeaderWebhookTimestamp, timestamp)
+	req.Header.Set(HeaderWebhookSignature, SignWebhook(wh.Secret, timestamp, []byte(d.Payload)))
+
+	resp, err := wu.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxWebhookResponse))
+
+	return resp.StatusCode, nil
+}
+
+// SignWebhook returns the signature header of a webhook request, "sha256=" followed by the hex
+// encoded HMAC-SHA256 of the timestamp header, a dot and the body, keyed with the webhook secret
+func SignWebhook(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func isEventType(eventType string) bool {
+	for _, t := range rsvp.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,346 @@
+package usecase_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	rsvp "github.com/faris-arifiansyah/fws-rsvp"
+	"github.com/faris-arifiansyah/fws-rsvp/enumeration"
+	"github.com/faris-arifiansyah/fws-rsvp/response"
+	"github.com/faris-arifiansyah/fws-rsvp/usecase"
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeWebhookRepo keeps webhooks and their deliveries in memory
+type fakeWebhookRepo struct {
+	sync.Mutex
+	webhooks   []rsvp.Webhook
+	deliveries []rsvp.WebhookDelivery
+}
+
+func (fr *fakeWebhookRepo) CreateWebhook(ctx context.Context, wh rsvp.Webhook) (rsvp.Webhook, error) {
+	fr.Lock()
+	defer fr.Unlock()
+
+	wh.ID = bson.NewObjectId()
+	fr.webhooks = append(fr.webhooks, wh)
+	return wh, nil
+}
+
+func (fr *fakeWebhookRepo) GetWebhook(ctx context.Context, id string) (*rsvp.Webhook, error) {
+	fr.Lock()
+	defer fr.Unlock()
+
+	for _, wh := range fr.webhooks {
+		if wh.ID.Hex() == id {
+			return &wh, nil
+		}
+	}
+	return nil, response.NotFoundError
+}
+
+func (fr *fakeWebhookRepo) GetWebhooks(ctx context.Context) ([]*rsvp.Webhook, error) {
+	fr.Lock()
+	defer fr.Unlock()
+
+	var webhooks []*rsvp.Webhook
+	for _, wh := range fr.webhooks {
+		wh := wh
+		webhooks = append(webhooks, &wh)
+	}
+	return webhooks, nil
+}
+
+func (fr *fakeWebhookRepo) GetWebhooksForEvent(ctx context.Context, eventType string) ([]*rsvp.Webhook, error) {
+	fr.Lock()
+	defer fr.Unlock()
+
+	var webhooks []*rsvp.Webhook
+	for _, wh := range fr.webhooks {
+		for _, e := range wh.Events {
+			if e == eventType {
+				wh := wh
+				webhooks = append(webhooks, &wh)
+			}
+		}
+	}
+	return webhooks, nil
+}
+
+func (fr *fakeWebhookRepo) DeleteWebhook(ctx context.Context, id string) error {
+	return nil
+}
+
+func (fr *fakeWebhookRepo) CreateWebhookDelivery(ctx context.Context, d rsvp.WebhookDelivery) (rsvp.WebhookDelivery, error) {
+	fr.Lock()
+	defer fr.Unlock()
+
+	d.ID = bson.NewObjectId()
+	fr.deliveries = append(fr.deliveries, d)
+	return d, nil
+}
+
+func (fr *fakeWebhookRepo) UpsertWebhookDelivery(ctx context.Context, d rsvp.WebhookDelivery) error {
+	fr.Lock()
+	defer fr.Unlock()
+
+	for _, queued := range fr.deliveries {
+		if queued.WebhookID == d.WebhookID && queued.EventID == d.EventID && queued.RedeliveryOf == nil {
+			return nil
+		}
+	}
+	d.ID = bson.NewObjectId()
+	fr.deliveries = append(fr.deliveries, d)
+	return nil
+}
+
+func (fr *fakeWebhookRepo) GetWebhookDelivery(ctx context.Context, webhookID string, id string) (*rsvp.WebhookDelivery, error) {
+	fr.Lock()
+	defer fr.Unlock()
+
+	for _, d := range fr.deliveries {
+		if d.WebhookID.Hex() == webhookID && d.ID.Hex() == id {
+			return &d, nil
+		}
+	}
+	return nil, response.NotFoundError
+}
+
+func (fr *fakeWebhookRepo) GetWebhookDeliveries(ctx context.Context, webhookID string, p *rsvp.Parameter) (*rsvp.WebhookDeliveryResult, error) {
+	fr.Lock()
+	defer fr.Unlock()
+
+	result := new(rsvp.WebhookDeliveryResult)
+	for _, d := range fr.deliveries {
+		if d.WebhookID.Hex() == webhookID {
+			d := d
+			result.Data = append(result.Data, &d)
+			result.Total++
+		}
+	}
+	return result, nil
+}
+
+func (fr *fakeWebhookRepo) ClaimWebhookDelivery(ctx context.Context, now time.Time, lease time.Time) (*rsvp.WebhookDelivery, error) {
+	fr.Lock()
+	defer fr.Unlock()
+
+	for i, d := range fr.deliveries {
+		if d.Status == rsvp.DeliveryPending && d.NextAttemptAt != nil && !d.NextAttemptAt.After(now) {
+			fr.deliveries[i].NextAttemptAt = &lease
+			return &d, nil
+		}
+	}
+	return nil, response.NotFoundError
+}
+
+func (fr *fakeWebhookRepo) UpdateWebhookDelivery(ctx context.Context, d rsvp.WebhookDelivery) error {
+	fr.Lock()
+	defer fr.Unlock()
+
+	for i := range fr.deliveries {
+		if fr.deliveries[i].ID == d.ID {
+			fr.deliveries[i] = d
+		}
+	}
+	return nil
+}
+
+func TestCreateWebhook(t *testing.T) {
+	tests := []struct {
+		name  string
+		req   rsvp.WebhookRequest
+		field string
+	}{
+		{"relative url", rsvp.WebhookRequest{URL: "/hook", Events: []string{rsvp.EventRsvpCreated}}, "url"},
+		{"ftp url", rsvp.WebhookRequest{URL: "ftp://example.com/hook", Events: []string{rsvp.EventRsvpCreated}}, "url"},
+		{"localhost url", rsvp.WebhookRequest{URL: "http://localhost:8080/hook", Events: []string{rsvp.EventRsvpCreated}}, "url"},
+		{"loopback url", rsvp.WebhookRequest{URL: "http://127.0.0.1/hook", Events: []string{rsvp.EventRsvpCreated}}, "url"},
+		{"private url", rsvp.WebhookRequest{URL: "http://10.0.0.5/hook", Events: []string{rsvp.EventRsvpCreated}}, "url"},
+		{"link-local url", rsvp.WebhookRequest{URL: "http://169.254.169.254/latest/meta-data", Events: []string{rsvp.EventRsvpCreated}}, "url"},
+		{"ipv6 loopback url", rsvp.WebhookRequest{URL: "http://[::1]/hook", Events: []string{rsvp.EventRsvpCreated}}, "url"},
+		{"this network url", rsvp.WebhookRequest{URL: "http://0.1.2.3/hook", Events: []string{rsvp.EventRsvpCreated}}, "url"},
+		{"nat64 url", rsvp.WebhookRequest{URL: "http://[64:ff9b::a00:5]/hook", Events: []string{rsvp.EventRsvpCreated}}, "url"},
+		{"no events", rsvp.WebhookRequest{URL: "https://example.com/hook"}, "events"},
+		{"unknown event", rsvp.WebhookRequest{URL: "https://example.com/hook", Events: []string{"rsvp.approved"}}, "events"},
+		{"valid", rsvp.WebhookRequest{URL: "https://example.com/hook", Events: []string{rsvp.EventRsvpCreated}}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			uc := usecase.NewWebhookUsecase(&usecase.AccessProvider{WebhookRepo: &fakeWebhookRepo{}}, usecase.WebhookOption{})
+
+			wh, err := uc.CreateWebhook(context.Background(), test.req, "admin:budi")
+			if test.field != "" {
+				assert.Equal(t, test.field, err.(response.CustomError).Field)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Len(t, wh.Secret, 64)
+
+			webhooks, err := uc.GetWebhooks(context.Background())
+			assert.NoError(t, err)
+			assert.Empty(t, webhooks[0].Secret)
+		})
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	assert := assert.New(t)
+
+	var mu sync.Mutex
+	var received []*http.Request
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, r)
+		bodies = append(bodies, body)
+		if len(received) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	repo := &fakeWebhookRepo{}
+	webhookUc := usecase.NewWebhookUsecase(&usecase.AccessProvider{WebhookRepo: repo}, usecase.WebhookOption{
+		MaxAttempts:  3,
+		Backoff:      10 * time.Millisecond,
+		MaxBackoff:   time.Second,
+		Timeout:      time.Second,
+		PollInterval: 10 * time.Millisecond,
+		// the test server listens on loopback
+		AllowPrivateTargets: true,
+	})
+
+	wh, err := webhookUc.CreateWebhook(context.Background(), rsvp.WebhookRequest{
+		URL:    server.URL,
+		Secret: "s3cret",
+		Events: []string{rsvp.EventRsvpCreated},
+	}, "admin:budi")
+	assert.NoError(err)
+
+	rp := rsvp.Rsvp{ID: bson.NewObjectId(), Name: "Budi", Address: "Jakarta", Attend: enumeration.AttendanceTypeYes}
+	assert.NoError(webhookUc.Publish(context.Background(), rsvp.Event{ID: "4e3f1b9c", Type: rsvp.EventRsvpCreated, Rsvp: rp}))
+	// published again by the outbox, the event is queued once
+	assert.NoError(webhookUc.Publish(context.Background(), rsvp.Event{ID: "4e3f1b9c", Type: rsvp.EventRsvpCreated, Rsvp: rp}))
+	// the webhook is not subscribed to deletions
+	assert.NoError(webhookUc.Publish(context.Background(), rsvp.Event{ID: "8a2d7c41", Type: rsvp.EventRsvpDeleted, Rsvp: rp}))
+	assert.Len(repo.deliveries, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	webhookUc.RunWebhookWorkers(ctx, 1)
+
+	var d *rsvp.WebhookDelivery
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		result, err := webhookUc.GetWebhookDeliveries(context.Background(), wh.ID.Hex(), &rsvp.Parameter{})
+		assert.NoError(err)
+		if d = result.Data[0]; d.Status != rsvp.DeliveryPending {
+			break
+		}
+	}
+
+	assert.Equal(rsvp.DeliverySucceeded, d.Status)
+	assert.Len(d.Attempts, 2)
+	assert.Equal(http.StatusServiceUnavailable, d.Attempts[0].ResponseCode)
+	assert.Equal(http.StatusOK, d.Attempts[1].ResponseCode)
+
+	mu.Lock()
+	r, body := received[1], bodies[1]
+	mu.Unlock()
+
+	assert.Equal(rsvp.EventRsvpCreated, r.Header.Get(usecase.HeaderWebhookEvent))
+	assert.Equal(d.ID.Hex(), r.Header.Get(usecase.HeaderWebhookDelivery))
+	assert.Equal(usecase.SignWebhook("s3cret", r.Header.Get(usecase.HeaderWebhookTimestamp), body), r.Header.Get(usecase.HeaderWebhookSignature))
+
+	var e rsvp.Event
+	assert.NoError(json.Unmarshal(body, &e))
+	assert.Equal(d.EventID, e.ID)
+	assert.Equal(rp.Name, e.Rsvp.Name)
+
+	redelivery, err := webhookUc.Redeliver(context.Background(), wh.ID.Hex(), d.ID.Hex())
+	assert.NoError(err)
+	assert.Equal(d.EventID, redelivery.EventID)
+	assert.Equal(d.ID, *redelivery.RedeliveryOf)
+
+	_, err = webhookUc.Redeliver(context.Background(), wh.ID.Hex(), bson.NewObjectId().Hex())
+	assert.Equal(response.NotFoundError, err)
+}
+
+func TestWebhookTargets(t *testing.T) {
+	var mu sync.Mutex
+	var hits []string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		hits = append(hits, r.URL.Path)
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/internal", http.StatusTemporaryRedirect)
+		}
+	}))
+	defer target.Close()
+
+	tests := []struct {
+		name    string
+		url     string
+		private bool
+		status  string
+		code    int
+		hits    []string
+	}{
+		// a host name may resolve to a private address after the webhook was created
+		{"private address refused on connect", target.URL + "/hook", false, rsvp.DeliveryFailed, 0, nil},
+		{"redirect not followed", target.URL + "/redirect", true, rsvp.DeliveryFailed, http.StatusTemporaryRedirect, []string{"/redirect"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mu.Lock()
+			hits = nil
+			mu.Unlock()
+
+			repo := &fakeWebhookRepo{webhooks: []rsvp.Webhook{{ID: bson.NewObjectId(), URL: test.url, Secret: "s3cret", Events: []string{rsvp.EventRsvpCreated}}}}
+			webhookUc := usecase.NewWebhookUsecase(&usecase.AccessProvider{WebhookRepo: repo}, usecase.WebhookOption{
+				MaxAttempts:         1,
+				Timeout:             time.Second,
+				PollInterval:        10 * time.Millisecond,
+				AllowPrivateTargets: test.private,
+			})
+
+			rp := rsvp.Rsvp{ID: bson.NewObjectId(), Name: "Budi", Address: "Jakarta", Attend: enumeration.AttendanceTypeYes}
+			assert.NoError(t, webhookUc.Publish(context.Background(), rsvp.Event{ID: "4e3f1b9c", Type: rsvp.EventRsvpCreated, Rsvp: rp}))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			webhookUc.RunWebhookWorkers(ctx, 1)
+
+			var d *rsvp.WebhookDelivery
+			for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+				result, err := webhookUc.GetWebhookDeliveries(context.Background(), repo.webhooks[0].ID.Hex(), &rsvp.Parameter{})
+				assert.NoError(t, err)
+				if d = result.Data[0]; d.Status != rsvp.DeliveryPending {
+					break
+				}
+			}
+
+			assert.Equal(t, test.status, d.Status)
+			assert.Len(t, d.Attempts, 1)
+			assert.Equal(t, test.code, d.Attempts[0].ResponseCode)
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, test.hits, hits)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package rsvp
+
+import (
+	"context"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+// Event types
+const (
+	EventRsvpCreated = "rsvp.created"
+	EventRsvpUpdated = "rsvp.updated"
+	EventRsvpDeleted = "rsvp.deleted"
+)
+
+// EventTypes lists every event type a webhook can subscribe to
+var EventTypes = []string{
+	EventRsvpCreated,
+	EventRsvpUpdated,
+	EventRsvpDeleted,
+}
+
+// Event tells about a change of an RSVP
+type Event struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Rsvp      Rsvp      `json:"data"`
+	// Previous is the RSVP before an rsvp.updated event
+	Previous *Rsvp `json:"previous,omitempty" bson:"previous,omitempty"`
+	// Bulk is set on the events of an import or a restore rather than of a guest
+	Bulk bool `json:"bulk,omitempty" bson:"bulk,omitempty"`
+}
+
+// Publisher tells subscribers about events
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+// Webhook statuses of a delivery
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook Entity, a subscription to events posted to URL.
+// Secret signs the payloads, it is only shown when the webhook is created.
+type Webhook struct {
+	ID        bson.ObjectId `json:"id" bson:"_id,omitempty"`
+	URL       string        `json:"url" bson:"url"`
+	Secret    string        `json:"secret,omitempty" bson:"secret"`
+	Events    []string      `json:"events" bson:"events"`
+	CreatedBy string        `json:"created_by" bson:"created_by"`
+	CreatedAt time.Time     `json:"created_at" bson:"created_at"`
+}
+
+// WebhookRequest holds data submitted to create a webhook, a secret is generated when empty
+type WebhookRequest struct {
+	URL    string   `json:"url,required"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+}
+
+// WebhookAttempt records one attempt to deliver an event
+type WebhookAttempt struct {
+	At           time.Time     `json:"at" bson:"at"`
+	ResponseCode int           `json:"response_code,omitempty" bson:"response_code,omitempty"`
+	Error        string        `json:"error,omitempty" bson:"error,omitempty"`
+	Duration     time.Duration `json:"duration" bson:"duration"`
+}
+
+// WebhookDelivery Entity, an event on its way to a webhook.
+// Payload is the exact body posted on every attempt.
+type WebhookDelivery struct {
+	ID            bson.ObjectId    `json:"id" bson:"_id,omitempty"`
+	WebhookID     bson.ObjectId    `json:"webhook_id" bson:"webhook_id"`
+	EventID       string           `json:"event_id" bson:"event_id"`
+	EventType     string           `json:"event_type" bson:"event_type"`
+	Payload       string           `json:"payload" bson:"payload"`
+	Status        string           `json:"status" bson:"status"`
+	Attempts      []WebhookAttempt `json:"attempts" bson:"attempts"`
+	NextAttemptAt *time.Time       `json:"next_attempt_at,omitempty" bson:"next_attempt_at,omitempty"`
+	RedeliveryOf  *bson.ObjectId   `json:"redelivery_of,omitempty" bson:"redelivery_of,omitempty"`
+	CreatedAt     time.Time        `json:"created_at" bson:"created_at"`
+}
+
+// WebhookDeliveryResult is a struct container to put result
+type WebhookDeliveryResult struct {
+	Data  []*WebhookDelivery
+	Total int64
+}
+
+// WebhookRepo provides data interchange between
+// application and webhook data provider.
+type WebhookRepo interface {
+	CreateWebhook(ctx context.Context, wh Webhook) (Webhook, error)
+	GetWebhook(ctx context.Context, id string) (*Webhook, error)
+	GetWebhooks(ctx context.Context) ([]*Webhook, error)
+	GetWebhooksForEvent(ctx context.Context, eventType string) ([]*Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+
+	CreateWebhookDelivery(ctx context.Context, d WebhookDelivery) (WebhookDelivery, error)
+	// UpsertWebhookDelivery creates d unless its event has been queued for its webhook already,
+	// so publishing an event again does not deliver it twice. Redeliveries are created apart.
+	UpsertWebhookDelivery(ctx context.Context, d WebhookDelivery) error
+	GetWebhookDelivery(ctx context.Context, webhookID string, id string) (*WebhookDelivery, error)
+	GetWebhookDeliveries(ctx context.Context, webhookID string, p *Parameter) (*WebhookDeliveryResult, error)
+	// ClaimWebhookDelivery returns the pending delivery due the longest, leasing it until lease
+	// so no other worker attempts it meanwhile, or returns response.NotFoundError when none is due
+	ClaimWebhookDelivery(ctx context.Context, now time.Time, lease time.Time) (*WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, d WebhookDelivery) error
+}
+
+type WebhookUsecase interface {
+	Publisher
+
+	CreateWebhook(ctx context.Context, req WebhookRequest, createdBy string) (Webhook, error)
+	GetWebhooks(ctx context.Context) ([]*Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	GetWebhookDeliveries(ctx context.Context, webhookID string, p *Parameter) (*WebhookDeliveryResult, error)
+	Redeliver(ctx context.Context, webhookID string, deliveryID string) (WebhookDelivery, error)
+	// RunWebhookWorkers delivers due events with workers goroutines until ctx is done
+	RunWebhookWorkers(ctx context.Context, workers int)
+}
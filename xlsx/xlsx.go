@@ -0,0 +1,284 @@
+// Package xlsx streams Office Open XML workbooks, one row at a time,
+// so a large spreadsheet never has to be held in memory
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cell styles, indexes of cellXfs in styles.xml
+const (
+	styleDefault = iota
+	styleHeader
+	styleDateTime
+)
+
+// excelEpoch is day zero of the 1900 date system, as Excel counts it
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// SheetOption configures a sheet
+type SheetOption struct {
+	// Widths are the widths of the first columns, in characters
+	Widths []float64
+	// FreezeHeader keeps the first row visible while scrolling
+	FreezeHeader bool
+	// AutoFilter adds filter buttons to the first row
+	AutoFilter bool
+}
+
+// Writer writes a workbook to a zip archive. Sheets are written one after another,
+// starting a sheet finishes the previous one.
+type Writer struct {
+	zw     *zip.Writer
+	sheets []string
+	sheet  *Sheet
+}
+
+// Sheet is a worksheet being written
+type Sheet struct {
+	zw      *zip.Writer
+	w       *bufio.Writer
+	opt     SheetOption
+	rows    int
+	columns int
+	err     error
+}
+
+// NewWriter is a function to create Writer writing to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{zw: zip.NewWriter(w)}
+}
+
+// NewSheet finishes the current sheet and starts one named name
+func (xw *Writer) NewSheet(name string, opt SheetOption) (*Sheet, error) {
+	if err := xw.closeSheet(); err != nil {
+		return nil, err
+	}
+
+	xw.sheets = append(xw.sheets, name)
+	f, err := xw.zw.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", len(xw.sheets)))
+	if err != nil {
+		return nil, err
+	}
+
+	sh := &Sheet{zw: xw.zw, w: bufio.NewWriter(f), opt: opt}
+	sh.printf(`%s<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`, xml.Header)
+	if opt.FreezeHeader {
+		sh.printf(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	}
+	if len(opt.Widths) > 0 {
+		sh.printf(`<cols>`)
+		for i, width := range opt.Widths {
+			sh.printf(`<col min="%d" max="%d" width="%g" customWidth="1"/>`, i+1, i+1, width)
+		}
+		sh.printf(`</cols>`)
+	}
+	sh.printf(`<sheetData>`)
+
+	xw.sheet = sh
+	return sh, sh.err
+}
+
+// WriteHeader writes labels as a bold row
+func (sh *Sheet) WriteHeader(labels ...string) error {
+	values := make([]interface{}, len(labels))
+	for i, label := range labels {
+		values[i] = label
+	}
+
+	return sh.writeRow(values, styleHeader)
+}
+
+// WriteRow writes values as a row. Strings are written as text, so they are never
+// reinterpreted as numbers or dates, integers and floats as numbers, time.Time
+// as a date of its wall clock and anything else as its fmt representation.
+func (sh *Sheet) WriteRow(values ...interface{}) error {
+	return sh.writeRow(values, styleDefault)
+}
+
+// Flush writes buffered rows to the underlying writer
+func (sh *Sheet) Flush() error {
+	if sh.err != nil {
+		return sh.err
+	}
+	if err := sh.w.Flush(); err != nil {
+		return err
+	}
+	return sh.zw.Flush()
+}
+
+func (sh *Sheet) writeRow(values []interface{}, style int) error {
+	sh.rows++
+	if len(values) > sh.columns {
+		sh.columns = len(values)
+	}
+
+	sh.printf(`<row r="%d">`, sh.rows)
+	for i, v := range values {
+		ref := cellRef(i, sh.rows)
+		switch v := v.(type) {
+		case nil:
+			continue
+		case int:
+			sh.printf(`<c r="%s" s="%d"><v>%d</v></c>`, ref, style, v)
+		case int64:
+			sh.printf(`<c r="%s" s="%d"><v>%d</v></c>`, ref, style, v)
+		case float64:
+			sh.printf(`<c r="%s" s="%d"><v>%s</v></c>`, ref, style, strconv.FormatFloat(v, 'g', -1, 64))
+		case time.Time:
+			dateStyle := style
+			if dateStyle == styleDefault {
+				dateStyle = styleDateTime
+			}
+			sh.printf(`<c r="%s" s="%d"><v>%s</v></c>`, ref, dateStyle, strconv.FormatFloat(serial(v), 'f', -1, 64))
+		case string:
+			sh.printf(`<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">`, ref, style)
+			sh.escape(v)
+			sh.printf(`</t></is></c>`)
+		default:
+			sh.printf(`<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">`, ref, style)
+			sh.escape(fmt.Sprint(v))
+			sh.printf(`</t></is></c>`)
+		}
+	}
+	sh.printf(`</row>`)
+
+	return sh.err
+}
+
+func (sh *Sheet) close() error {
+	sh.printf(`</sheetData>`)
+	if sh.opt.AutoFilter && sh.rows > 0 && sh.columns > 0 {
+		sh.printf(`<autoFilter ref="A1:%s"/>`, cellRef(sh.columns-1, sh.rows))
+	}
+	sh.printf(`</worksheet>`)
+
+	return sh.Flush()
+}
+
+func (sh *Sheet) printf(format string, a ...interface{}) {
+	if sh.err != nil {
+		return
+	}
+	_, sh.err = fmt.Fprintf(sh.w, format, a...)
+}
+
+func (sh *Sheet) escape(s string) {
+	if sh.err != nil {
+		return
+	}
+	sh.err = xml.EscapeText(sh.w, []byte(s))
+}
+
+func (xw *Writer) closeSheet() error {
+	if xw.sheet == nil {
+		return nil
+	}
+
+	sh := xw.sheet
+	xw.sheet = nil
+	return sh.close()
+}
+
+// Close finishes the current sheet and the workbook, it does not close the underlying writer
+func (xw *Writer) Close() error {
+	if err := xw.closeSheet(); err != nil {
+		return err
+	}
+
+	for _, p := range xw.parts() {
+		f, err := xw.zw.Create(p.name)
+		if err != nil {
+			return err
+		}
+		if _, err = io.WriteString(f, p.content); err != nil {
+			return err
+		}
+	}
+
+	return xw.zw.Close()
+}
+
+// part is a file of the archive
+type part struct {
+	name    string
+	content string
+}
+
+// parts returns the workbook parts besides the sheets
+func (xw *Writer) parts() []part {
+	var overrides, sheets, rels string
+	for i, name := range xw.sheets {
+		id := i + 1
+		overrides += fmt.Sprintf(`<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, id)
+		sheets += fmt.Sprintf(`<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escapeAttr(name), id, id)
+		rels += fmt.Sprintf(`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, id, id)
+	}
+	stylesID := len(xw.sheets) + 1
+
+	return []part{
+		{"[Content_Types].xml", xml.Header +
+			`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+			overrides +
+			`</Types>`},
+		{"_rels/.rels", xml.Header +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", xml.Header +
+			`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets>` + sheets + `</sheets>` +
+			`</workbook>`},
+		{"xl/_rels/workbook.xml.rels", xml.Header +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			rels +
+			fmt.Sprintf(`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, stylesID) +
+			`</Relationships>`},
+		{"xl/styles.xml", xml.Header +
+			`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts>` +
+			`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+			`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+			`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+			`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+			`<cellXfs count="3">` +
+			`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+			`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+			`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+			`</cellXfs>` +
+			`</styleSheet>`},
+	}
+}
+
+// cellRef returns the A1 reference of the cell in the zero based column col and one based row
+func cellRef(col, row int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+
+	return name + strconv.Itoa(row)
+}
+
+// serial returns the wall clock of t as days since excelEpoch
+func serial(t time.Time) float64 {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	return wall.Sub(excelEpoch).Hours() / 24
+}
+
+func escapeAttr(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
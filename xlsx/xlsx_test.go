@@ -0,0 +1,85 @@
+package xlsx_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/faris-arifiansyah/fws-rsvp/xlsx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriter(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := &bytes.Buffer{}
+	xw := xlsx.NewWriter(buffer)
+
+	sh, err := xw.NewSheet("RSVPs", xlsx.SheetOption{Widths: []float64{8, 30}, FreezeHeader: true, AutoFilter: true})
+	assert.NoError(err)
+	assert.NoError(sh.WriteHeader("Number", "Name", "Created Date"))
+	assert.NoError(sh.WriteRow(1, "0812 <Budi> & Siti", time.Date(2019, 8, 17, 12, 0, 0, 0, time.UTC)))
+
+	sh, err = xw.NewSheet("Summary", xlsx.SheetOption{})
+	assert.NoError(err)
+	assert.NoError(sh.WriteRow("Total", int64(1)))
+
+	assert.NoError(xw.Close())
+
+	zr, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	assert.NoError(err)
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		assert.NoError(err)
+		b, err := ioutil.ReadAll(rc)
+		assert.NoError(err)
+		rc.Close()
+
+		// every part must be well formed
+		assert.NoError(xml.Unmarshal(b, new(interface{})), f.Name)
+		files[f.Name] = string(b)
+	}
+
+	testCases := []struct {
+		name     string
+		expected []string
+	}{
+		{
+			name: "xl/worksheets/sheet1.xml",
+			expected: []string{
+				`<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>`,
+				`<col min="2" max="2" width="30" customWidth="1"/>`,
+				`<c r="A1" s="1" t="inlineStr"><is><t xml:space="preserve">Number</t></is></c>`,
+				`<c r="A2" s="0"><v>1</v></c>`,
+				`<c r="B2" s="0" t="inlineStr"><is><t xml:space="preserve">0812 &lt;Budi&gt; &amp; Siti</t></is></c>`,
+				`<c r="C2" s="2"><v>43694.5</v></c>`,
+				`<autoFilter ref="A1:C2"/>`,
+			},
+		},
+		{
+			name:     "xl/worksheets/sheet2.xml",
+			expected: []string{`<c r="B1" s="0"><v>1</v></c>`},
+		},
+		{
+			name: "xl/workbook.xml",
+			expected: []string{
+				`<sheet name="RSVPs" sheetId="1" r:id="rId1"/>`,
+				`<sheet name="Summary" sheetId="2" r:id="rId2"/>`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		for _, expected := range tc.expected {
+			assert.Contains(files[tc.name], expected)
+		}
+	}
+	assert.NotContains(files["xl/worksheets/sheet2.xml"], "autoFilter")
+	assert.Contains(files, "[Content_Types].xml")
+	assert.Contains(files, "xl/styles.xml")
+}